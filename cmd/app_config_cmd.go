@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultProviderModel stands for the model of the default LLM provider,
+// which is only known once the config is loaded.
+const defaultProviderModel = "llm.providers.{default}.model"
+
+// registerAppFlags defines the flags that override AppConfig settings, with
+// the built-in defaults as shown values, and returns flag name -> config key.
+// Only flags given on the command line are applied (see loadAppConfig).
+func registerAppFlags(fs *flag.FlagSet, d *config.AppConfig) map[string]string {
+	keys := map[string]string{}
+	str := func(name, key, value, usage string) { fs.String(name, value, usage); keys[name] = key }
+	num := func(name, key string, value int, usage string) { fs.Int(name, value, usage); keys[name] = key }
+	boolean := func(name, key string, value bool, usage string) { fs.Bool(name, value, usage); keys[name] = key }
+	provider, _ := d.Provider("")
+
+	str("llm", "llm.default", d.LLM.Default, "LLM provider (from llm.providers) used by the agents")
+	str("model", defaultProviderModel, provider.Model, "Model of the selected LLM provider")
+	str("task", "agents.task", d.Agents.Task, "First user message of the session")
+	str("team", "agents.team", d.Agents.Team, "Agent team file (default: built-in Orchestrator/Assistant/ToolRunner team)")
+	num("max-turns", "limits.max_turns", d.Limits.MaxTurns, "Maximum conversation turns")
+	num("max-tokens", "limits.max_tokens", d.Limits.MaxTokens, "Maximum tokens used by a conversation")
+	str("tools-config", "tools.config", d.Tools.Config, "tools.yaml with per-tool enabled flags")
+	str("mcp-config", "tools.mcp_config", d.Tools.McpConfig, "mcp_tools.yaml with HTTP tools and MCP servers")
+	num("tools-reload", "tools.reload_seconds", d.Tools.ReloadSeconds, "Seconds between checks of tools.yaml and mcp_tools.yaml for changes (0 disables reloading)")
+	str("report-dir", "tools.report_dir", d.Tools.ReportDir, "Directory markdown_report writes to when there is no session workspace")
+	str("download-dir", "tools.download_dir", d.Tools.DownloadDir, "Directory arxiv_download saves papers to when there is no session workspace")
+	str("export-dir", "sandbox.export.dir", d.Sandbox.Export.Dir, "Directory to export the session workspace to at session end (disabled if empty)")
+	str("export-include", "sandbox.export.include", strings.Join(d.Sandbox.Export.Include, ","), "Comma-separated glob patterns of workspace files to export (default: all)")
+	str("export-exclude", "sandbox.export.exclude", strings.Join(d.Sandbox.Export.Exclude, ","), "Comma-separated glob patterns of workspace files to skip (default: build outputs and dependencies)")
+	boolean("export-tar", "sandbox.export.tar", d.Sandbox.Export.Tar, "Export the workspace as a .tar.gz archive instead of a directory")
+	str("container-prefix", "sandbox.container_prefix", d.Sandbox.ContainerPrefix, "Name prefix of sandbox containers")
+	str("sandbox-image", "sandbox.default_image", d.Sandbox.DefaultImage, "Sandbox image for languages without a dedicated image")
+	num("max-file-kb", "sandbox.max_file_kb", d.Sandbox.MaxFileKB, "Size limit in KB of each file docker_exec writes to the workspace")
+	num("max-files", "sandbox.max_files", d.Sandbox.MaxFiles, "Maximum number of files one docker_exec call may write")
+	num("pool-size", "sandbox.pool.size", d.Sandbox.Pool.Size, "Maximum number of pooled sandbox containers (0 disables the warm pool)")
+	num("pool-max-uses", "sandbox.pool.max_uses", d.Sandbox.Pool.MaxUses, "Sessions a pooled container serves before it is destroyed (reuse only wipes /workspace)")
+	str("pool-warm", "sandbox.pool.warm", formatIntSpec(d.Sandbox.Pool.Warm), "Comma-separated image=count of idle containers to keep warm")
+	boolean("cache", "sandbox.cache.enabled", d.Sandbox.Cache.Enabled, "Mount shared npm/pip/NuGet cache volumes into sandbox containers")
+	str("cache-max-mb", "sandbox.cache.max_mb", formatIntSpec(d.Sandbox.Cache.MaxMB), "Comma-separated manager=MB size limits; caches over the limit are pruned at startup")
+	str("sandbox-network", "sandbox.network", d.Sandbox.Network, `Docker network for sandbox containers ("none" isolates them; use with package mirrors)`)
+	str("npm-registry", "sandbox.mirrors.npm_registry", d.Sandbox.Mirrors.NpmRegistry, "npm registry mirror URL (e.g. a local Verdaccio) injected as .npmrc")
+	str("pip-index-url", "sandbox.mirrors.pip_index_url", d.Sandbox.Mirrors.PipIndexURL, "pip index mirror URL injected into pip.conf")
+	str("pip-find-links", "sandbox.mirrors.pip_find_links", d.Sandbox.Mirrors.PipFindLinks, "Host directory of wheels mounted read-only as a pip find-links source")
+	str("nuget-feed", "sandbox.mirrors.nuget_feed", d.Sandbox.Mirrors.NugetFeed, "NuGet feed URL or host folder feed injected as NuGet.config")
+	str("secrets-env-file", "secrets.env_file", d.Secrets.EnvFile, "dotenv file with secrets for sandbox/tools")
+	str("secrets-file", "secrets.file", d.Secrets.File, "Encrypted secrets file (see 'playground secrets seal'); key from $"+secrets.KeyEnvVar)
+	str("secrets-allow", "secrets.allow", d.Secrets.Allow, `Secrets injected per tool, e.g. "docker_exec=STRIPE_API_KEY,DB_URL;stripe_mcp=STRIPE_API_KEY"`)
+	str("log-level", "logging.level", d.Logging.Level, "Log level: trace, debug, info, warn or error")
+	str("log-file", "logging.file", d.Logging.File, "Log file (empty disables it)")
+	boolean("metrics", "metrics.enabled", d.Metrics.Enabled, "Serve Prometheus metrics")
+	str("metrics-addr", "metrics.addr", d.Metrics.Addr, "Address of the /metrics endpoint")
+	return keys
+}
+
+// loadAppConfig layers the defaults, the config file, GOGEN_* environment
+// variables and the flags given on the command line, then validates.
+func loadAppConfig(fs *flag.FlagSet, file string, flagKeys map[string]string) (*config.AppConfig, error) {
+	cfg, err := config.LoadAppConfig(config.FindAppConfigFile(file))
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	fs.Visit(func(f *flag.Flag) {
+		key, ok := flagKeys[f.Name]
+		if !ok {
+			return
+		}
+		if key == defaultProviderModel {
+			key = "llm.providers." + cfg.LLM.Default + ".model"
+		}
+		if err := cfg.Set(key, f.Value.String()); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// printAppConfig writes the effective config as YAML, API keys masked.
+func printAppConfig(w io.Writer, cfg *config.AppConfig) error {
+	layers := "defaults"
+	if cfg.File != "" {
+		layers += " < " + cfg.File
+	}
+	fmt.Fprintf(w, "# Effective config: %s < %s* env < flags\n", layers, config.AppConfigEnvPrefix)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// formatIntSpec renders a map as "key=n,key=n" (sorted), the flag syntax
+// read by AppConfig.Set.
+func formatIntSpec(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = fmt.Sprintf("%s=%d", k, m[k])
+	}
+	return strings.Join(items, ",")
+}
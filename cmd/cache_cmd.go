@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"aiupstart.com/go-gen/internal/tools"
+)
+
+const cacheUsage = `usage: playground cache <list|prune> [npm pip nuget]
+
+  list    show the size of each package cache volume
+  prune   remove the given cache volumes (all if none given)`
+
+// runCacheCommand implements "playground cache ..." for the sandbox package cache volumes.
+func runCacheCommand(args []string) int {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	prefix := fs.String("volume-prefix", tools.DefaultCacheVolumePrefix, "Cache volume name prefix")
+	fs.Usage = func() { fmt.Println(cacheUsage); fs.PrintDefaults() }
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+	sub := args[0]
+	fs.Parse(args[1:])
+	cfg := &tools.CacheConfig{Enabled: true, VolumePrefix: *prefix}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	switch sub {
+	case "list":
+		vols, err := tools.ListCacheVolumes(ctx, cfg)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		for _, v := range vols {
+			if !v.Exists {
+				fmt.Printf("%-6s %-24s (not created)\n", v.Manager, v.Volume)
+				continue
+			}
+			fmt.Printf("%-6s %-24s %8.1f MB\n", v.Manager, v.Volume, v.SizeMB)
+		}
+	case "prune":
+		if err := tools.PruneCacheVolumes(ctx, cfg, fs.Args()); err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		fmt.Println("Package caches pruned.")
+	default:
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"aiupstart.com/go-gen/internal/tools/mcptest"
+)
+
+const mcpFakeUsage = `usage: playground mcp-fake [-http addr [-sse-responses] | -sse addr]
+
+Runs the fake MCP server (tools: echo, add, fail, sleep) for trying out
+mcp_servers entries. Without flags it speaks stdio.`
+
+// runMcpFakeCommand implements "playground mcp-fake".
+func runMcpFakeCommand(args []string) int {
+	fs := flag.NewFlagSet("mcp-fake", flag.ExitOnError)
+	httpAddr := fs.String("http", "", "Serve streamable HTTP at http://<addr>/mcp")
+	sseResponses := fs.Bool("sse-responses", false, "Answer streamable HTTP requests as text/event-stream")
+	sseAddr := fs.String("sse", "", "Serve the legacy SSE transport at http://<addr>/sse")
+	pageSize := fs.Int("page-size", 0, "Tools per tools/list page (0 = one page)")
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, mcpFakeUsage); fs.PrintDefaults() }
+	fs.Parse(args)
+
+	srv := mcptest.NewServer()
+	srv.PageSize = *pageSize
+	srv.SSE = *sseResponses
+	var err error
+	switch {
+	case *httpAddr != "":
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", srv)
+		fmt.Fprintf(os.Stderr, "fake MCP server on http://%s/mcp\n", *httpAddr)
+		err = http.ListenAndServe(*httpAddr, mux)
+	case *sseAddr != "":
+		fmt.Fprintf(os.Stderr, "fake MCP server on http://%s/sse\n", *sseAddr)
+		err = http.ListenAndServe(*sseAddr, srv.SSEHandler())
+	default:
+		err = srv.ServeStdio(context.Background(), os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/tools"
+)
+
+// serveMcp serves the registered tools over MCP until ctx is cancelled (or
+// stdin closes in stdio mode). Input schemas from mcp_tools.yaml take
+// precedence over Parameters(), matching what the LLM is given. Tools that
+// require approval are only served with approvalTools, since MCP clients
+// call them without a human confirming.
+func serveMcp(ctx context.Context, registry *tools.ToolRegistry, mcpCfg *config.McpConfig, httpAddr, token string, origins []string, approvalTools bool, stdout io.Writer) error {
+	schemas := map[string]map[string]interface{}{}
+	for _, t := range mcpCfg.McpTools {
+		for _, op := range t.Operations {
+			if op.Parameters != nil {
+				schemas[op.Name] = op.Parameters
+			}
+		}
+	}
+	srv := tools.NewMcpServer(registry, tools.McpServerOptions{
+		Instructions:   "Tools of the go-gen playground: a docker code sandbox with workspace file tools, arXiv search and configured REST/MCP tools.",
+		Schemas:        schemas,
+		Token:          token,
+		AllowedOrigins: origins,
+		ApprovalTools:  approvalTools,
+	})
+	if !approvalTools {
+		var hidden []string
+		for _, t := range registry.List() {
+			if tools.MetadataOf(t).RequiresApproval {
+				hidden = append(hidden, t.Name())
+			}
+		}
+		if len(hidden) > 0 {
+			sort.Strings(hidden)
+			fmt.Fprintf(os.Stderr, "Not serving tools that require approval: %s (use -mcp-approval-tools to serve them)\n", strings.Join(hidden, ", "))
+		}
+	}
+
+	if httpAddr == "" {
+		return srv.ServeStdio(ctx, os.Stdin, stdout)
+	}
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "Warning: serving MCP over HTTP without -mcp-token; any client that can reach "+httpAddr+" can call the tools")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", srv)
+	httpSrv := &http.Server{Addr: httpAddr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpSrv.Shutdown(shutdownCtx)
+	}()
+	fmt.Printf("Serving MCP on http://%s/mcp\n", httpAddr)
+	if err := httpSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
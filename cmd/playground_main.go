@@ -1,120 +1,243 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"aiupstart.com/go-gen/internal/agent"
 	"aiupstart.com/go-gen/internal/config"
-	"aiupstart.com/go-gen/internal/llm"
 	"aiupstart.com/go-gen/internal/metrics"
 	"aiupstart.com/go-gen/internal/model"
+	"aiupstart.com/go-gen/internal/secrets"
 	"aiupstart.com/go-gen/internal/tools"
+	"aiupstart.com/go-gen/internal/utils"
 
 	"github.com/joho/godotenv"
-	"github.com/sashabaranov/go-openai"
 )
 
 func main() {
 	// utils.Logger.Debug().Str("module", "main").Msg("Starting AIUpStart Playground")
 
-	_ = godotenv.Load() // Loads .env file if present
-
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("Please set the OPENAI_API_KEY environment variable.")
-		return
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		os.Exit(runCacheCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		os.Exit(runSecretsCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(runValidateConfigCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mcp-fake" {
+		os.Exit(runMcpFakeCommand(os.Args[2:]))
+	}
+	// "mcp-serve" takes the same flags as a chat session but serves the tools
+	// over MCP; "print-config" prints the config those flags produce.
+	serveMCP := len(os.Args) > 1 && os.Args[1] == "mcp-serve"
+	printConfig := len(os.Args) > 1 && os.Args[1] == "print-config"
+	if serveMCP || printConfig {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
 	}
 
-	metrics.StartMetricsServer(":2112")
+	configFile := flag.String("config", "", "Application config file (default $"+config.AppConfigEnv+", else ./"+config.DefaultAppConfigFile+" if present)")
+	flagKeys := registerAppFlags(flag.CommandLine, config.DefaultAppConfig())
+	mcpHTTP := flag.String("mcp-http", "", "mcp-serve: serve streamable HTTP at http://<addr>/mcp instead of stdio")
+	mcpToken := flag.String("mcp-token", os.Getenv("GOGEN_MCP_TOKEN"), "mcp-serve: bearer token required from HTTP clients")
+	mcpAllowOrigin := flag.String("mcp-allow-origin", "", "mcp-serve: comma-separated browser origins allowed over HTTP (default: localhost only)")
+	mcpApprovalTools := flag.Bool("mcp-approval-tools", false, "mcp-serve: also serve tools that require approval; MCP clients call them without confirmation")
+	flag.Parse()
 
-	
-	// Logger to file as well as stdout
-	// f, _ := os.OpenFile("run.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	// utils.Logger.SetOutput(f)
-
-	// Tool config (optional)
-	cfg, _ := tools.LoadToolConfig("./tools.yaml")
+	_ = godotenv.Load() // Loads .env file if present, before GOGEN_* overrides are read
 
-	pwd, err := os.Getwd()
+	// Defaults < config file < GOGEN_* environment < flags.
+	appCfg, err := loadAppConfig(flag.CommandLine, *configFile, flagKeys)
 	if err != nil {
-		panic(err)
+		fmt.Println("Config:", err)
+		os.Exit(1)
 	}
-	mcpCfgPath := filepath.Join(filepath.Dir(pwd), "mcp_tools.yaml")
-	// utils.Logger.Debug().Str("module", "main").Msgf("Starting AIUpStart Playground with file %s", mcpCfgPath)
-
-	mcp_cfg, err := config.LoadConfig(mcpCfgPath)
-	if err != nil { panic(err) }
-
-// 	if err := config.ValidateMcpConfig(cfg); err != nil {
-//     log.Fatalf("Config validation error: %v", err)
-// }
-
-	registry := tools.NewToolRegistry()
-
-	for _, mcp := range mcp_cfg.McpTools {
-		tool := &tools.GenericMcpTool{
-			NameStr:        mcp.Name,
-			Endpoint:       mcp.Endpoint,
-			DescriptionStr: mcp.Description,
+	if printConfig {
+		if err := printAppConfig(os.Stdout, appCfg); err != nil {
+			fmt.Println("Config:", err)
+			os.Exit(1)
 		}
-		registry.Register(tool)
+		return
+	}
+	if err := utils.ConfigureLogger(appCfg.Logging.Level, appCfg.Logging.File, appCfg.Logging.Console); err != nil {
+		fmt.Println("Logging:", err)
 	}
 
-	// OpenAI Example
-	// 
-	// llmClient := llm.NewOpenAIClient(apiKey, "gpt-4o", mcp_cfg)
+	// Over stdio, stdout carries MCP messages; everything else goes to stderr.
+	protocolOut := os.Stdout
+	if serveMCP {
+		os.Stdout = os.Stderr
+		if appCfg.Logging.Console {
+			utils.SetConsoleOutput(os.Stderr)
+		}
+	}
 
-	oaClient := openai.NewClient(apiKey)
-	openAITools := llm.BuildOpenAIToolsFromConfig(mcp_cfg)
-	  // --- 4. Wrap OpenAI client in your LLM interface ---
-	  llmClient := llm.NewOpenAILLMClient(oaClient, openAITools)
+	// Ctrl-C / SIGTERM cancel ctx; the session is then closed below.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	//   // --- 5. Build ToolRegistry for runtime tool calls ---
-	//   registry := tools.NewToolRegistry()
-	//   for _, mcp := range cfg.McpTools {
-	// 	  for _, op := range mcp.Operations {
-	// 		  registry.Register(&tools.GenericMcpTool{
-	// 			  NameStr:        op.Name,
-	// 			  Endpoint:       mcp.Endpoint,
-	// 			  DescriptionStr: op.Description,
-	// 			  // Optionally: add validation fields for runtime here
-	// 		  })
-	// 	  }
-	//   }
+	if n, err := tools.SweepOrphans(ctx); err != nil {
+		fmt.Println("Orphaned container sweep skipped:", err)
+	} else if n > 0 {
+		fmt.Printf("Removed %d orphaned sandbox container(s) from earlier runs\n", n)
+	}
 
+	secretStore, err := loadSecrets(appCfg.Secrets.EnvFile, appCfg.Secrets.File, appCfg.Secrets.Allow)
+	if err != nil {
+		fmt.Println("Secrets:", err)
+		return
+	}
+	utils.SetLogRedactor(secretStore.Redact)
 
+	team, err := loadTeam(appCfg)
+	if err != nil {
+		fmt.Println("Invalid agent team:", err)
+		return
+	}
+	if !serveMCP {
+		if err := checkTeamProviders(appCfg, team, secretStore.Get); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
 
-	// Register tools only if enabled
-	if cfg == nil || toolEnabled(cfg, "fetch_arxiv") {
-		registry.Register(&tools.FetchArxivTool{})
+	var metricsServer *http.Server
+	if appCfg.Metrics.Enabled {
+		metricsServer = metrics.StartMetricsServer(appCfg.Metrics.Addr)
 	}
-	newDockerExec := tools.NewDockerExecTool("go-gen-","node:20")
-	registry.Register(newDockerExec)
 
-	prompt := `You are precise, helpful, and always prefer running and testing code over guessing. 
-		If the user requests a coding task, you generate high-quality, working code, and always execute it for validation.`
 	
+	// Logger to file as well as stdout
+	// f, _ := os.OpenFile("run.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// utils.Logger.SetOutput(f)
+
+	registry := tools.NewToolRegistry()
+	registry.SetRedactor(secretStore.Redact)
 
-	// Generic assistant agent
-	assistant := agent.NewAssistantAgent("Assistant", llmClient, prompt, registry)
+	sb := appCfg.Sandbox
+	sandboxOpts := &tools.SandboxOptions{
+		Cache:       &tools.CacheConfig{Enabled: sb.Cache.Enabled, MaxSizeMB: sb.Cache.MaxMB},
+		NetworkMode: sb.Network,
+	}
+	if m := sb.Mirrors; m != (config.MirrorsConfig{}) {
+		sandboxOpts.Mirror = &tools.MirrorConfig{
+			NpmRegistry:  m.NpmRegistry,
+			PipIndexURL:  m.PipIndexURL,
+			PipFindLinks: m.PipFindLinks,
+			NugetFeed:    m.NugetFeed,
+		}
+	}
+	if err := tools.EnforceCacheLimits(context.Background(), sandboxOpts.Cache); err != nil {
+		fmt.Println("Package cache limits not enforced:", err)
+	}
+	newDockerExec := tools.NewDockerExecTool(sb.ContainerPrefix, sb.DefaultImage)
+	newDockerExec.SetSandboxOptions(sandboxOpts)
+	newDockerExec.SetWriteLimits(sb.MaxFileKB<<10, sb.MaxFiles)
+	dockerEnv, err := secretStore.EnvFor(newDockerExec.Name())
+	if err != nil {
+		fmt.Println("Secrets:", err)
+	}
+	newDockerExec.SetEnv(dockerEnv)
+	// tools.yaml enables built-in tools and mcp_tools.yaml entries; one tool
+	// per mcp_tools.yaml operation, matching the functions advertised to the
+	// LLM. Both files are reloaded at runtime (tools.reload_seconds).
+	reloader := tools.NewToolReloader(registry, appCfg.Tools.Config, appCfg.Tools.McpConfig, secretStore.Get,
+		&tools.FetchArxivTool{},
+		tools.NewArxivDownloadTool(newDockerExec, appCfg.Tools.DownloadDir),
+		newDockerExec,
+		tools.NewWorkspacePatchTool(newDockerExec),
+		tools.NewWorkspaceListTool(newDockerExec),
+		tools.NewWorkspaceReadTool(newDockerExec),
+		tools.NewWorkspaceGrepTool(newDockerExec),
+		tools.NewMarkdownReportTool(newDockerExec, appCfg.Tools.ReportDir),
+	)
+	// LoadConfig rejects unknown keys and invalid schemas, listing every problem.
+	if err := reloader.Load(); err != nil {
+		fmt.Println("Invalid tool config:", err)
+		return
+	}
+	mcp_cfg := reloader.McpConfig()
+
+	var pool *tools.ContainerPool
+	if sb.Pool.Size > 0 {
+		pool = tools.NewContainerPool(tools.ContainerPoolConfig{
+			Prefix:        sb.ContainerPrefix,
+			MaxContainers: sb.Pool.Size,
+			WarmPerImage:  sb.Pool.Warm,
+			MaxUses:       sb.Pool.MaxUses,
+			Sandbox:       sandboxOpts,
+		})
+		pool.Warm()
+		newDockerExec.SetPool(pool)
+	}
+
+	// Tools discovered on MCP servers register as mcp__<server>__<tool>.
+	mcpClients := tools.ConnectMcpServers(ctx, mcp_cfg.McpServers, registry, secretStore.Get)
+	// Plugins are executables serving one tool each over stdin/stdout.
+	plugins := tools.StartPlugins(ctx, mcp_cfg.Plugins, registry, secretStore.Get)
 
-	// User proxy agent (choose console or MQ)
-	// hitlAgent := agent.NewHITLAgent("User", registry)
-	// hitlAgent.ApproveTools = false // Enable tool approval if desired
-	toolRunner := agent.NewToolRunnerAgent("ToolRunner", registry)
+	// Closers run in reverse: sandbox first, then the pool, metrics and logs.
+	session := agent.NewSession(nil)
+	session.OnClose("logger", func(context.Context) error { return utils.CloseLogger() })
+	if metricsServer != nil {
+		session.OnClose("metrics", metricsServer.Shutdown)
+	}
+	if pool != nil {
+		session.OnClose("container pool", pool.Drain)
+	}
+	session.OnClose("docker_exec", newDockerExec.Close)
+	for _, c := range mcpClients {
+		c := c
+		session.OnClose("mcp server "+c.Name(), func(context.Context) error { return c.Close() })
+	}
+	for _, p := range plugins {
+		p := p
+		session.OnClose("plugin "+p.Name(), func(context.Context) error { return p.Close() })
+	}
 
-	agents := []agent.Agent{ assistant, toolRunner}
+	if serveMCP {
+		if secs := appCfg.Tools.ReloadSeconds; secs > 0 {
+			go reloader.Watch(ctx, time.Duration(secs)*time.Second)
+		}
+		err := serveMcp(ctx, registry, mcp_cfg, *mcpHTTP, *mcpToken, splitList(*mcpAllowOrigin), *mcpApprovalTools, protocolOut)
+		if err != nil {
+			fmt.Println("MCP server:", err)
+		}
+		closeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := session.Close(closeCtx); err != nil {
+			fmt.Println("Shutdown:", err)
+		}
+		return
+	}
 
+	// Agents, their LLM profiles and allowed tools come from the team file
+	// (agents.team), or the built-in Orchestrator/Assistant/ToolRunner team.
+	newLLM, refreshLLMTools := teamLLMFactory(appCfg, secretStore.Get, advertisedTools(mcp_cfg, registry))
+	manager, err := agent.BuildTeam(ctx, team, registry, newLLM)
+	if err != nil {
+		fmt.Println("Agent team:", err)
+		session.Close(context.Background())
+		return
+	}
+	manager.SetLimits(appCfg.Limits.MaxTurns, appCfg.Limits.MaxTokens)
 
-	// agents := []agent.Agent{hitlAgent, assistant}
+	// Reloaded tool definitions reach the LLMs with their next request.
+	reloader.OnReload(func(cfg *config.McpConfig) { refreshLLMTools(advertisedTools(cfg, registry)) })
+	if secs := appCfg.Tools.ReloadSeconds; secs > 0 {
+		go reloader.Watch(ctx, time.Duration(secs)*time.Second)
+	}
 
-	var manager *agent.ChatManager
-    orchestrator := agent.NewOrchestratorAgent("Orchestrator", manager, agents, llmClient)
-    agentListWithOrch := append([]agent.Agent{orchestrator}, agents...)
-    manager = agent.NewChatManager(agentListWithOrch)
-    orchestrator.SetManager(manager) // set after to avoid nil ref
+	session.Manager = manager
 
 
 	// // check if hitlAgent is enabled via if check append(agents, hitlAgent)...
@@ -126,7 +249,7 @@ func main() {
 	// manager := agent.NewChatManager(topAgents, chat.RoundRobinSelector())
 	// manager.Start()
 	
-    first := model.Message{Sender: "User", Content: "Create a new angular web app which has a main user login page."}
+    first := model.Message{Sender: "User", Content: appCfg.Agents.Task}
     manager.Start()
 
     go func() { manager.InputChan() <- first }()
@@ -134,9 +257,33 @@ func main() {
 
 	// go hitlAgent.BeginChat(manager, first)
 
-	for msg := range manager.OutputChan() {
+	// The manager emits exactly one final output per input message, which ends the session.
+	select {
+	case msg := <-manager.OutputChan():
 		fmt.Printf("*** [%s]: %s ***\n", msg.Sender, msg.Content)
-		// add termination condition to avoid endless loop
+	case <-ctx.Done():
+		fmt.Println("Interrupted, shutting down...")
+	}
+	stop() // a second Ctrl-C now kills the process immediately
+
+	if export := sb.Export; export.Dir != "" {
+		manifest, err := newDockerExec.ExportArtifacts(tools.ExportOptions{
+			Dest:    export.Dir,
+			Include: export.Include,
+			Exclude: export.Exclude,
+			Archive: export.Tar,
+		})
+		if err != nil {
+			fmt.Println("Artifact export failed:", err)
+		} else {
+			fmt.Printf("Exported %d files (%d bytes) to %s\n", len(manifest.Files), manifest.TotalSize, manifest.Output)
+		}
+	}
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := session.Close(closeCtx); err != nil {
+		fmt.Println("Shutdown:", err)
 	}
 
     // for {
@@ -157,9 +304,6 @@ func main() {
     //     // Autonomous: feed response back to manager.input for next agent
     //     manager.InputChan() <- msg
     // }
-	//   if cfg == nil || toolEnabled(cfg, "markdown_report") {
-	// 	  registry.Register(&tools.MarkdownReportTool{})
-	//   }
 
 	// llmClient := llm.NewOpenAIClient("sk-your-openai-key")
 	// planner := agent.NewPlanner("Planner", llmClient)
@@ -200,13 +344,38 @@ func main() {
 	// }
 }
 
-func toolEnabled(cfg *tools.ToolConfig, name string) bool {
-	for _, t := range cfg.Tools {
-		if t.Name == name && t.Enabled {
-			return true
+// loadSecrets builds the secret store: process env first, then the env file,
+// then the encrypted file. All allowlisted values are preloaded for redaction.
+func loadSecrets(envFile, encFile, allow string) (*secrets.Store, error) {
+	providers := []secrets.Provider{secrets.EnvProvider{}}
+	if envFile != "" {
+		p, err := secrets.NewEnvFileProvider(envFile)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if encFile != "" {
+		p, err := secrets.NewEncryptedFileProvider(encFile)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	store := secrets.NewStore(secrets.ParseAllowlist(allow), providers...)
+	store.Preload()
+	return store, nil
+}
+
+// splitList parses a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	out := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
 		}
 	}
-	return false
+	return out
 }
 
 func SimpleStrategy(msg model.Message, agents []agent.Agent) int {
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"aiupstart.com/go-gen/internal/secrets"
+	"github.com/joho/godotenv"
+)
+
+const secretsUsage = `usage: playground secrets seal -in <file.env> -out <file.enc>
+
+Encrypts a dotenv file for use with -secrets-file. The passphrase is read
+from $` + secrets.KeyEnvVar + `.`
+
+// runSecretsCommand implements "playground secrets ...".
+func runSecretsCommand(args []string) int {
+	fs := flag.NewFlagSet("secrets", flag.ExitOnError)
+	in := fs.String("in", "", "dotenv file to encrypt")
+	out := fs.String("out", "", "encrypted output file")
+	fs.Usage = func() { fmt.Println(secretsUsage); fs.PrintDefaults() }
+	if len(args) == 0 || args[0] != "seal" {
+		fs.Usage()
+		return 2
+	}
+	fs.Parse(args[1:])
+	pass := os.Getenv(secrets.KeyEnvVar)
+	if *in == "" || *out == "" || pass == "" {
+		fs.Usage()
+		return 2
+	}
+	values, err := godotenv.Read(*in)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	data, err := secrets.EncryptSecrets(values, pass)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	fmt.Printf("Sealed %d secrets into %s\n", len(values), *out)
+	return 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"aiupstart.com/go-gen/internal/agent"
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/llm"
+	"aiupstart.com/go-gen/internal/tools"
+	"github.com/sashabaranov/go-openai"
+)
+
+// loadTeam returns the team of agents.team, or the built-in code team.
+func loadTeam(appCfg *config.AppConfig) (*config.TeamConfig, error) {
+	if appCfg.Agents.Team == "" {
+		return config.DefaultTeam(appCfg), nil
+	}
+	return config.LoadTeamConfig(appCfg.Agents.Team)
+}
+
+// checkTeamProviders reports LLM agents whose provider is unknown or has no
+// API key, before anything is started.
+func checkTeamProviders(appCfg *config.AppConfig, team *config.TeamConfig, getSecret func(string) (string, bool)) error {
+	for _, a := range team.Agents {
+		if !a.UsesLLM() {
+			continue
+		}
+		if _, _, err := resolveProvider(appCfg, a.LLM, getSecret); err != nil {
+			return fmt.Errorf("agent %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolveProvider returns the named provider (default for "") and its API
+// key with ${NAME} references expanded from the secret store.
+func resolveProvider(appCfg *config.AppConfig, name string, getSecret func(string) (string, bool)) (config.LLMProviderConfig, string, error) {
+	if name == "" {
+		name = appCfg.LLM.Default
+	}
+	provider, err := appCfg.Provider(name)
+	if err != nil {
+		return provider, "", err
+	}
+	apiKey := os.Expand(provider.APIKey, func(key string) string {
+		v, _ := getSecret(key)
+		return v
+	})
+	if apiKey == "" {
+		return provider, "", fmt.Errorf("please set the API key of LLM provider %q (llm.providers.%s.api_key)", name, name)
+	}
+	return provider, apiKey, nil
+}
+
+// advertisedTools returns the function definitions offered to the LLM: the
+// mcp_tools.yaml operations, then the other registered tools with a schema,
+// dropping definitions of tools that are not registered (disabled).
+func advertisedTools(mcpCfg *config.McpConfig, registry *tools.ToolRegistry) []openai.Tool {
+	defs := llm.BuildOpenAIToolsFromConfig(mcpCfg)
+	defs = llm.AppendRegistryTools(defs, registry)
+	return llm.FilterTools(defs, registry.Registered)
+}
+
+// teamLLMFactory builds one OpenAI client per agent: the agent's provider and
+// model, offering only the tools its scope (tools, deny_tools) permits. The
+// returned refresh function re-applies each agent's scope to new definitions
+// (after a tool reload).
+func teamLLMFactory(appCfg *config.AppConfig, getSecret func(string) (string, bool), allTools []openai.Tool) (agent.TeamLLMFactory, func([]openai.Tool)) {
+	var mu sync.Mutex
+	clients := map[string]*openai.Client{} // one HTTP client per provider
+	type scoped struct {
+		client *llm.OpenAILLMClient
+		scope  tools.ToolScope
+	}
+	var built []scoped
+	factory := func(a config.TeamAgentConfig) (llm.LLMClient, error) {
+		name := a.LLM
+		if name == "" {
+			name = appCfg.LLM.Default
+		}
+		provider, apiKey, err := resolveProvider(appCfg, name, getSecret)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		oaClient, ok := clients[name]
+		if !ok {
+			oaConfig := openai.DefaultConfig(apiKey)
+			if provider.BaseURL != "" {
+				oaConfig.BaseURL = provider.BaseURL
+			}
+			oaClient = openai.NewClientWithConfig(oaConfig)
+			clients[name] = oaClient
+		}
+		scope := tools.ToolScope{Allow: a.Tools, Deny: a.DenyTools}
+		client := llm.NewOpenAILLMClient(oaClient, llm.FilterTools(allTools, scope.Permits))
+		client.SetModel(provider.Model)
+		if a.Model != "" {
+			client.SetModel(a.Model)
+		}
+		built = append(built, scoped{client, scope})
+		return client, nil
+	}
+	refresh := func(defs []openai.Tool) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, b := range built {
+			b.client.SetTools(llm.FilterTools(defs, b.scope.Permits))
+		}
+	}
+	return factory, refresh
+}
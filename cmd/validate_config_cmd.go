@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"aiupstart.com/go-gen/internal/config"
+)
+
+const validateConfigUsage = `usage: playground validate-config [-config gogen.yaml] [file ...]
+
+Validates the application config and its agent team file (agents.team),
+then strictly loads MCP tool config files (default: tools.mcp_config of the
+application config, as used at startup) and reports every problem as
+file:line:column. Exits 1 on errors.`
+
+// runValidateConfigCommand implements "playground validate-config".
+func runValidateConfigCommand(args []string) int {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	configFile := fs.String("config", "", "Application config file (default $"+config.AppConfigEnv+", else ./"+config.DefaultAppConfigFile+" if present)")
+	fs.Usage = func() { fmt.Println(validateConfigUsage); fs.PrintDefaults() }
+	fs.Parse(args)
+
+	appCfg, err := config.LoadAppConfig(config.FindAppConfigFile(*configFile))
+	if err == nil {
+		err = appCfg.Validate()
+	}
+	if err != nil {
+		fmt.Printf("application config: invalid\n%v\n", err)
+		return 1
+	}
+	status := 0
+	if file := appCfg.Agents.Team; file != "" {
+		if err := validateTeamFile(appCfg, file); err != nil {
+			fmt.Printf("%s: invalid\n%v\n", file, err)
+			status = 1
+		}
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{appCfg.Tools.McpConfig}
+	}
+	for _, file := range files {
+		cfg, err := config.LoadConfig(file)
+		if err != nil {
+			fmt.Printf("%s: invalid\n%v\n", file, err)
+			status = 1
+			continue
+		}
+		ops := 0
+		for _, t := range cfg.McpTools {
+			ops += len(t.Operations)
+		}
+		fmt.Printf("%s: ok (%d tools, %d operations, %d MCP servers, %d plugins, %d composite tools)\n", file, len(cfg.McpTools), ops, len(cfg.McpServers), len(cfg.Plugins), len(cfg.CompositeTools))
+	}
+	return status
+}
+
+// validateTeamFile loads a team file and checks that its LLM agents use
+// providers defined in the application config.
+func validateTeamFile(appCfg *config.AppConfig, file string) error {
+	team, err := config.LoadTeamConfig(file)
+	if err != nil {
+		return err
+	}
+	var errs config.ValidationErrors
+	for i, a := range team.Agents {
+		if !a.UsesLLM() {
+			continue
+		}
+		if _, err := appCfg.Provider(a.LLM); err != nil {
+			errs = append(errs, config.ConfigError{File: file, Path: fmt.Sprintf("agents[%d].llm", i), Msg: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Printf("%s: ok (team %q, %d agents, entry %s)\n", file, team.Name, len(team.Agents), team.EntryAgent())
+	return nil
+}
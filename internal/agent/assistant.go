@@ -23,6 +23,8 @@ Each object should have:
 - filename: e.g. main.py
 - code: the code/content as a string
 
+Each language runs in its own container; all containers share /workspace. For multi-service apps, set the runtime argument (e.g. "frontend", "backend") and use the runtime name as hostname between them (e.g. http://backend:5000).
+
 You must pass in the dockerfile content inside the docker_file parameter which can be used to setup an image that will have all the required dependencies installed and configured
 
 Do not output code as plain strings or markdown—always use this structure for tool calls.
@@ -41,6 +43,8 @@ You have access to the following tools:
 ---------------------------------------------
 
 If the previous execution failed, analyze the error shown, fix the code and retry.
+When only a few lines need to change in files already written, use the apply_patch tool with a unified diff or search/replace edits instead of resending whole files.
+To see what is already in the workspace (e.g. files generated by "ng new"), use workspace_list, workspace_read and workspace_grep rather than guessing file contents.
 
 ------------------------------------
 
@@ -121,7 +125,10 @@ func (a *AssistantAgent) Start(input <-chan model.Message, output chan<- model.M
             // --- OpenAI function calling: check ToolCalls ---
             if len(llmResp.ToolCalls) > 0 {
                 for _, toolCall := range llmResp.ToolCalls {
-                    if a.toolRegistry.HasTool(toolCall.Name) {
+                    // Calls outside the agent's scope are sent anyway: the
+                    // registry denies them when run and the denial is
+                    // reported back, instead of being dropped here.
+                    if a.toolRegistry.Registered(toolCall.Name) {
                         utils.Logger.Debug().
                             Str("tool_call", fmt.Sprintf("%+v", toolCall.Name)).
                             Msg("Tool call from OpenAI response")
@@ -142,6 +149,7 @@ func (a *AssistantAgent) Start(input <-chan model.Message, output chan<- model.M
 			// Try parsing as a tool suggestion
 			toolCall, toolDetected := tools.ParseToolCall(llmResp.Content)
 			if toolDetected && a.toolRegistry.HasTool(toolCall.Name) {
+				toolCall.Caller = a.name
 				utils.Logger.Debug().Str("tool_call", fmt.Sprintf("%+v", toolCall)).Msg("Tool call created from LLM response\n")
 
 				// Instead of running, delegate to HITL agent by sending tool call message
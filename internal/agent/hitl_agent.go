@@ -3,6 +3,7 @@ package agent
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,13 +14,14 @@ import (
 	"aiupstart.com/go-gen/internal/utils"
 )
 type HITLAgent struct {
+    ctx          context.Context // tool calls run under it; cancelled at shutdown
     name         string
     toolRegistry *tools.ToolRegistry // For listing/dispatching tools if user wants to call one manually
     ApproveTools bool // <----: Toggle approval
 }
 
-func NewHITLAgent(name string, registry *tools.ToolRegistry) *HITLAgent {
-    return &HITLAgent{name: name, toolRegistry: registry}
+func NewHITLAgent(ctx context.Context, name string, registry *tools.ToolRegistry) *HITLAgent {
+    return &HITLAgent{ctx: ctx, name: name, toolRegistry: registry}
 }
 
 func (h *HITLAgent) Name() string { return h.name }
@@ -30,15 +32,15 @@ func (h *HITLAgent) Start(input <-chan model.Message, output chan<- model.Messag
         for msg := range input {
             metrics.AgentMessagesTotal.WithLabelValues(h.Name()).Inc()
             if msg.MessageType == model.TypeToolCall && msg.ToolCall != nil {
-                if h.ApproveTools {
+                if h.ApproveTools || h.requiresApproval(msg.ToolCall.Name) {
                     fmt.Printf("\n[Assistant suggests tool: %s] Args: %v\n", msg.ToolCall.Name, msg.ToolCall.Args)
                     fmt.Print("Approve tool execution? (y/n/edit): ")
                     userInput := waitForUserInput()
                     switch userInput {
                     case "y", "Y":
                         // approved, execute tool
-                        result := h.toolRegistry.CallTool(nil, *msg.ToolCall)
-                        output <- model.Message{Sender: h.name, Content: fmt.Sprintf("%v", result.Output), MessageType: model.TypeToolResult, ToolResult: &result}
+                        result := h.toolRegistry.CallTool(h.ctx, *msg.ToolCall)
+                        output <- h.resultMessage(msg, result)
                     case "edit":
                         fmt.Print("Edit tool call JSON: ")
                         raw := waitForUserInput()
@@ -48,16 +50,17 @@ func (h *HITLAgent) Start(input <-chan model.Message, output chan<- model.Messag
                             output <- model.Message{Sender: h.name, Content: "[TOOL] Invalid JSON, skipped.", MessageType: model.TypeToolResult}
                             continue
                         }
-                        result := h.toolRegistry.CallTool(nil, editedCall)
-                        output <- model.Message{Sender: h.name, Content: fmt.Sprintf("%v", result.Output), MessageType: model.TypeToolResult, ToolResult: &result}
+                        editedCall.Caller = msg.ToolCall.Caller // edits cannot escape the caller's scope
+                        result := h.toolRegistry.CallTool(h.ctx, editedCall)
+                        output <- h.resultMessage(msg, result)
                     default:
                         fmt.Println("Tool execution skipped.")
                         output <- model.Message{Sender: h.name, Content: "[TOOL] Execution skipped by user.", MessageType: model.TypeToolResult}
                     }
                 } else {
                     // Auto-approve: just execute the tool immediately
-                    result := h.toolRegistry.CallTool(nil, *msg.ToolCall)
-                    output <- model.Message{Sender: h.name, Content: fmt.Sprintf("%v", result.Output), MessageType: model.TypeToolResult, ToolResult: &result}
+                    result := h.toolRegistry.CallTool(h.ctx, *msg.ToolCall)
+                    output <- h.resultMessage(msg, result)
                 }
             } else {
                 utils.Logger.Debug().
@@ -70,6 +73,32 @@ func (h *HITLAgent) Start(input <-chan model.Message, output chan<- model.Messag
     }()
 }
 
+// resultMessage reports a tool result like the ToolRunner does, so errors
+// (including scope denials) go back to the agent that asked for the call.
+func (h *HITLAgent) resultMessage(call model.Message, result tools.ToolResult) model.Message {
+    content := fmt.Sprintf("%v", result.Output)
+    if result.Output == nil && result.Error != nil {
+        content = result.Error.Error()
+    }
+    return model.Message{
+        Sender:        h.name,
+        Content:       content,
+        MessageType:   model.TypeToolResult,
+        ToolResult:    &result,
+        IsError:       result.Error != nil,
+        Error:         result.Error,
+        OriginAgent:   call.OriginAgent,
+        OriginContent: call.OriginContent,
+    }
+}
+
+// requiresApproval reports whether the tool's metadata asks a human to
+// confirm every call, regardless of ApproveTools.
+func (h *HITLAgent) requiresApproval(tool string) bool {
+    t, ok := h.toolRegistry.Get(tool)
+    return ok && tools.MetadataOf(t).RequiresApproval
+}
+
 func (h *HITLAgent) BeginChat(manager *ChatManager, firstMessage model.Message)  {
     manager.InputChan() <- firstMessage
     utils.Logger.Debug().Str("agent", h.name).Msg("HITLAgent started chat session")
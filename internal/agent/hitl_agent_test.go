@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"aiupstart.com/go-gen/internal/model"
+	"aiupstart.com/go-gen/internal/tools"
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	// Keep test runs from writing run.log next to the package.
+	if err := utils.ConfigureLogger("error", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// approvalTool requires approval and records the context of its call.
+type approvalTool struct {
+	ctx chan context.Context
+}
+
+func (t *approvalTool) Name() string        { return "deploy" }
+func (t *approvalTool) Description() string { return "needs a human" }
+func (t *approvalTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *approvalTool) Metadata() tools.ToolMetadata {
+	return tools.ToolMetadata{RequiresApproval: true}
+}
+func (t *approvalTool) Call(ctx context.Context, call tools.ToolCall) tools.ToolResult {
+	t.ctx <- ctx
+	if err := ctx.Err(); err != nil {
+		return tools.ToolResult{Error: err}
+	}
+	return tools.ToolResult{Output: "deployed " + call.Args["target"].(string)}
+}
+
+// answerStdin makes the HITL prompt read answer from stdin.
+func answerStdin(t *testing.T, answer string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+	if _, err := w.WriteString(answer + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
+
+func TestHITLRunsApprovedCallWithContext(t *testing.T) {
+	answerStdin(t, "y")
+	registry := tools.NewToolRegistry()
+	tool := &approvalTool{ctx: make(chan context.Context, 1)}
+	registry.Register(tool)
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "session")
+	h := NewHITLAgent(ctx, "HITL", registry) // ApproveTools off: the tool's metadata asks
+	in := make(chan model.Message)
+	out := make(chan model.Message, 1)
+	h.Start(in, out)
+	defer close(in)
+
+	in <- model.Message{
+		Sender:      "Assistant",
+		MessageType: model.TypeToolCall,
+		ToolCall:    &tools.ToolCall{Name: "deploy", Args: map[string]interface{}{"target": "staging"}},
+		OriginAgent: "Assistant",
+	}
+
+	select {
+	case got := <-tool.ctx:
+		if got == nil || got.Value(key{}) != "session" {
+			t.Fatalf("tool called with context %v, want the agent's context", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool was not called")
+	}
+	select {
+	case msg := <-out:
+		if msg.MessageType != model.TypeToolResult || msg.IsError {
+			t.Fatalf("result = %+v, want a successful tool result", msg)
+		}
+		if msg.Content != "deployed staging" {
+			t.Errorf("content = %q, want %q", msg.Content, "deployed staging")
+		}
+		if msg.OriginAgent != "Assistant" {
+			t.Errorf("origin agent = %q, want Assistant", msg.OriginAgent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no tool result")
+	}
+}
+
+func TestHITLSkipsRejectedCall(t *testing.T) {
+	answerStdin(t, "n")
+	registry := tools.NewToolRegistry()
+	tool := &approvalTool{ctx: make(chan context.Context, 1)}
+	registry.Register(tool)
+
+	h := NewHITLAgent(context.Background(), "HITL", registry)
+	in := make(chan model.Message)
+	out := make(chan model.Message, 1)
+	h.Start(in, out)
+	defer close(in)
+
+	in <- model.Message{
+		Sender:      "Assistant",
+		MessageType: model.TypeToolCall,
+		ToolCall:    &tools.ToolCall{Name: "deploy", Args: map[string]interface{}{"target": "prod"}},
+	}
+	select {
+	case msg := <-out:
+		if msg.Content != "[TOOL] Execution skipped by user." {
+			t.Errorf("content = %q, want the skip notice", msg.Content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply")
+	}
+	select {
+	case <-tool.ctx:
+		t.Fatal("rejected tool was called")
+	default:
+	}
+}
@@ -1,13 +1,18 @@
 // internal/chat/manager.go
-// always starts flow with Orchestrator, then routes based on message type.
+// always starts flow with the entry agent (Orchestrator), then routes based on message type.
 package agent
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"aiupstart.com/go-gen/internal/metrics"
 	"aiupstart.com/go-gen/internal/model"
+	"aiupstart.com/go-gen/internal/tools"
 	"aiupstart.com/go-gen/internal/utils"
 )
 
@@ -29,8 +34,14 @@ type ChatManager struct {
 	tokenCount  int
 	maxTurns    int // e.g. 15
 	maxTokens   int // e.g. 20000
+    entry       string // agent receiving every input message
+    toolRoutes  *RoutingTable
     dockerContainerPrefix string
     errorHistory []string
+    done        chan struct{} // closed by Close
+    stopped     chan struct{} // closed when the manager goroutine exits
+    started     atomic.Bool
+    closeOnce   sync.Once
 }
 
 func NewChatManager(agentList []Agent) *ChatManager {
@@ -51,13 +62,78 @@ func NewChatManager(agentList []Agent) *ChatManager {
         input:        make(chan model.Message, 4),
         output:       make(chan model.Message, 4),
         history:      []model.Message{},
+        entry:      "Orchestrator",
         maxTurns:   10,
         maxTokens:  20000,
         turns:      0,
         tokenCount: 0,
+        done:       make(chan struct{}),
+        stopped:    make(chan struct{}),
     }
 }
 
+// SetLimits overrides the turn and token budgets of a conversation; values
+// <= 0 keep the current limit.
+func (cm *ChatManager) SetLimits(maxTurns, maxTokens int) {
+    if maxTurns > 0 {
+        cm.maxTurns = maxTurns
+    }
+    if maxTokens > 0 {
+        cm.maxTokens = maxTokens
+    }
+}
+
+// SetEntryAgent names the agent that receives every input message
+// (default "Orchestrator").
+func (cm *ChatManager) SetEntryAgent(name string) {
+    cm.entry = name
+}
+
+// SetToolRoutes installs the table choosing the agent that executes each
+// tool call.
+func (cm *ChatManager) SetToolRoutes(routes *RoutingTable) {
+    cm.toolRoutes = routes
+}
+
+// routeTool returns the agent executing calls of tool.
+func (cm *ChatManager) routeTool(tool string) (string, error) {
+    if cm.toolRoutes == nil {
+        return "", fmt.Errorf("no tool routing table set; cannot route tool %q", tool)
+    }
+    agentName, err := cm.toolRoutes.Route(tool)
+    if err != nil {
+        return "", err
+    }
+    if _, ok := cm.agentInputs[agentName]; !ok {
+        return "", fmt.Errorf("tool %q is routed to unknown agent %q", tool, agentName)
+    }
+    return agentName, nil
+}
+
+// recordDenial adds a tool call refused by the caller's tool scope to the
+// conversation history; the denial itself is counted by the registry.
+func (cm *ChatManager) recordDenial(call, result model.Message) {
+    err := result.Error
+    if err == nil && result.ToolResult != nil {
+        err = result.ToolResult.Error
+    }
+    if err == nil || !errors.Is(err, tools.ErrToolDenied) {
+        return
+    }
+    utils.Logger.Warn().
+        Str("agent", call.ToolCall.Caller).
+        Str("tool", call.ToolCall.Name).
+        Msg("Tool call denied")
+    cm.history = append(cm.history, model.Message{
+        Sender:      "Manager",
+        Content:     "[DENIED] " + err.Error(),
+        MessageType: model.TypeToolResult,
+        IsError:     true,
+        Error:       err,
+        OriginAgent: call.ToolCall.Caller,
+    })
+}
+
 // Start initializes the ChatManager by setting up dedicated input and output channels
 // for each agent and launching their processing goroutines. It also starts a manager
 // goroutine that listens for incoming messages, updates the conversation history,
@@ -66,21 +142,31 @@ func NewChatManager(agentList []Agent) *ChatManager {
 // and sends the response to the output channel. This enables concurrent, coordinated
 // communication between the manager and multiple agents.
 func (cm *ChatManager) Start() {
+	if !cm.started.CompareAndSwap(false, true) {
+		return // already started, or closed
+	}
 	utils.Logger.Debug().Msg(fmt.Sprintf("Starting ChatManager with agents: %d", len(cm.agents)))
 
     go func() {
-        
+		defer close(cm.stopped)
 		for {
-			msg := <-cm.input
+			var msg model.Message
+			select {
+			case msg = <-cm.input:
+			case <-cm.done:
+				return
+			}
 			utils.Logger.Debug().
 				Str("sender", msg.Sender).
-				Msgf("Manager received message: %s, now routing to [Orchestrator]", msg.Content)
+				Msgf("Manager received message: %s, now routing to [%s]", msg.Content, cm.entry)
 			metrics.AgentMessagesTotal.WithLabelValues("Manager").Inc()
 			cm.history = append(cm.history, msg)
 			
-			// Start by always sending to Orchestrator
-			cm.agentInputs["Orchestrator"] <- msg
-			resp := <-cm.agentOutputs["Orchestrator"]
+			// Start by always sending to the entry agent (the Orchestrator)
+			resp, ok := cm.exchange(cm.entry, msg)
+			if !ok {
+				return
+			}
 			utils.Logger.Debug().
 				Str("sender", resp.Sender).
 				Msgf("Manager received response: %s", resp.Content)
@@ -92,19 +178,19 @@ func (cm *ChatManager) Start() {
                 if cm.turns >= cm.maxTurns {
 					utils.Logger.Warn().
 						Msgf("[ChatManager] Cycle limit reached (%d turns) - halting conversation.", cm.maxTurns)
-					cm.output <- model.Message{
+					cm.emit(model.Message{
 						Sender:  "Manager",
 						Content: fmt.Sprintf("Conversation stopped: maximum of %d turns reached.", cm.maxTurns),
-					}
+					})
 					break
 				}
 				if cm.tokenCount >= cm.maxTokens {
 					utils.Logger.Warn().
 						Msgf("[ChatManager] Token limit reached (%d tokens) - halting conversation.", cm.maxTokens)
-					cm.output <- model.Message{
+					cm.emit(model.Message{
 						Sender:  "Manager",
 						Content: fmt.Sprintf("Conversation stopped: maximum of %d tokens used.", cm.maxTokens),
-					}
+					})
 					break
 				}
 
@@ -118,12 +204,12 @@ func (cm *ChatManager) Start() {
 
 				// --- Tool Call: Route to ToolRunner ---
 				if resp.MessageType == model.TypeToolCall && resp.ToolCall != nil {
-					toolAgent := ToolNameToAgent(resp.ToolCall.Name)
-					utils.Logger.Debug().
-						Str("tool", resp.ToolCall.Name).
-						Msgf("Routing tool call to agent %s", toolAgent)
+					toolAgent, err := cm.routeTool(resp.ToolCall.Name)
+					if err == nil {
+						utils.Logger.Debug().
+							Str("tool", resp.ToolCall.Name).
+							Msgf("Routing tool call to agent %s", toolAgent)
 
-					if inChan, ok := cm.agentInputs[toolAgent]; ok {
 						toolMsg := resp
                         // Set origin agent/content on tool call message
                         if toolMsg.OriginAgent == "" { toolMsg.OriginAgent = resp.Sender }
@@ -137,14 +223,17 @@ func (cm *ChatManager) Start() {
                                 toolMsg.OriginContent = resp.Content
                             }
                         }
-                        inChan <- toolMsg
-						resp = <-cm.agentOutputs[toolAgent]
+                        if resp, ok = cm.exchange(toolAgent, toolMsg); !ok {
+                            return
+                        }
+                        cm.recordDenial(toolMsg, resp)
 						continue // chain: check next response
 					} else {
 						utils.Logger.Error().
 							Str("tool", resp.ToolCall.Name).
-							Msgf("[ERROR] Unknown tool agent: %s", toolAgent)
-						cm.output <- model.Message{Sender: "Manager", Content: "[ERROR] Unknown tool agent: " + toolAgent}
+							Err(err).
+							Msg("[ERROR] Cannot route tool call")
+						cm.emit(model.Message{Sender: "Manager", Content: "[ERROR] Cannot route tool call: " + err.Error()})
 						break
 					}
 				}
@@ -199,12 +288,13 @@ func (cm *ChatManager) Start() {
                         RouteTarget: targetAgent,
                     }
 
-                    if inChan, ok := cm.agentInputs[targetAgent]; ok {
-                        inChan <- fixMsg
-                        resp = <-cm.agentOutputs[targetAgent]
+                    if _, ok := cm.agentInputs[targetAgent]; ok {
+                        if resp, ok = cm.exchange(targetAgent, fixMsg); !ok {
+                            return
+                        }
                         continue // chain: check next response
                     } else {
-                        cm.output <- model.Message{Sender: "Manager", Content: "[ERROR] Could not find origin agent: " + targetAgent}
+                        cm.emit(model.Message{Sender: "Manager", Content: "[ERROR] Could not find origin agent: " + targetAgent})
                         break
                     }
                 }
@@ -215,15 +305,16 @@ func (cm *ChatManager) Start() {
 					utils.Logger.Debug().
 						Str("task", resp.Content).
 						Msgf("Routing task to agent %s", agentName)
-					if inChan, ok := cm.agentInputs[agentName]; ok {
-						inChan <- resp
-						resp = <-cm.agentOutputs[agentName]
+					if _, ok := cm.agentInputs[agentName]; ok {
+						if resp, ok = cm.exchange(agentName, resp); !ok {
+							return
+						}
 						continue // chain: check next response
 					} else {
 						utils.Logger.Error().
 							Str("agent", agentName).
 							Msgf("[ERROR] Unknown agent: %s", agentName)
-						cm.output <- model.Message{Sender: "Manager", Content: "[ERROR] Unknown agent: " + agentName}
+						cm.emit(model.Message{Sender: "Manager", Content: "[ERROR] Unknown agent: " + agentName})
 						break
 					}
 				}
@@ -233,13 +324,67 @@ func (cm *ChatManager) Start() {
 				utils.Logger.Debug().
 					Str("sender", resp.Sender).
 					Msgf("Final output from agent: %s", resp.Content)
-				cm.output <- resp
+				cm.emit(resp)
 				break
 			}
 		}
 	}()
 }
 
+// exchange sends msg to an agent and waits for its reply; ok is false if the
+// manager was closed meanwhile.
+func (cm *ChatManager) exchange(agentName string, msg model.Message) (model.Message, bool) {
+	select {
+	case cm.agentInputs[agentName] <- msg:
+	case <-cm.done:
+		return model.Message{}, false
+	}
+	select {
+	case resp := <-cm.agentOutputs[agentName]:
+		return resp, true
+	case <-cm.done:
+		return model.Message{}, false
+	}
+}
+
+// emit delivers a final message unless the manager is closing.
+func (cm *ChatManager) emit(msg model.Message) {
+	select {
+	case cm.output <- msg:
+	case <-cm.done:
+	}
+}
+
+// Close stops routing and closes every agent input so the agent goroutines
+// exit. The inputs are closed only after the manager goroutine has exited,
+// since it may be sending to them; Close waits for that until ctx is done and
+// leaves the closing to a goroutine otherwise. In-flight LLM or tool calls
+// finish on their own; their replies are dropped.
+func (cm *ChatManager) Close(ctx context.Context) error {
+	var err error
+	cm.closeOnce.Do(func() {
+		close(cm.done)
+		if cm.started.CompareAndSwap(false, true) {
+			close(cm.stopped) // never started
+		}
+		inputsClosed := make(chan struct{})
+		go func() {
+			<-cm.stopped
+			for name, in := range cm.agentInputs {
+				close(in)
+				utils.Logger.Debug().Str("agent", name).Msg("Closed agent input")
+			}
+			close(inputsClosed)
+		}()
+		select {
+		case <-inputsClosed:
+		case <-ctx.Done():
+			err = fmt.Errorf("chat manager did not stop: %w", ctx.Err())
+		}
+	})
+	return err
+}
+
 // func (cm *ChatManager) Send(msg model.Message) {
 // 	cm.input <- msg
 // }
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aiupstart.com/go-gen/internal/model"
+)
+
+// stuckAgent reads nothing until release is closed, then drains its input
+// and reports when the input is closed.
+type stuckAgent struct {
+	release chan struct{}
+	exited  chan struct{}
+}
+
+func (a *stuckAgent) Name() string { return "Orchestrator" }
+func (a *stuckAgent) Start(input <-chan model.Message, output chan<- model.Message) {
+	<-a.release
+	for range input {
+	}
+	close(a.exited)
+}
+
+func TestChatManagerCloseWhileSendingToAgent(t *testing.T) {
+	// The manager goroutine is blocked sending to a full agent input when
+	// Close gives up waiting; closing that input under it would panic.
+	for i := 0; i < 50; i++ {
+		a := &stuckAgent{release: make(chan struct{}), exited: make(chan struct{})}
+		cm := NewChatManager([]Agent{a})
+		cm.Start()
+		for j := 0; j < cap(cm.agentInputs[a.Name()]); j++ {
+			cm.AgentInputChan(a.Name()) <- model.Message{Content: "queued"}
+		}
+		cm.Send(model.Message{Sender: "User", Content: "hello"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cm.Close(ctx)
+		close(a.release)
+		select {
+		case <-a.exited:
+		case <-time.After(5 * time.Second):
+			t.Fatal("agent input was never closed")
+		}
+	}
+}
+
+func TestChatManagerCloseWithoutStart(t *testing.T) {
+	a := &stuckAgent{release: make(chan struct{}), exited: make(chan struct{})}
+	close(a.release)
+	cm := NewChatManager([]Agent{a})
+	if err := cm.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-a.exited
+	cm.Start() // a no-op after Close
+}
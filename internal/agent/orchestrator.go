@@ -18,9 +18,9 @@ You are an orchestration agent for an AI multi-agent system.
 Your role is to read the user’s request and decide **which agent** should handle it next.
 
 Given a user request, plan the required subtasks, and for each:
-- If code must be generated, assign to the "Assistant" agent.
+- If code must be generated, assign to the "%[3]s" agent.
 - If the next action is to execute a tool, send to the ToolRunnerAgent.
-- If the code fails verification, send the error and original task back to "Assistant" for correction and retry.
+- If the code fails verification, send the error and original task back to "%[3]s" for correction and retry.
 - Repeat until the code runs successfully or user stops.
 
 Reply ONLY with a JSON object in the format:
@@ -28,10 +28,10 @@ Reply ONLY with a JSON object in the format:
 - To verify: {"tool": "docker_exec", "args": { "language": "...", "code": "...", ... }}
 
 Agents:
-%s
+%[1]s
 
 User's request:
-"%s"
+"%[2]s"
 `
 
 // MessageType additions for routing/direct
@@ -57,12 +57,26 @@ type OrchestratorAgent struct {
     name      string
     manager   *ChatManager  // for sending route requests
     agentList    []Agent
+    descriptions map[string]string // agent name -> when to route there
+    fallback     string            // gets tasks the LLM reply does not assign
     strategy  func(request model.Message, agents []Agent) int
 	llmClient llm.LLMClient
 }
 
 func NewOrchestratorAgent(name string, manager *ChatManager, agentList []Agent, llmClient llm.LLMClient) *OrchestratorAgent {
-    return &OrchestratorAgent{name: name, manager: manager, agentList: agentList, llmClient: llmClient}
+    return &OrchestratorAgent{name: name, manager: manager, agentList: agentList, llmClient: llmClient, fallback: "Assistant"}
+}
+
+// SetAgentDescriptions adds a routing hint per agent name to the agent list
+// shown to the LLM.
+func (o *OrchestratorAgent) SetAgentDescriptions(descriptions map[string]string) {
+    o.descriptions = descriptions
+}
+
+// SetFallbackAgent names the agent receiving tasks when the LLM reply cannot
+// be parsed or the LLM fails (default "Assistant").
+func (o *OrchestratorAgent) SetFallbackAgent(name string) {
+    o.fallback = name
 }
 
 func (o *OrchestratorAgent) Name() string { return o.name }
@@ -81,9 +95,13 @@ func (o *OrchestratorAgent) Start(input <-chan model.Message, output chan<- mode
                 Msgf("Received: %s", msg.Content)
             agentListStr := ""
             for _, a := range o.agentList {
-                agentListStr += fmt.Sprintf("- %s\n", a.Name())
+                if d := o.descriptions[a.Name()]; d != "" {
+                    agentListStr += fmt.Sprintf("- %s: %s\n", a.Name(), d)
+                } else {
+                    agentListStr += fmt.Sprintf("- %s\n", a.Name())
+                }
             }
-            prompt := fmt.Sprintf(orchestrationPrompt, agentListStr, msg.Content)
+            prompt := fmt.Sprintf(orchestrationPrompt, agentListStr, msg.Content, o.fallback)
             llmResp, err := o.llmClient.Generate(prompt)
             if err != nil {
                 fmt.Println("[Orchestrator LLM ERROR]:", err)
@@ -91,7 +109,7 @@ func (o *OrchestratorAgent) Start(input <-chan model.Message, output chan<- mode
                     Sender:      o.name,
                     Content:     "[Orchestrator LLM ERROR]: " + err.Error(),
                     MessageType: model.TypeRoute,
-                    RouteTarget: "Assistant",
+                    RouteTarget: o.fallback,
                 }
                 continue
             }
@@ -137,7 +155,7 @@ func (o *OrchestratorAgent) Start(input <-chan model.Message, output chan<- mode
             var routeResp LLMOrchAgentResponse
             if err := json.Unmarshal([]byte(llmResp.Content), &routeResp); err != nil {
                 // fallback to assistant
-                routeResp.Agent = "Assistant"
+                routeResp.Agent = o.fallback
                 routeResp.Subtask = msg.Content
             }
             // Ask manager to route to the chosen agent
@@ -150,16 +168,3 @@ func (o *OrchestratorAgent) Start(input <-chan model.Message, output chan<- mode
         }
     }()
 }
-
-
-func ToolNameToAgent(tool string) string {
-    switch tool {
-    case "docker_exec":
-        return "ToolRunner" // Change to your docker agent name
-    case "stripe_mcp":
-        return "HITL" // Or the agent handling Stripe MCP
-    // Add more as needed
-    default:
-        return "Assistant"
-    }
-}
\ No newline at end of file
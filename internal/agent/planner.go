@@ -10,25 +10,32 @@ import (
 
 // Planner is an agent that plans task steps using an LLM client.
 type Planner struct {
-    name      string
-    llmClient llm.LLMClient
+    name         string
+    llmClient    llm.LLMClient
+    instructions string
 }
 
+const defaultPlannerInstructions = "As a planner agent, break down the following user task into actionable steps:"
+
 func NewPlanner(name string, llmClient llm.LLMClient) *Planner {
     return &Planner{
-        name:      name,
-        llmClient: llmClient,
+        name:         name,
+        llmClient:    llmClient,
+        instructions: defaultPlannerInstructions,
     }
 }
 
 func (p *Planner) Name() string { return p.name }
 
+// SetInstructions replaces the text put before each task in the LLM prompt.
+func (p *Planner) SetInstructions(instructions string) { p.instructions = instructions }
+
 // Start launches the planner's asynchronous message loop.
 func (p *Planner) Start(input <-chan model.Message, output chan<- model.Message) {
     go func() {
         for msg := range input {
             // Compose the LLM prompt based on incoming message
-            prompt := fmt.Sprintf("As a planner agent, break down the following user task into actionable steps:\n\n%s", msg.Content)
+            prompt := fmt.Sprintf("%s\n\n%s", p.instructions, msg.Content)
             
             // Call the LLM client to generate a plan
             llmResponse, err := p.llmClient.Generate(prompt)
@@ -1,4 +1,4 @@
-// internal/agent/planner.go
+// internal/agent/researcher.go
 package agent
 
 import (
@@ -8,35 +8,42 @@ import (
 	"aiupstart.com/go-gen/internal/model"
 )
 
+// Researcher answers research questions using an LLM client.
 type Researcher struct {
     name string
 	llmClient llm.LLMClient
+    instructions string
 }
 
+const defaultResearcherInstructions = "As a research agent, answer the following question. Summarize what is known, cite your sources and say where evidence is missing:"
+
 func NewResearcher(name string, llmClient llm.LLMClient) *Researcher {
-    return &Researcher{name: name, llmClient: llmClient}
+    return &Researcher{name: name, llmClient: llmClient, instructions: defaultResearcherInstructions}
 }
 
 func (p *Researcher) Name() string {
     return p.name
 }
 
+// SetInstructions replaces the text put before each question in the LLM prompt.
+func (p *Researcher) SetInstructions(instructions string) { p.instructions = instructions }
+
 func (p *Researcher) Start(input <-chan model.Message, output chan<- model.Message) {
     go func() {
         for msg := range input {
 			// Compose the LLM prompt based on incoming message
-            prompt := fmt.Sprintf("As a planner agent, break down the following user task into actionable steps:\n\n%s", msg.Content)
+            prompt := fmt.Sprintf("%s\n\n%s", p.instructions, msg.Content)
             
-            // Call the LLM client to generate a plan
+            // Call the LLM client to generate an answer
             llmResponse, err := p.llmClient.Generate(prompt)
             var reply string
             if err != nil {
-                reply = fmt.Sprintf("[Planner error]: %v", err)
+                reply = fmt.Sprintf("[Researcher error]: %v", err)
             } else {
                 reply = llmResponse.Content
             }
 
-            // Send the plan (or error) out
+            // Send the answer (or error) out
             output <- model.Message{
                 Sender:  p.name,
                 Content: reply,
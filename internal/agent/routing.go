@@ -0,0 +1,148 @@
+// internal/agent/routing.go
+// Tool routing: which agent executes a tool call.
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"aiupstart.com/go-gen/internal/tools"
+)
+
+// RoutingTable picks the agent executing each tool call. A call goes to, in
+// order:
+//  1. the first explicit route whose tool pattern matches (team routes),
+//  2. the executor named by the tool's metadata,
+//  3. a hitl agent, if the tool requires approval,
+//  4. the first executor listing a matching tool pattern,
+//  5. the first tool runner without tool patterns.
+//
+// Tools are looked up in the registry when a call is routed, so tools
+// registered later (MCP servers, reloads) are routed too.
+type RoutingTable struct {
+	mu        sync.RWMutex
+	registry  *tools.ToolRegistry
+	routes    []toolRoute
+	executors []executor
+}
+
+type toolRoute struct {
+	pattern string
+	agent   string
+}
+
+type executor struct {
+	name     string
+	hitl     bool     // asks a human before running calls
+	patterns []string // tools it runs; empty runs any tool
+}
+
+func NewRoutingTable(registry *tools.ToolRegistry) *RoutingTable {
+	return &RoutingTable{registry: registry}
+}
+
+// AddExecutor declares an agent that runs tool calls. hitl agents receive
+// the tools requiring approval; patterns (globs) restrict the tools it runs.
+func (t *RoutingTable) AddExecutor(name string, hitl bool, patterns []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.executors = append(t.executors, executor{name: name, hitl: hitl, patterns: patterns})
+}
+
+// AddRoute sends calls of the tools matching pattern (a name or glob) to
+// agent, ahead of metadata and executor patterns.
+func (t *RoutingTable) AddRoute(pattern, agent string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.routes = append(t.routes, toolRoute{pattern: pattern, agent: agent})
+}
+
+// Route returns the agent executing calls of the named tool, or an error
+// saying why no agent can run it.
+func (t *RoutingTable) Route(tool string) (string, error) {
+	registered, ok := t.registry.Get(tool)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q: it is not registered", tool)
+	}
+	meta := tools.MetadataOf(registered)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	target, source := "", ""
+	for _, r := range t.routes {
+		if tools.MatchToolName([]string{r.pattern}, tool) {
+			target, source = r.agent, fmt.Sprintf("route %q", r.pattern)
+			break
+		}
+	}
+	if target == "" && meta.Executor != "" {
+		target, source = meta.Executor, "tool metadata"
+	}
+	if target != "" {
+		e, ok := t.executor(target)
+		if !ok {
+			return "", fmt.Errorf("tool %q: %s names agent %q, which does not run tools in this team", tool, source, target)
+		}
+		// Executors only run the tools they list and the routes naming them.
+		if source == "tool metadata" && len(e.patterns) > 0 && !tools.MatchToolName(e.patterns, tool) {
+			return "", fmt.Errorf("tool %q: tool metadata names agent %q, whose tools do not include it", tool, target)
+		}
+		if meta.RequiresApproval && !e.hitl {
+			return "", fmt.Errorf("tool %q requires approval but %s sends it to %q, which is not a hitl agent", tool, source, target)
+		}
+		return target, nil
+	}
+
+	if meta.RequiresApproval {
+		for _, e := range t.executors {
+			if e.hitl && (len(e.patterns) == 0 || tools.MatchToolName(e.patterns, tool)) {
+				return e.name, nil
+			}
+		}
+		return "", fmt.Errorf("tool %q requires approval but the team has no hitl agent for it", tool)
+	}
+	for _, e := range t.executors {
+		if len(e.patterns) > 0 && tools.MatchToolName(e.patterns, tool) {
+			return e.name, nil
+		}
+	}
+	for _, e := range t.executors {
+		if !e.hitl && len(e.patterns) == 0 {
+			return e.name, nil
+		}
+	}
+	return "", fmt.Errorf("no agent runs tool %q: add it to the tools of a tool_runner agent or to the team's routes", tool)
+}
+
+// Resolve routes every registered tool accepted by include (nil: all),
+// returning tool -> agent and one error per tool without a route.
+func (t *RoutingTable) Resolve(include func(tool string) bool) (map[string]string, []error) {
+	names := make([]string, 0)
+	for _, tool := range t.registry.List() {
+		if include == nil || include(tool.Name()) {
+			names = append(names, tool.Name())
+		}
+	}
+	sort.Strings(names)
+	routes := map[string]string{}
+	var errs []error
+	for _, name := range names {
+		agent, err := t.Route(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		routes[name] = agent
+	}
+	return routes, errs
+}
+
+func (t *RoutingTable) executor(name string) (executor, bool) {
+	for _, e := range t.executors {
+		if e.name == name {
+			return e, true
+		}
+	}
+	return executor{}, false
+}
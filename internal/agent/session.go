@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// Session owns a ChatManager plus everything that must be released when the
+// run ends (containers, pools, servers, log files). Closers run in reverse
+// registration order, so register dependencies before their users.
+type Session struct {
+	Manager *ChatManager
+
+	mu      sync.Mutex
+	closers []namedCloser
+	closed  bool
+}
+
+type namedCloser struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func NewSession(manager *ChatManager) *Session {
+	return &Session{Manager: manager}
+}
+
+// OnClose registers fn to run when the session closes.
+func (s *Session) OnClose(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closers = append(s.closers, namedCloser{name: name, fn: fn})
+}
+
+// Close stops the manager and runs every closer, even if earlier ones fail or
+// ctx expires; each closer is expected to honour ctx itself. Close is safe to
+// call more than once.
+func (s *Session) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	closers := s.closers
+	s.mu.Unlock()
+
+	var errs []error
+	if s.Manager != nil {
+		if err := s.Manager.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		c := closers[i]
+		utils.Logger.Debug().Str("closer", c.name).Msg("Closing session resource")
+		if err := c.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
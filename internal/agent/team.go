@@ -0,0 +1,148 @@
+// internal/agent/team.go
+// Builds a ChatManager from a declarative team definition.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/llm"
+	"aiupstart.com/go-gen/internal/tools"
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// TeamLLMFactory returns the LLM client of one team agent, using its
+// provider and model and offering only the tools it may use.
+type TeamLLMFactory func(a config.TeamAgentConfig) (llm.LLMClient, error)
+
+// BuildTeam creates the agents of team and a ChatManager starting every
+// conversation at the team's entry agent. The tools and deny_tools of
+// agents calling tools become their registry scopes (ToolRegistry.SetScope);
+// an executor listing tools is scoped to them and to the routes naming it.
+// Assistants and executors get a scoped view of registry. Tool calls are
+// routed by a RoutingTable built from the team's routes and executor
+// agents; a tool an assistant may call but no agent can run is an error.
+// Tool calls of the tool_runner and hitl agents run under ctx.
+func BuildTeam(ctx context.Context, team *config.TeamConfig, registry *tools.ToolRegistry, newLLM TeamLLMFactory) (*ChatManager, error) {
+	if err := config.ValidateTeam(team); err != nil {
+		return nil, err
+	}
+	clients := map[string]llm.LLMClient{}
+	for _, a := range team.Agents {
+		if !a.UsesLLM() {
+			continue
+		}
+		client, err := newLLM(a)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", a.Name, err)
+		}
+		clients[a.Name] = client
+	}
+
+	var (
+		agents       []Agent
+		orchestrator *config.TeamAgentConfig
+		fallback     string // first assistant, else first LLM agent
+		firstLLM     string
+		executors    []config.TeamAgentConfig
+	)
+	descriptions := map[string]string{}
+	routed := map[string][]string{} // executor -> tool patterns routed to it
+	for _, r := range team.Routes {
+		routed[r.Agent] = append(routed[r.Agent], r.Tool)
+	}
+	for i, a := range team.Agents {
+		descriptions[a.Name] = a.Description
+		switch {
+		case a.IsExecutor() && len(a.Tools) > 0:
+			allow := append(append([]string(nil), a.Tools...), routed[a.Name]...)
+			registry.SetScope(a.Name, tools.ToolScope{Allow: allow})
+		case !a.IsExecutor() && (len(a.Tools) > 0 || len(a.DenyTools) > 0):
+			registry.SetScope(a.Name, tools.ToolScope{Allow: a.Tools, Deny: a.DenyTools})
+		}
+		switch a.Type {
+		case config.AgentTypeOrchestrator:
+			orchestrator = &team.Agents[i]
+			continue
+		case config.AgentTypeAssistant:
+			agents = append(agents, NewAssistantAgent(a.Name, clients[a.Name], a.Prompt, registry.Scoped(a.Name)))
+			if fallback == "" {
+				fallback = a.Name
+			}
+		case config.AgentTypeToolRunner:
+			agents = append(agents, NewToolRunnerAgent(ctx, a.Name, registry.Scoped(a.Name)))
+			executors = append(executors, a)
+		case config.AgentTypeHITL:
+			hitl := NewHITLAgent(ctx, a.Name, registry.Scoped(a.Name))
+			hitl.ApproveTools = a.ApproveTools
+			agents = append(agents, hitl)
+			executors = append(executors, a)
+		case config.AgentTypePlanner:
+			planner := NewPlanner(a.Name, clients[a.Name])
+			if a.Prompt != "" {
+				planner.SetInstructions(a.Prompt)
+			}
+			agents = append(agents, planner)
+		case config.AgentTypeResearcher:
+			researcher := NewResearcher(a.Name, clients[a.Name])
+			if a.Prompt != "" {
+				researcher.SetInstructions(a.Prompt)
+			}
+			agents = append(agents, researcher)
+		}
+		if firstLLM == "" && a.UsesLLM() {
+			firstLLM = a.Name
+		}
+	}
+	if fallback == "" {
+		fallback = firstLLM
+	}
+
+	var orch *OrchestratorAgent
+	if orchestrator != nil {
+		orch = NewOrchestratorAgent(orchestrator.Name, nil, agents, clients[orchestrator.Name])
+		orch.SetAgentDescriptions(descriptions)
+		if fallback != "" {
+			orch.SetFallbackAgent(fallback)
+		}
+		agents = append([]Agent{orch}, agents...)
+	}
+
+	routes := NewRoutingTable(registry)
+	for _, r := range team.Routes {
+		routes.AddRoute(r.Tool, r.Agent)
+	}
+	for _, e := range executors {
+		routes.AddExecutor(e.Name, e.Type == config.AgentTypeHITL, e.Tools)
+	}
+	// Every tool an assistant may call needs an agent to run it.
+	resolved, errs := routes.Resolve(func(tool string) bool {
+		for _, a := range team.Agents {
+			scope := tools.ToolScope{Allow: a.Tools, Deny: a.DenyTools}
+			if a.Type == config.AgentTypeAssistant && scope.Permits(tool) {
+				return true
+			}
+		}
+		return false
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("tool routing: %w", errors.Join(errs...))
+	}
+	for tool, agentName := range resolved {
+		utils.Logger.Debug().Str("tool", tool).Str("agent", agentName).Msg("Tool route")
+	}
+
+	manager := NewChatManager(agents)
+	manager.SetEntryAgent(team.EntryAgent())
+	manager.SetToolRoutes(routes)
+	if orch != nil {
+		orch.SetManager(manager)
+	}
+	utils.Logger.Debug().
+		Str("team", team.Name).
+		Str("entry", team.EntryAgent()).
+		Msgf("Built team with %d agents", len(agents))
+	return manager, nil
+}
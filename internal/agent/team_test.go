@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/tools"
+)
+
+// approvalOnly requires approval without naming an executor.
+type approvalOnly struct{ countingTool }
+
+func (t *approvalOnly) Metadata() tools.ToolMetadata {
+	return tools.ToolMetadata{RequiresApproval: true}
+}
+
+func TestBuildTeamInvalid(t *testing.T) {
+	assistant := config.TeamAgentConfig{Name: "Coder", Type: config.AgentTypeAssistant}
+	runner := config.TeamAgentConfig{Name: "Runner", Type: config.AgentTypeToolRunner}
+	hitl := config.TeamAgentConfig{Name: "HITL", Type: config.AgentTypeHITL}
+	tests := []struct {
+		name  string
+		team  config.TeamConfig
+		tools []tools.Tool
+		want  string
+	}{
+		{
+			name: "no agents",
+			want: "agents: a team needs at least one agent",
+		},
+		{
+			name: "unknown entry agent",
+			team: config.TeamConfig{Entry: "Lead", Agents: []config.TeamAgentConfig{assistant, runner}},
+			want: `entry: entry agent "Lead" is not in the team`,
+		},
+		{
+			name: "duplicate agent",
+			team: config.TeamConfig{Agents: []config.TeamAgentConfig{assistant, runner, assistant}},
+			want: `agents[2].name: duplicate agent name "Coder"`,
+		},
+		{
+			name: "reserved name",
+			team: config.TeamConfig{Agents: []config.TeamAgentConfig{{Name: "Manager", Type: config.AgentTypeAssistant}}},
+			want: `agents[0].name: "Manager" is reserved`,
+		},
+		{
+			name: "route to a missing agent",
+			team: config.TeamConfig{
+				Agents: []config.TeamAgentConfig{assistant, runner},
+				Routes: []config.TeamRouteConfig{{Tool: "mcp__*", Agent: "Ghost"}},
+			},
+			want: `routes[0].agent: agent "Ghost" is not in the team`,
+		},
+		{
+			// Routing a call to an agent that calls tools would send its
+			// own calls back to it.
+			name: "route back to a calling agent",
+			team: config.TeamConfig{
+				Agents: []config.TeamAgentConfig{assistant, runner},
+				Routes: []config.TeamRouteConfig{{Tool: "docker_exec", Agent: "Coder"}},
+			},
+			want: `routes[0].agent: agent "Coder" does not run tools (want a tool_runner or hitl agent)`,
+		},
+		{
+			name: "two orchestrators",
+			team: config.TeamConfig{Agents: []config.TeamAgentConfig{
+				{Name: "A", Type: config.AgentTypeOrchestrator},
+				{Name: "B", Type: config.AgentTypeOrchestrator},
+			}},
+			want: "agents[1].type: a team can have only one orchestrator",
+		},
+		{
+			name:  "tool no agent runs",
+			team:  config.TeamConfig{Agents: []config.TeamAgentConfig{assistant, {Name: "Reader", Type: config.AgentTypeToolRunner, Tools: []string{"workspace_*"}}}},
+			tools: []tools.Tool{&countingTool{name: "docker_exec"}},
+			want:  `tool routing: no agent runs tool "docker_exec"`,
+		},
+		{
+			name:  "approval without a hitl agent",
+			team:  config.TeamConfig{Agents: []config.TeamAgentConfig{assistant, runner}},
+			tools: []tools.Tool{&approvalOnly{countingTool{name: "deploy"}}},
+			want:  `tool "deploy" requires approval but the team has no hitl agent for it`,
+		},
+		{
+			name: "approval routed past the hitl agent",
+			team: config.TeamConfig{
+				Agents: []config.TeamAgentConfig{assistant, runner, hitl},
+				Routes: []config.TeamRouteConfig{{Tool: "deploy", Agent: "Runner"}},
+			},
+			tools: []tools.Tool{&approvalOnly{countingTool{name: "deploy"}}},
+			want:  `tool "deploy" requires approval but route "deploy" sends it to "Runner", which is not a hitl agent`,
+		},
+		{
+			name:  "metadata names a missing executor",
+			team:  config.TeamConfig{Agents: []config.TeamAgentConfig{assistant, runner}},
+			tools: []tools.Tool{&pinnedTool{countingTool: countingTool{name: "deploy"}, executor: "Ops"}},
+			want:  `tool "deploy": tool metadata names agent "Ops", which does not run tools in this team`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tools.NewToolRegistry()
+			for _, tool := range tt.tools {
+				registry.Register(tool)
+			}
+			_, err := BuildTeam(context.Background(), &tt.team, registry, noLLM)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutingTablePrecedence(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	for _, tool := range []tools.Tool{
+		&countingTool{name: "workspace_read"},
+		&countingTool{name: "docker_exec"},
+		&countingTool{name: "create_issue"},
+		&pinnedTool{countingTool: countingTool{name: "mcp__github__merge"}, executor: "Shell"},
+		&approvalOnly{countingTool{name: "deploy"}},
+	} {
+		registry.Register(tool)
+	}
+	routes := NewRoutingTable(registry)
+	routes.AddRoute("create_*", "HITL")
+	routes.AddRoute("mcp__github__*", "Reader") // routes win over metadata
+	routes.AddExecutor("Reader", false, []string{"workspace_*"})
+	routes.AddExecutor("HITL", true, nil)
+	routes.AddExecutor("Shell", false, nil)
+
+	for tool, want := range map[string]string{
+		"create_issue":       "HITL",   // route
+		"mcp__github__merge": "Reader", // route ahead of metadata
+		"deploy":             "HITL",   // requires approval
+		"workspace_read":     "Reader", // executor pattern
+		"docker_exec":        "Shell",  // catch-all tool runner
+	} {
+		if got, err := routes.Route(tool); err != nil || got != want {
+			t.Errorf("Route(%s) = %q, %v, want %q", tool, got, err, want)
+		}
+	}
+	if _, err := routes.Route("missing"); err == nil || !strings.Contains(err.Error(), `unknown tool "missing"`) {
+		t.Errorf("Route(missing) err = %v", err)
+	}
+}
+
+// teamMcpConfig writes an mcp_tools.yaml with one operation per tool name,
+// run by executor.
+func teamMcpConfig(t *testing.T, path, executor string, names ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("mcp_tools:\n- name: ops\n  endpoint: http://127.0.0.1:1\n")
+	if executor != "" {
+		b.WriteString("  executor: " + executor + "\n")
+	}
+	b.WriteString("  operations:\n")
+	for _, name := range names {
+		b.WriteString("    - name: " + name + "\n      path: /" + name + "\n      parameters: {type: object}\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTeamRoutesReloadedTools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp_tools.yaml")
+	teamMcpConfig(t, path, "", "list_items")
+	registry := tools.NewToolRegistry()
+	reloader := tools.NewToolReloader(registry, "", path, nil)
+	if err := reloader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	team := &config.TeamConfig{Agents: []config.TeamAgentConfig{
+		{Name: "Coder", Type: config.AgentTypeAssistant, Tools: []string{"list_items", "delete_item"}},
+		{Name: "Runner", Type: config.AgentTypeToolRunner},
+		{Name: "Ops", Type: config.AgentTypeToolRunner},
+	}}
+	manager, err := BuildTeam(context.Background(), team, registry, noLLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := manager.toolRoutes.Route("list_items"); err != nil || got != "Runner" {
+		t.Fatalf("before reload: Route(list_items) = %q, %v", got, err)
+	}
+
+	// The reload adds a tool and pins both to Ops; routing follows.
+	teamMcpConfig(t, path, "Ops", "list_items", "delete_item")
+	if err := reloader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range []string{"list_items", "delete_item"} {
+		if got, err := manager.toolRoutes.Route(tool); err != nil || got != "Ops" {
+			t.Errorf("after reload: Route(%s) = %q, %v, want Ops", tool, got, err)
+		}
+	}
+	if !registry.Scoped("Coder").HasTool("delete_item") || !registry.Scoped("Ops").HasTool("delete_item") {
+		t.Error("reloaded tool missing from the agents' views")
+	}
+
+	// Removed tools are no longer routed.
+	teamMcpConfig(t, path, "", "list_items")
+	if err := reloader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.toolRoutes.Route("delete_item"); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("removed tool: err = %v", err)
+	}
+}
@@ -10,12 +10,13 @@ import (
 )
 
 type ToolRunnerAgent struct {
+	ctx      context.Context // tool calls run under it; cancelled at shutdown
 	name     string
 	registry *tools.ToolRegistry
 }
 
-func NewToolRunnerAgent(name string, registry *tools.ToolRegistry) *ToolRunnerAgent {
-	return &ToolRunnerAgent{name: name, registry: registry}
+func NewToolRunnerAgent(ctx context.Context, name string, registry *tools.ToolRegistry) *ToolRunnerAgent {
+	return &ToolRunnerAgent{ctx: ctx, name: name, registry: registry}
 }
 func (a *ToolRunnerAgent) Name() string { return a.name }
 func (a *ToolRunnerAgent) Start(input <-chan model.Message, output chan<- model.Message) {
@@ -28,16 +29,20 @@ func (a *ToolRunnerAgent) Start(input <-chan model.Message, output chan<- model.
 				Msgf("Received: %s", msg.Content)
 				
 			if msg.MessageType == model.TypeToolCall && msg.ToolCall != nil {
-				result := a.registry.Call(context.TODO(), *msg.ToolCall)
+				result := a.registry.Call(a.ctx, *msg.ToolCall)
 
 				utils.Logger.Debug().
 					Str("agent", a.name).
 					Str("tool", msg.ToolCall.Name).
 					Msgf("Tool call result:\n\n  %v \n\n", result.Output)
 
+				content := fmt.Sprintf("%v", result.Output) // Safely stringify any output
+				if result.Output == nil && result.Error != nil {
+					content = result.Error.Error()
+				}
 				output <- model.Message{
 					Sender:      a.name,
-					Content:     fmt.Sprintf("%v", result.Output), // Safely stringify any output,
+					Content:     content,
 					MessageType: model.TypeToolResult,
 					IsError: result.Error != nil,
 					Error: result.Error,
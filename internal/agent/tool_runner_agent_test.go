@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/llm"
+	"aiupstart.com/go-gen/internal/model"
+	"aiupstart.com/go-gen/internal/tools"
+)
+
+// countingTool counts its calls.
+type countingTool struct {
+	name  string
+	calls chan struct{}
+}
+
+func (t *countingTool) Name() string        { return t.name }
+func (t *countingTool) Description() string { return "counts calls" }
+func (t *countingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *countingTool) Call(ctx context.Context, call tools.ToolCall) tools.ToolResult {
+	t.calls <- struct{}{}
+	return tools.ToolResult{Output: "ran " + t.name}
+}
+
+func noLLM(config.TeamAgentConfig) (llm.LLMClient, error) { return nil, nil }
+
+// runToolCall sends one tool call to a started executor and returns its reply.
+func runToolCall(t *testing.T, a Agent, call tools.ToolCall) model.Message {
+	t.Helper()
+	in := make(chan model.Message)
+	out := make(chan model.Message, 1)
+	a.Start(in, out)
+	defer close(in)
+	in <- model.Message{Sender: call.Caller, MessageType: model.TypeToolCall, ToolCall: &call, OriginAgent: call.Caller}
+	select {
+	case msg := <-out:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no tool result")
+		return model.Message{}
+	}
+}
+
+func TestExecutorsRunOnlyTheirTools(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	read := &countingTool{name: "workspace_read", calls: make(chan struct{}, 1)}
+	exec := &countingTool{name: "docker_exec", calls: make(chan struct{}, 1)}
+	registry.Register(read)
+	registry.Register(exec)
+	team := &config.TeamConfig{
+		Agents: []config.TeamAgentConfig{
+			{Name: "Coder", Type: config.AgentTypeAssistant, Tools: []string{"workspace_*", "docker_exec"}},
+			{Name: "Reader", Type: config.AgentTypeToolRunner, Tools: []string{"workspace_*"}},
+			{Name: "Shell", Type: config.AgentTypeToolRunner},
+		},
+	}
+	if _, err := BuildTeam(context.Background(), team, registry, noLLM); err != nil {
+		t.Fatal(err)
+	}
+	// The executor listing tools is scoped to them; the catch-all is not.
+	if registry.Scoped("Reader").HasTool("docker_exec") || !registry.Scoped("Shell").HasTool("docker_exec") {
+		t.Fatal("executor scopes do not follow their tools")
+	}
+
+	reader := NewToolRunnerAgent(context.Background(), "Reader", registry.Scoped("Reader"))
+	msg := runToolCall(t, reader, tools.ToolCall{Name: "docker_exec", Caller: "Coder"})
+	if !msg.IsError || !errors.Is(msg.Error, tools.ErrToolDenied) || !strings.Contains(msg.Content, "agent Reader may not call docker_exec") {
+		t.Errorf("result = %+v, want the executor's denial", msg)
+	}
+	select {
+	case <-exec.calls:
+		t.Error("denied tool was run")
+	default:
+	}
+
+	if msg := runToolCall(t, reader, tools.ToolCall{Name: "workspace_read", Caller: "Coder"}); msg.IsError || msg.Content != "ran workspace_read" {
+		t.Errorf("permitted call: %+v", msg)
+	}
+}
+
+func TestBuildTeamScopesExecutorsToRoutes(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	registry.Register(&countingTool{name: "workspace_read"})
+	registry.Register(&countingTool{name: "deploy"})
+	team := &config.TeamConfig{
+		Agents: []config.TeamAgentConfig{
+			{Name: "Coder", Type: config.AgentTypeAssistant},
+			{Name: "Runner", Type: config.AgentTypeToolRunner, Tools: []string{"workspace_*"}},
+		},
+		Routes: []config.TeamRouteConfig{{Tool: "deploy", Agent: "Runner"}},
+	}
+	if _, err := BuildTeam(context.Background(), team, registry, noLLM); err != nil {
+		t.Fatal(err)
+	}
+	// A route adds to the tools an executor may run.
+	if view := registry.Scoped("Runner"); !view.HasTool("deploy") || !view.HasTool("workspace_read") {
+		t.Error("routed tool hidden from its executor")
+	}
+}
+
+// pinnedTool names the executor that must run it.
+type pinnedTool struct {
+	countingTool
+	executor string
+}
+
+func (t *pinnedTool) Metadata() tools.ToolMetadata { return tools.ToolMetadata{Executor: t.executor} }
+
+func TestBuildTeamRejectsExecutorOutsideItsTools(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	registry.Register(&pinnedTool{countingTool: countingTool{name: "deploy"}, executor: "Runner"})
+	team := &config.TeamConfig{Agents: []config.TeamAgentConfig{
+		{Name: "Coder", Type: config.AgentTypeAssistant},
+		{Name: "Runner", Type: config.AgentTypeToolRunner, Tools: []string{"workspace_*"}},
+	}}
+	_, err := BuildTeam(context.Background(), team, registry, noLLM)
+	if err == nil || !strings.Contains(err.Error(), `tool "deploy": tool metadata names agent "Runner", whose tools do not include it`) {
+		t.Errorf("err = %v, want the executor's tools to exclude deploy", err)
+	}
+}
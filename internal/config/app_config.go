@@ -0,0 +1,336 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AppConfigEnv names the config file when --config is not given.
+const AppConfigEnv = "GOGEN_CONFIG"
+
+// DefaultAppConfigFile is picked up from the working directory when neither
+// --config nor $GOGEN_CONFIG is set.
+const DefaultAppConfigFile = "gogen.yaml"
+
+// AppConfig holds every application setting. It is built in layers:
+// DefaultAppConfig, then the YAML file, then GOGEN_* environment variables
+// (ApplyEnv), then command line flags (Set).
+type AppConfig struct {
+	LLM     LLMConfig     `yaml:"llm" json:"llm"`
+	Agents  AgentsConfig  `yaml:"agents" json:"agents"`
+	Limits  LimitsConfig  `yaml:"limits" json:"limits"`
+	Tools   ToolsConfig   `yaml:"tools" json:"tools"`
+	Sandbox SandboxConfig `yaml:"sandbox" json:"sandbox"`
+	Secrets SecretsConfig `yaml:"secrets" json:"secrets"`
+	Logging LoggingConfig `yaml:"logging" json:"logging"`
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
+	// File is the config file that was loaded ("" for defaults only).
+	File string `yaml:"-" json:"-"`
+	root *yaml.Node // parsed File, positions Validate errors
+}
+
+// LLMConfig lists the LLM providers; Default names the one agents use.
+type LLMConfig struct {
+	Default   string                       `yaml:"default" json:"default"`
+	Providers map[string]LLMProviderConfig `yaml:"providers" json:"providers"`
+}
+
+// LLMProviderConfig is one LLM endpoint. APIKey may reference ${NAME},
+// resolved from the secret store. BaseURL points the OpenAI client at a
+// compatible API (Azure, a local gateway, ...).
+type LLMProviderConfig struct {
+	Type    string `yaml:"type" json:"type"` // openai
+	APIKey  string `yaml:"api_key" json:"api_key"`
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	Model   string `yaml:"model" json:"model"`
+}
+
+type AgentsConfig struct {
+	SystemPrompt string `yaml:"system_prompt" json:"system_prompt"` // assistant persona of the default team
+	Task         string `yaml:"task" json:"task"`                   // first user message of the session
+	Team         string `yaml:"team" json:"team"`                   // team file (see TeamConfig); empty uses DefaultTeam
+}
+
+type LimitsConfig struct {
+	MaxTurns  int `yaml:"max_turns" json:"max_turns"`
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens"`
+}
+
+type ToolsConfig struct {
+	Config        string `yaml:"config" json:"config"`                 // tools.yaml (enabled flags); optional
+	McpConfig     string `yaml:"mcp_config" json:"mcp_config"`         // mcp_tools.yaml
+	ReloadSeconds int    `yaml:"reload_seconds" json:"reload_seconds"` // poll both files for changes; 0 disables
+	ReportDir     string `yaml:"report_dir" json:"report_dir"`         // markdown_report output without a session workspace
+	DownloadDir   string `yaml:"download_dir" json:"download_dir"`     // arxiv_download output without a session workspace
+}
+
+type SandboxConfig struct {
+	ContainerPrefix string        `yaml:"container_prefix" json:"container_prefix"`
+	DefaultImage    string        `yaml:"default_image" json:"default_image"`
+	Network         string        `yaml:"network" json:"network"` // "none" isolates containers
+	Pool            PoolConfig    `yaml:"pool" json:"pool"`
+	Cache           CacheConfig   `yaml:"cache" json:"cache"`
+	Mirrors         MirrorsConfig `yaml:"mirrors" json:"mirrors"`
+	Export          ExportConfig  `yaml:"export" json:"export"`
+	MaxFileKB       int           `yaml:"max_file_kb" json:"max_file_kb"` // per code block written to the workspace
+	MaxFiles        int           `yaml:"max_files" json:"max_files"`     // code blocks per docker_exec call
+}
+
+type PoolConfig struct {
+	Size int            `yaml:"size" json:"size"` // 0 disables the warm pool
+	Warm map[string]int `yaml:"warm" json:"warm"` // image -> idle containers
+	// MaxUses is how many sessions a pooled container serves. Reuse only
+	// wipes /workspace, so keep 1 unless sessions trust each other.
+	MaxUses int `yaml:"max_uses" json:"max_uses"`
+}
+
+type CacheConfig struct {
+	Enabled bool           `yaml:"enabled" json:"enabled"`
+	MaxMB   map[string]int `yaml:"max_mb" json:"max_mb"` // manager -> size limit
+}
+
+type MirrorsConfig struct {
+	NpmRegistry  string `yaml:"npm_registry" json:"npm_registry"`
+	PipIndexURL  string `yaml:"pip_index_url" json:"pip_index_url"`
+	PipFindLinks string `yaml:"pip_find_links" json:"pip_find_links"`
+	NugetFeed    string `yaml:"nuget_feed" json:"nuget_feed"`
+}
+
+type ExportConfig struct {
+	Dir     string   `yaml:"dir" json:"dir"` // disabled if empty
+	Include []string `yaml:"include" json:"include"`
+	Exclude []string `yaml:"exclude" json:"exclude"` // empty uses the built-in excludes
+	Tar     bool     `yaml:"tar" json:"tar"`
+}
+
+type SecretsConfig struct {
+	EnvFile string `yaml:"env_file" json:"env_file"`
+	File    string `yaml:"file" json:"file"`   // encrypted secrets file
+	Allow   string `yaml:"allow" json:"allow"` // "tool=NAME,NAME;tool2=NAME"
+}
+
+type LoggingConfig struct {
+	Level   string `yaml:"level" json:"level"` // trace, debug, info, warn, error
+	File    string `yaml:"file" json:"file"`   // empty disables the log file
+	Console bool   `yaml:"console" json:"console"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Addr    string `yaml:"addr" json:"addr"`
+}
+
+// DefaultAppConfig returns the built-in settings.
+func DefaultAppConfig() *AppConfig {
+	return &AppConfig{
+		LLM: LLMConfig{
+			Default: "openai",
+			Providers: map[string]LLMProviderConfig{
+				"openai": {Type: "openai", APIKey: "${OPENAI_API_KEY}", Model: "gpt-4.1"},
+			},
+		},
+		Agents: AgentsConfig{
+			SystemPrompt: "You are precise, helpful, and always prefer running and testing code over guessing.\n" +
+				"If the user requests a coding task, you generate high-quality, working code, and always execute it for validation.",
+			Task: "Create a new angular web app which has a main user login page.",
+		},
+		Limits: LimitsConfig{MaxTurns: 10, MaxTokens: 20000},
+		Tools:  ToolsConfig{Config: "tools.yaml", McpConfig: "mcp_tools.yaml", ReloadSeconds: 2, ReportDir: "reports", DownloadDir: "downloads"},
+		Sandbox: SandboxConfig{
+			ContainerPrefix: "go-gen-",
+			DefaultImage:    "node:20",
+			Pool:            PoolConfig{Warm: map[string]int{"angular-dev:latest": 1}, MaxUses: 1},
+			Cache:           CacheConfig{Enabled: true, MaxMB: map[string]int{"npm": 4096, "pip": 2048, "nuget": 4096}},
+			MaxFileKB:       2048,
+			MaxFiles:        200,
+		},
+		Logging: LoggingConfig{Level: "debug", File: "run.log", Console: true},
+		Metrics: MetricsConfig{Enabled: true, Addr: ":2112"},
+	}
+}
+
+// FindAppConfigFile returns the config file to load: flagValue, else
+// $GOGEN_CONFIG, else gogen.yaml in the working directory if present.
+func FindAppConfigFile(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv(AppConfigEnv); env != "" {
+		return env
+	}
+	if _, err := os.Stat(DefaultAppConfigFile); err == nil {
+		return DefaultAppConfigFile
+	}
+	return ""
+}
+
+// LoadAppConfig layers the defaults, the file at path (skipped if empty)
+// and GOGEN_* environment variables. Flags are applied by the caller with
+// Set, followed by Validate. Unknown keys in the file are errors, and
+// relative paths in the file resolve against its directory; relative
+// default paths stay relative to the working directory. Maps the file sets
+// (llm.providers, sandbox.pool.warm, sandbox.cache.max_mb) replace the
+// defaults instead of adding to them, so "warm: {}" clears the warm pool.
+func LoadAppConfig(path string) (*AppConfig, error) {
+	cfg := DefaultAppConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return nil, yamlErrors(path, err)
+		}
+		// Decode again without defaults to see which maps and paths the
+		// file sets.
+		var fromFile AppConfig
+		yaml.Unmarshal(data, &fromFile)
+		if fromFile.LLM.Providers != nil {
+			cfg.LLM.Providers = fromFile.LLM.Providers
+		}
+		if fromFile.Sandbox.Pool.Warm != nil {
+			cfg.Sandbox.Pool.Warm = fromFile.Sandbox.Pool.Warm
+		}
+		if fromFile.Sandbox.Cache.MaxMB != nil {
+			cfg.Sandbox.Cache.MaxMB = fromFile.Sandbox.Cache.MaxMB
+		}
+		dir := filepath.Dir(path)
+		for _, p := range []struct {
+			set string
+			dst *string
+		}{
+			{fromFile.Agents.Team, &cfg.Agents.Team},
+			{fromFile.Tools.Config, &cfg.Tools.Config},
+			{fromFile.Tools.McpConfig, &cfg.Tools.McpConfig},
+			{fromFile.Tools.ReportDir, &cfg.Tools.ReportDir},
+			{fromFile.Tools.DownloadDir, &cfg.Tools.DownloadDir},
+			{fromFile.Secrets.EnvFile, &cfg.Secrets.EnvFile},
+			{fromFile.Secrets.File, &cfg.Secrets.File},
+			{fromFile.Logging.File, &cfg.Logging.File},
+			{fromFile.Sandbox.Export.Dir, &cfg.Sandbox.Export.Dir},
+		} {
+			if p.set != "" && !filepath.IsAbs(p.set) {
+				*p.dst = filepath.Join(dir, p.set)
+			}
+		}
+		cfg.File = path
+		cfg.root = &yaml.Node{}
+		yaml.Unmarshal(data, cfg.root)
+	}
+	if err := cfg.ApplyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Provider returns the named LLM provider, or the default one for "".
+func (c *AppConfig) Provider(name string) (LLMProviderConfig, error) {
+	if name == "" {
+		name = c.LLM.Default
+	}
+	p, ok := c.LLM.Providers[name]
+	if !ok {
+		return p, fmt.Errorf("unknown LLM provider %q", name)
+	}
+	return p, nil
+}
+
+var logLevels = map[string]bool{"trace": true, "debug": true, "info": true, "warn": true, "error": true, "fatal": true, "panic": true, "disabled": true}
+
+// Validate checks the effective config and returns every problem found.
+func (c *AppConfig) Validate() error {
+	v := &validator{file: c.File, root: c.root}
+	if _, ok := c.LLM.Providers[c.LLM.Default]; !ok {
+		v.errorf(cfgPath{"llm", "default"}, "provider %q is not defined in llm.providers", c.LLM.Default)
+	}
+	for _, name := range sortedProviderNames(c.LLM.Providers) {
+		p, pp := c.LLM.Providers[name], cfgPath{"llm", "providers", name}
+		if p.Type != "openai" {
+			v.errorf(pp.at("type"), "unsupported provider type %q (want openai)", p.Type)
+		}
+		if p.Model == "" {
+			v.errorf(pp.at("model"), "model is required")
+		}
+	}
+	if c.Limits.MaxTurns <= 0 {
+		v.errorf(cfgPath{"limits", "max_turns"}, "must be greater than 0")
+	}
+	if c.Limits.MaxTokens <= 0 {
+		v.errorf(cfgPath{"limits", "max_tokens"}, "must be greater than 0")
+	}
+	if c.Tools.McpConfig == "" {
+		v.errorf(cfgPath{"tools", "mcp_config"}, "is required")
+	}
+	if c.Tools.ReloadSeconds < 0 {
+		v.errorf(cfgPath{"tools", "reload_seconds"}, "must not be negative")
+	}
+	if c.Sandbox.ContainerPrefix == "" {
+		v.errorf(cfgPath{"sandbox", "container_prefix"}, "is required")
+	}
+	if c.Sandbox.Pool.Size < 0 {
+		v.errorf(cfgPath{"sandbox", "pool", "size"}, "must not be negative")
+	}
+	if c.Sandbox.Pool.MaxUses < 1 {
+		v.errorf(cfgPath{"sandbox", "pool", "max_uses"}, "must be at least 1")
+	}
+	if c.Sandbox.MaxFileKB <= 0 {
+		v.errorf(cfgPath{"sandbox", "max_file_kb"}, "must be greater than 0")
+	}
+	if c.Sandbox.MaxFiles <= 0 {
+		v.errorf(cfgPath{"sandbox", "max_files"}, "must be greater than 0")
+	}
+	if c.Sandbox.Network == "none" {
+		// Containers without a network cannot reach a mirror server; only
+		// host directories (pip_find_links, a nuget folder feed) work.
+		m := c.Sandbox.Mirrors
+		for _, u := range []struct{ key, value string }{
+			{"npm_registry", m.NpmRegistry},
+			{"pip_index_url", m.PipIndexURL},
+			{"nuget_feed", m.NugetFeed},
+		} {
+			if strings.HasPrefix(u.value, "http://") || strings.HasPrefix(u.value, "https://") {
+				v.errorf(cfgPath{"sandbox", "mirrors", u.key}, "URL mirror is unreachable with sandbox.network none; set sandbox.network to an internal docker network the mirror is on")
+			}
+		}
+	}
+	if !logLevels[strings.ToLower(c.Logging.Level)] {
+		v.errorf(cfgPath{"logging", "level"}, "unknown level %q", c.Logging.Level)
+	}
+	if c.Metrics.Enabled && c.Metrics.Addr == "" {
+		v.errorf(cfgPath{"metrics", "addr"}, "is required when metrics are enabled")
+	}
+	return v.err()
+}
+
+// Redacted returns a copy safe to print: literal API keys are masked,
+// ${NAME} references are kept.
+func (c *AppConfig) Redacted() *AppConfig {
+	out := *c
+	out.LLM.Providers = make(map[string]LLMProviderConfig, len(c.LLM.Providers))
+	for name, p := range c.LLM.Providers {
+		if p.APIKey != "" && !strings.HasPrefix(p.APIKey, "${") {
+			p.APIKey = "****"
+		}
+		out.LLM.Providers[name] = p
+	}
+	return &out
+}
+
+func sortedProviderNames(m map[string]LLMProviderConfig) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
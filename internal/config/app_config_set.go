@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AppConfigEnvPrefix prefixes environment overrides: the dotted key
+// sandbox.pool.size is read from GOGEN_SANDBOX_POOL_SIZE.
+const AppConfigEnvPrefix = "GOGEN_"
+
+// EnvName returns the environment variable overriding a dotted key.
+func EnvName(key string) string {
+	r := strings.NewReplacer(".", "_", "-", "_", ":", "_")
+	return AppConfigEnvPrefix + strings.ToUpper(r.Replace(key))
+}
+
+// Keys lists the dotted keys of every settable value, e.g. limits.max_turns
+// and llm.providers.openai.model (one entry per configured provider).
+func (c *AppConfig) Keys() []string {
+	var keys []string
+	collectKeys(reflect.ValueOf(c).Elem(), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectKeys(v reflect.Value, prefix string, keys *[]string) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if name := yamlName(t.Field(i)); name != "" {
+				collectKeys(v.Field(i), joinKey(prefix, name), keys)
+			}
+		}
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Struct:
+		for _, k := range v.MapKeys() {
+			collectKeys(v.MapIndex(k), joinKey(prefix, k.String()), keys)
+		}
+	default:
+		*keys = append(*keys, prefix)
+	}
+}
+
+// ApplyEnv overrides settings from GOGEN_* variables in environ
+// ("NAME=value" pairs, as from os.Environ).
+func (c *AppConfig) ApplyEnv(environ []string) error {
+	env := map[string]string{}
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, AppConfigEnvPrefix) {
+			env[name] = value
+		}
+	}
+	var errs ValidationErrors
+	for _, key := range c.Keys() {
+		name := EnvName(key)
+		value, ok := env[name]
+		if !ok {
+			continue
+		}
+		if err := c.Set(key, value); err != nil {
+			errs = append(errs, ConfigError{File: "$" + name, Msg: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Set assigns a value given as text to the setting at a dotted key, as a
+// flag or environment variable would: lists are comma-separated and maps
+// are "key=n,key=n". A map entry of structs (a provider) is created on
+// demand.
+func (c *AppConfig) Set(key, value string) error {
+	if err := setPath(reflect.ValueOf(c).Elem(), strings.Split(key, "."), value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func setPath(v reflect.Value, path []string, value string) error {
+	if len(path) == 0 {
+		return setValue(v, value)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if yamlName(t.Field(i)) == path[0] {
+				return setPath(v.Field(i), path[1:], value)
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		k := reflect.ValueOf(path[0])
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if cur := v.MapIndex(k); cur.IsValid() {
+			elem.Set(cur)
+		}
+		if err := setPath(elem, path[1:], value); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, elem)
+		return nil
+	}
+	return fmt.Errorf("unknown setting")
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("want an integer, got %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type")
+		}
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item))
+			}
+		}
+		v.Set(items)
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.Int {
+			return fmt.Errorf("a group of settings cannot be set from one value")
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, item := range strings.Split(value, ",") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			k, n, ok := strings.Cut(item, "=")
+			count, err := strconv.Atoi(strings.TrimSpace(n))
+			if !ok || err != nil {
+				return fmt.Errorf("want key=n, got %q", item)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), reflect.ValueOf(count))
+		}
+		v.Set(m)
+	case reflect.Struct:
+		return fmt.Errorf("a group of settings cannot be set from one value")
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" || !f.IsExported() {
+		return ""
+	}
+	return name
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadAppConfigToolPaths(t *testing.T) {
+	// Without a file both tool configs resolve against the working directory.
+	cfg, err := LoadAppConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Tools.Config != "tools.yaml" || cfg.Tools.McpConfig != "mcp_tools.yaml" {
+		t.Errorf("defaults = %q, %q, want both in the working directory", cfg.Tools.Config, cfg.Tools.McpConfig)
+	}
+
+	// Paths set in a file resolve against the file's directory.
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gogen.yaml")
+	data := "tools:\n  config: tools.yaml\n  mcp_config: mcp_tools.yaml\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadAppConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "tools.yaml"); cfg.Tools.Config != want {
+		t.Errorf("tools.config = %q, want %q", cfg.Tools.Config, want)
+	}
+	if want := filepath.Join(dir, "mcp_tools.yaml"); cfg.Tools.McpConfig != want {
+		t.Errorf("tools.mcp_config = %q, want %q", cfg.Tools.McpConfig, want)
+	}
+}
+
+func TestLoadAppConfigMapsReplaceDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gogen.yaml")
+	data := `llm:
+  default: local
+  providers:
+    local: {type: openai, base_url: http://localhost:11434/v1, model: llama3.1}
+sandbox:
+  pool:
+    size: 2
+    warm: {}
+  export:
+    exclude: [dist]
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadAppConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.LLM.Providers["openai"]; ok || len(cfg.LLM.Providers) != 1 {
+		t.Errorf("providers = %v, want only local", cfg.LLM.Providers)
+	}
+	if len(cfg.Sandbox.Pool.Warm) != 0 {
+		t.Errorf("pool.warm = %v, want it cleared", cfg.Sandbox.Pool.Warm)
+	}
+	// Maps the file leaves out keep their defaults, as do scalars.
+	if !reflect.DeepEqual(cfg.Sandbox.Cache.MaxMB, DefaultAppConfig().Sandbox.Cache.MaxMB) {
+		t.Errorf("cache.max_mb = %v, want the defaults", cfg.Sandbox.Cache.MaxMB)
+	}
+	if cfg.Sandbox.Pool.Size != 2 || cfg.Sandbox.Pool.MaxUses != 1 || !reflect.DeepEqual(cfg.Sandbox.Export.Exclude, []string{"dist"}) {
+		t.Errorf("pool = %+v, export = %+v", cfg.Sandbox.Pool, cfg.Sandbox.Export)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// The environment still replaces a map set in the file.
+	t.Setenv("GOGEN_SANDBOX_POOL_WARM", "node:20=1")
+	if cfg, err = LoadAppConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Sandbox.Pool.Warm, map[string]int{"node:20": 1}) {
+		t.Errorf("pool.warm = %v, want the environment's", cfg.Sandbox.Pool.Warm)
+	}
+}
+
+func TestDefaultToolConfigsExistAtRepoRoot(t *testing.T) {
+	// validate-config run from the repository root loads the defaults.
+	d := DefaultAppConfig()
+	for _, name := range []string{d.Tools.Config, d.Tools.McpConfig} {
+		if _, err := os.Stat(filepath.Join("..", "..", name)); err != nil {
+			t.Errorf("default %s: %v", name, err)
+		}
+	}
+	if _, err := LoadConfig(filepath.Join("..", "..", d.Tools.McpConfig)); err != nil {
+		t.Errorf("default mcp_config does not load: %v", err)
+	}
+}
+
+func TestValidateURLMirrorNeedsNetwork(t *testing.T) {
+	cfg := DefaultAppConfig()
+	cfg.Sandbox.Network = "none"
+	cfg.Sandbox.Mirrors = MirrorsConfig{NpmRegistry: "http://verdaccio:4873", PipFindLinks: "/srv/wheels"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "npm_registry") {
+		t.Fatalf("err = %v, want the unreachable npm registry", err)
+	}
+	if strings.Contains(err.Error(), "pip_find_links") {
+		t.Errorf("err = %v; a host directory mirror works without a network", err)
+	}
+
+	cfg.Sandbox.Network = "mirrors"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("named network: %v", err)
+	}
+}
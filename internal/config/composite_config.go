@@ -0,0 +1,255 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Step error policies of composite tools.
+const (
+	OnErrorFail     = "fail"     // stop the pipeline and report the step's error (default)
+	OnErrorContinue = "continue" // record the error in .steps.<id>.error and go on
+)
+
+// CompositeToolConfig defines a tool that runs other tools as steps of a
+// pipeline and is offered to the LLM as a single tool. Step arguments are Go
+// templates over .args (the composite's arguments) and .steps.<id>.output /
+// .error (results of earlier steps). A step runs once the steps it lists in
+// needs or references in its templates are done; independent steps run in
+// parallel.
+type CompositeToolConfig struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Description string                 `yaml:"description" json:"description"`
+	Parameters  map[string]interface{} `yaml:"parameters" json:"parameters"` // JSON Schema of the arguments
+	Steps       []CompositeStepConfig  `yaml:"steps" json:"steps"`
+	Output      string                 `yaml:"output" json:"output"` // result template; default: output of the last step
+	// Routing of the composite's calls, as for mcp_tools entries. Steps run
+	// with the composite's approval: a step tool requiring approval needs
+	// requires_approval here.
+	Executor         string `yaml:"executor" json:"executor"`
+	RequiresApproval bool   `yaml:"requires_approval" json:"requires_approval"`
+}
+
+// CompositeStepConfig is one tool call of a composite tool.
+type CompositeStepConfig struct {
+	ID      string                 `yaml:"id" json:"id"`
+	Tool    string                 `yaml:"tool" json:"tool"`
+	Args    map[string]interface{} `yaml:"args" json:"args"`
+	Needs   []string               `yaml:"needs" json:"needs"`       // steps to wait for besides the referenced ones
+	OnError string                 `yaml:"on_error" json:"on_error"` // fail (default) or continue
+	Retries int                    `yaml:"retries" json:"retries"`   // extra attempts after a failure
+}
+
+// CompositeTemplateFuncs are available in composite templates besides the
+// text/template builtins.
+var CompositeTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v interface{}) string {
+		var parts []string
+		switch list := v.(type) {
+		case []string:
+			parts = list
+		case []interface{}:
+			for _, e := range list {
+				parts = append(parts, fmt.Sprint(e))
+			}
+		default:
+			return fmt.Sprint(v)
+		}
+		return strings.Join(parts, sep)
+	},
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+var (
+	stepIDPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	stepRefPattern = regexp.MustCompile(`\.steps\.([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// WalkStrings calls fn for every string in v (nested maps and lists included).
+func WalkStrings(v interface{}, fn func(string)) {
+	switch x := v.(type) {
+	case string:
+		fn(x)
+	case map[string]interface{}:
+		for _, e := range x {
+			WalkStrings(e, fn)
+		}
+	case []interface{}:
+		for _, e := range x {
+			WalkStrings(e, fn)
+		}
+	}
+}
+
+// StepRefs returns the step ids referenced as .steps.<id> in the templates
+// of v, sorted and without duplicates.
+func StepRefs(v interface{}) []string {
+	seen := map[string]bool{}
+	WalkStrings(v, func(s string) {
+		for _, m := range stepRefPattern.FindAllStringSubmatch(s, -1) {
+			seen[m[1]] = true
+		}
+	})
+	refs := make([]string, 0, len(seen))
+	for id := range seen {
+		refs = append(refs, id)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Dependencies returns the steps s waits for: needs and the steps its
+// argument templates reference.
+func (s CompositeStepConfig) Dependencies() []string {
+	deps := append([]string{}, s.Needs...)
+	for _, id := range StepRefs(s.Args) {
+		if !contains(deps, id) {
+			deps = append(deps, id)
+		}
+	}
+	return deps
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateComposite checks one composite tool: step ids, tools, error
+// policies, templates and that the step dependencies form a DAG.
+func (v *validator) validateComposite(p cfgPath, c CompositeToolConfig) {
+	if c.Parameters != nil {
+		if c.Parameters["type"] != "object" {
+			v.errorf(p.at("parameters", "type"), "composite tool %q: parameters.type must be 'object'", c.Name)
+		}
+		v.validateSchema(p.at("parameters"), c.Parameters)
+	}
+	if len(c.Steps) == 0 {
+		v.errorf(p, "composite tool %q has no steps", c.Name)
+		return
+	}
+	ids := map[string]bool{}
+	for i, s := range c.Steps {
+		sp := p.at("steps", i)
+		switch {
+		case !stepIDPattern.MatchString(s.ID):
+			v.errorf(sp.at("id"), "step id %q must start with a letter or '_' and contain only letters, digits and '_'", s.ID)
+		case ids[s.ID]:
+			v.errorf(sp.at("id"), "duplicate step id %q", s.ID)
+		}
+		ids[s.ID] = true
+		switch s.Tool {
+		case "":
+			v.errorf(sp, "step %q: tool is required", s.ID)
+		case c.Name:
+			v.errorf(sp.at("tool"), "step %q calls the composite tool itself", s.ID)
+		}
+		switch s.OnError {
+		case "", OnErrorFail, OnErrorContinue:
+		default:
+			v.errorf(sp.at("on_error"), "unknown on_error %q (want fail or continue)", s.OnError)
+		}
+		if s.Retries < 0 {
+			v.errorf(sp.at("retries"), "must not be negative")
+		}
+		v.validateTemplates(sp.at("args"), s.Args)
+	}
+	for i, s := range c.Steps {
+		sp := p.at("steps", i)
+		for _, dep := range s.Dependencies() {
+			switch {
+			case dep == s.ID:
+				v.errorf(sp, "step %q depends on itself", s.ID)
+			case !ids[dep]:
+				v.errorf(sp, "step %q depends on unknown step %q", s.ID, dep)
+			}
+		}
+	}
+	if cycle := compositeCycle(c.Steps); cycle != nil {
+		v.errorf(p.at("steps"), "steps depend on each other in a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	if c.Output != "" {
+		v.validateTemplates(p.at("output"), c.Output)
+		for _, id := range StepRefs(c.Output) {
+			if !ids[id] {
+				v.errorf(p.at("output"), "output references unknown step %q", id)
+			}
+		}
+	}
+}
+
+func (v *validator) validateTemplates(p cfgPath, val interface{}) {
+	WalkStrings(val, func(s string) {
+		if !strings.Contains(s, "{{") {
+			return
+		}
+		if _, err := template.New("").Funcs(CompositeTemplateFuncs).Parse(s); err != nil {
+			v.errorf(p, "invalid template %q: %v", s, err)
+		}
+	})
+}
+
+// compositeCycle returns a dependency cycle among steps, or nil. Unknown
+// and self dependencies are reported elsewhere and ignored here.
+func compositeCycle(steps []CompositeStepConfig) []string {
+	deps := map[string][]string{}
+	for _, s := range steps {
+		deps[s.ID] = s.Dependencies()
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var stack []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range deps[id] {
+			if _, known := deps[dep]; !known || dep == id {
+				continue
+			}
+			switch state[dep] {
+			case visiting:
+				for i, s := range stack {
+					if s == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case 0:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return nil
+	}
+	for _, s := range steps {
+		if state[s.ID] == 0 {
+			if cycle := visit(s.ID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	// Keep test runs from writing run.log next to the package.
+	if err := utils.ConfigureLogger("error", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
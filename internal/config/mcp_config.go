@@ -1,10 +1,11 @@
 package config
 
 import (
-	"fmt"
+	"bytes"
+	"io"
 	"os"
+	"path/filepath"
 
-	"aiupstart.com/go-gen/internal/utils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,87 +25,164 @@ type McpToolOperation struct {
     Description string                 `yaml:"description" json:"description"`
     ExampleArgs map[string]interface{} `yaml:"example_args" json:"example_args"`
     Parameters  map[string]interface{} `yaml:"parameters" json:"parameters"`
+    QueryParams []string               `yaml:"query_params" json:"query_params"` // args sent in the query string whatever the method
+    BodyParam   string                 `yaml:"body_param" json:"body_param"`     // arg whose value is the whole JSON body
 }
 type McpToolConfig struct {
     Name        string              `yaml:"name" json:"name"`
     Endpoint    string              `yaml:"endpoint" json:"endpoint"`
     Description string              `yaml:"description" json:"description"`
+    Builtin     bool                `yaml:"builtin" json:"builtin"` // implemented in code (e.g. docker_exec); no endpoint
+
+    // Routing of the operations' calls: the agent executing them (default:
+    // the team's tool runner) and whether a human approves each call.
+    Executor         string `yaml:"executor" json:"executor"`
+    RequiresApproval bool   `yaml:"requires_approval" json:"requires_approval"`
     Operations  []McpToolOperation  `yaml:"operations" json:"operations"`
+    OpenAPI     *OpenAPISource      `yaml:"openapi" json:"openapi"` // operations generated from an OpenAPI 3 document
+
+    // HTTP client settings for the operations. Credential and header values
+    // may reference ${NAME}, resolved from the secret store (env first).
+    Auth             *McpAuthConfig    `yaml:"auth" json:"auth"`
+    Headers          map[string]string `yaml:"headers" json:"headers"`
+    TimeoutSeconds   int               `yaml:"timeout_seconds" json:"timeout_seconds"`
+    Retry            *McpRetryConfig   `yaml:"retry" json:"retry"`
+    TLS              *McpTLSConfig     `yaml:"tls" json:"tls"`
+    MaxResponseBytes int64             `yaml:"max_response_bytes" json:"max_response_bytes"`
+}
+
+// McpAuthConfig selects how requests are authenticated.
+//   bearer:  token
+//   basic:   username, password
+//   api_key: name (header or query parameter), value, in (header|query)
+//   oauth2_client_credentials: token_url, client_id, client_secret, scopes, audience
+type McpAuthConfig struct {
+    Type         string   `yaml:"type" json:"type"`
+    Token        string   `yaml:"token" json:"token"`
+    Username     string   `yaml:"username" json:"username"`
+    Password     string   `yaml:"password" json:"password"`
+    Name         string   `yaml:"name" json:"name"`
+    Value        string   `yaml:"value" json:"value"`
+    In           string   `yaml:"in" json:"in"`
+    TokenURL     string   `yaml:"token_url" json:"token_url"`
+    ClientID     string   `yaml:"client_id" json:"client_id"`
+    ClientSecret string   `yaml:"client_secret" json:"client_secret"`
+    Scopes       []string `yaml:"scopes" json:"scopes"`
+    Audience     string   `yaml:"audience" json:"audience"`
+}
+
+// McpRetryConfig retries failed requests with exponential backoff. Only
+// idempotent methods are retried unless RetryNonIdempotent is set.
+type McpRetryConfig struct {
+    MaxAttempts        int   `yaml:"max_attempts" json:"max_attempts"`
+    InitialBackoffMs   int   `yaml:"initial_backoff_ms" json:"initial_backoff_ms"`
+    MaxBackoffMs       int   `yaml:"max_backoff_ms" json:"max_backoff_ms"`
+    RetryOn            []int `yaml:"retry_on" json:"retry_on"` // status codes, default 429, 502, 503, 504
+    RetryNonIdempotent bool  `yaml:"retry_non_idempotent" json:"retry_non_idempotent"`
 }
+
+// McpTLSConfig configures server verification and client certificates (mTLS).
+type McpTLSConfig struct {
+    CAFile             string `yaml:"ca_file" json:"ca_file"`
+    CertFile           string `yaml:"cert_file" json:"cert_file"`
+    KeyFile            string `yaml:"key_file" json:"key_file"`
+    ServerName         string `yaml:"server_name" json:"server_name"`
+    InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+}
+// McpServerConfig describes a Model Context Protocol server whose tools are
+// discovered with tools/list and registered alongside the local tools.
+// Command/args start a stdio server; url connects over HTTP. ${VAR} references
+// in env and headers are expanded from the process environment.
+type McpServerConfig struct {
+    Name           string            `yaml:"name" json:"name"`
+    Transport      string            `yaml:"transport" json:"transport"` // stdio, http (streamable HTTP) or sse (legacy); inferred if empty
+    Command        string            `yaml:"command" json:"command"`
+    Args           []string          `yaml:"args" json:"args"`
+    Env            map[string]string `yaml:"env" json:"env"`
+    Dir            string            `yaml:"dir" json:"dir"`
+    URL            string            `yaml:"url" json:"url"`
+    Headers        map[string]string `yaml:"headers" json:"headers"`
+    Tools          []string          `yaml:"tools" json:"tools"`   // remote tools to register (default: all)
+    Prefix         string            `yaml:"prefix" json:"prefix"` // replaces the server name in registered tool names
+    TimeoutSeconds int               `yaml:"timeout_seconds" json:"timeout_seconds"`
+    Disabled       bool              `yaml:"disabled" json:"disabled"`
+    // Routing of the server's tools, as for mcp_tools entries.
+    Executor         string `yaml:"executor" json:"executor"`
+    RequiresApproval bool   `yaml:"requires_approval" json:"requires_approval"`
+}
+
+// PluginConfig describes an out-of-process tool: an executable speaking the
+// plugin protocol (JSON lines on stdin/stdout, see tools.PluginTool) that
+// implements one tool. Dir defaults to the directory of mcp_tools.yaml, so
+// relative commands and args resolve against it. ${VAR} references in env
+// are expanded from the secret store.
+type PluginConfig struct {
+    Name           string            `yaml:"name" json:"name"`
+    Command        string            `yaml:"command" json:"command"`
+    Args           []string          `yaml:"args" json:"args"`
+    Env            map[string]string `yaml:"env" json:"env"`
+    Dir            string            `yaml:"dir" json:"dir"`
+    TimeoutSeconds int               `yaml:"timeout_seconds" json:"timeout_seconds"` // per call, default 30
+    MaxRestarts    *int              `yaml:"max_restarts" json:"max_restarts"`       // consecutive crashes tolerated, default 3; 0 never restarts
+    Disabled       bool              `yaml:"disabled" json:"disabled"`
+    // Routing of the plugin's tool, as for mcp_tools entries.
+    Executor         string `yaml:"executor" json:"executor"`
+    RequiresApproval bool   `yaml:"requires_approval" json:"requires_approval"`
+}
+
 type McpConfig struct {
-    McpTools []McpToolConfig `yaml:"mcp_tools" json:"mcp_tools"`
+    McpTools   []McpToolConfig   `yaml:"mcp_tools" json:"mcp_tools"`
+    McpServers []McpServerConfig `yaml:"mcp_servers" json:"mcp_servers"`
+    Plugins    []PluginConfig    `yaml:"plugins" json:"plugins"`
+    CompositeTools []CompositeToolConfig `yaml:"composite_tools" json:"composite_tools"`
 }
 
 
+// LoadConfig reads mcp_tools.yaml strictly: unknown keys are errors, and the
+// result is validated with ValidateMcpConfig. All problems are returned
+// together as ValidationErrors annotated with file:line.
 func LoadConfig(path string) (*McpConfig, error) {
-    f, err := os.Open(path)
+    data, err := os.ReadFile(path)
     if err != nil {
         return nil, err
     }
-    defer f.Close()
+    var root yaml.Node
+    if err := yaml.Unmarshal(data, &root); err != nil {
+        return nil, yamlErrors(path, err)
+    }
+    v := &validator{file: path, root: &root}
     var cfg McpConfig
-    decoder := yaml.NewDecoder(f)
-    if err := decoder.Decode(&cfg); err != nil {
-        return nil, err
+    decoder := yaml.NewDecoder(bytes.NewReader(data))
+    decoder.KnownFields(true)
+    if err := decoder.Decode(&cfg); err != nil && err != io.EOF {
+        // Type errors (unknown keys, wrong kinds) leave the rest decoded, so
+        // keep validating and report everything at once.
+        if _, ok := err.(*yaml.TypeError); !ok {
+            return nil, yamlErrors(path, err)
+        }
+        v.errs = append(v.errs, yamlErrors(path, err)...)
     }
-    return &cfg, nil
-}
-func ValidateMcpConfig(cfg *McpConfig) error {
-    hasErr := false
-    for _, tool := range cfg.McpTools {
-        for _, op := range tool.Operations {
-            params := op.Parameters // already map[string]interface{}
-            if params == nil {
-                utils.Logger.Error().
-                    Str("tool", tool.Name).
-                    Str("operation", op.Name).
-                    Msg("Missing parameters block")
-                hasErr = true
-                continue
-            }
-            // Check type
-            typ, _ := params["type"].(string)
-            if typ != "object" {
-                utils.Logger.Error().
-                    Str("tool", tool.Name).
-                    Str("operation", op.Name).
-                    Msg("parameters.type must be 'object'")
-                hasErr = true
-            }
-            // Check properties
-            props, _ := params["properties"].(map[string]interface{})
-            if props == nil || len(props) == 0 {
-                utils.Logger.Error().
-                    Str("tool", tool.Name).
-                    Str("operation", op.Name).
-                    Msg("parameters.properties missing or empty")
-                hasErr = true
-            }
-            // Check required
-            req, _ := params["required"].([]interface{})
-            if req == nil || len(req) == 0 {
-                utils.Logger.Error().
-                    Str("tool", tool.Name).
-                    Str("operation", op.Name).
-                    Msg("parameters.required missing or empty")
-                hasErr = true
-            } else {
-                for _, r := range req {
-                    rStr, _ := r.(string)
-                    if rStr == "" || props[rStr] == nil {
-                        utils.Logger.Error().
-                            Str("tool", tool.Name).
-                            Str("operation", op.Name).
-                            Str("required", rStr).
-                            Msgf("Required property '%s' not in properties", rStr)
-                        hasErr = true
-                    }
-                }
-            }
+
+    for i := range cfg.McpTools {
+        t := &cfg.McpTools[i]
+        if t.OpenAPI == nil {
+            continue
+        }
+        if err := t.loadOpenAPI(filepath.Dir(path)); err != nil {
+            v.errorf(cfgPath{"mcp_tools", i, "openapi"}, "%v", err)
+        }
+    }
+    for i := range cfg.Plugins {
+        p := &cfg.Plugins[i]
+        if p.Dir == "" {
+            p.Dir = filepath.Dir(path)
+        } else if !filepath.IsAbs(p.Dir) {
+            p.Dir = filepath.Join(filepath.Dir(path), p.Dir)
         }
     }
-    if hasErr {
-        return fmt.Errorf("invalid MCP config: see above errors")
+    v.validateMcp(&cfg)
+    if err := v.err(); err != nil {
+        return nil, err
     }
-    return nil
-}
\ No newline at end of file
+    return &cfg, nil
+}
@@ -0,0 +1,434 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"aiupstart.com/go-gen/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISource generates operations from a local OpenAPI 3 document (YAML
+// or JSON). Filters combine: an operation must match every filter given.
+type OpenAPISource struct {
+	File        string   `yaml:"file" json:"file"`                 // relative to the config file
+	Tags        []string `yaml:"tags" json:"tags"`                 // keep operations with any of these tags
+	ExcludeTags []string `yaml:"exclude_tags" json:"exclude_tags"` // drop operations with any of these tags
+	Paths       []string `yaml:"paths" json:"paths"`               // path patterns; "*" matches one segment, a trailing "/**" any suffix
+	Operations  []string `yaml:"operations" json:"operations"`     // keep only these operation names
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+var (
+	operationNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+	pathTemplateParam      = regexp.MustCompile(`\{([^{}]+)\}`)
+)
+
+// loadOpenAPI appends the operations generated from the tool's openapi
+// source. An empty endpoint defaults to the document's first server, and a
+// missing auth to the document's security requirement.
+func (t *McpToolConfig) loadOpenAPI(baseDir string) error {
+	file := t.OpenAPI.File
+	if file == "" {
+		return fmt.Errorf("openapi.file is required")
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(baseDir, file)
+	}
+	imported, err := LoadOpenAPI(file, *t.OpenAPI, t.Name)
+	if err != nil {
+		return err
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = imported.Server
+	}
+	if t.Auth == nil {
+		t.Auth = imported.Auth
+	}
+	t.Operations = append(t.Operations, imported.Operations...)
+	utils.Logger.Debug().Str("tool", t.Name).Int("operations", len(imported.Operations)).Msg("Loaded OpenAPI operations")
+	return nil
+}
+
+// OpenAPIImport is what an OpenAPI document contributes to a tool.
+type OpenAPIImport struct {
+	Operations []McpToolOperation
+	Server     string         // first server URL
+	Auth       *McpAuthConfig // nil without a supported security requirement
+}
+
+// LoadOpenAPI reads an OpenAPI 3 document and returns one operation per
+// selected path/method, the first server URL and the auth of the security
+// requirement (see openAPIAuth; credentials are secrets named after tool).
+// Parameter schemas combine path and query parameters with the JSON
+// request body; local $refs (#/components/...) are inlined.
+func LoadOpenAPI(file string, src OpenAPISource, tool string) (*OpenAPIImport, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	version, _ := doc["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%s: not an OpenAPI 3 document (openapi: %q)", file, version)
+	}
+	r := &refResolver{doc: doc}
+
+	imported := &OpenAPIImport{}
+	if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		if s, ok := servers[0].(map[string]interface{}); ok {
+			imported.Server, _ = s["url"].(string)
+		}
+	}
+	// The document's requirement applies to every operation; without one,
+	// the first selected operation declaring security decides.
+	security, hasSecurity := doc["security"].([]interface{})
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	keys := make([]string, 0, len(paths))
+	for p := range paths {
+		keys = append(keys, p)
+	}
+	sort.Strings(keys)
+
+	seen := map[string]bool{}
+	for _, p := range keys {
+		if !src.matchPath(p) {
+			continue
+		}
+		item, _ := r.resolve(paths[p], nil).(map[string]interface{})
+		common, _ := item["parameters"].([]interface{})
+		for _, method := range openAPIMethods {
+			raw, ok := item[method].(map[string]interface{})
+			if !ok || !src.matchTags(raw["tags"]) {
+				continue
+			}
+			op, err := r.operation(p, method, raw, common)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), p, err)
+			}
+			if !src.matchOperation(op.Name) {
+				continue
+			}
+			if seen[op.Name] {
+				return nil, fmt.Errorf("duplicate operation name %q (%s %s)", op.Name, strings.ToUpper(method), p)
+			}
+			seen[op.Name] = true
+			imported.Operations = append(imported.Operations, op)
+			if !hasSecurity {
+				security, hasSecurity = raw["security"].([]interface{})
+			}
+		}
+	}
+	imported.Auth = r.auth(security, tool)
+	return imported, nil
+}
+
+var secretNameSanitizer = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// auth maps the first supported alternative of a security requirement to
+// an auth config. Credentials reference secrets named after the tool, e.g.
+// for tool "petstore": PETSTORE_TOKEN (http bearer), PETSTORE_USERNAME and
+// PETSTORE_PASSWORD (http basic), PETSTORE_API_KEY (apiKey in a header or
+// the query) and PETSTORE_CLIENT_ID and PETSTORE_CLIENT_SECRET (oauth2
+// client credentials, with the requirement's scopes). An empty alternative
+// makes auth optional, so none is configured; alternatives combining
+// schemes, cookie API keys, OpenID Connect and other OAuth2 flows are
+// skipped.
+func (r *refResolver) auth(security []interface{}, tool string) *McpAuthConfig {
+	components, _ := r.doc["components"].(map[string]interface{})
+	schemes, _ := components["securitySchemes"].(map[string]interface{})
+	prefix := strings.Trim(secretNameSanitizer.ReplaceAllString(strings.ToUpper(tool), "_"), "_")
+	secret := func(name string) string { return "${" + prefix + "_" + name + "}" }
+
+	for _, alt := range security {
+		req, _ := alt.(map[string]interface{})
+		if len(req) == 0 {
+			return nil
+		}
+		if len(req) > 1 {
+			continue
+		}
+		for name, scopes := range req {
+			scheme, _ := r.resolve(schemes[name], nil).(map[string]interface{})
+			kind, _ := scheme["type"].(string)
+			switch kind {
+			case "http":
+				switch s, _ := scheme["scheme"].(string); strings.ToLower(s) {
+				case "bearer":
+					return &McpAuthConfig{Type: "bearer", Token: secret("TOKEN")}
+				case "basic":
+					return &McpAuthConfig{Type: "basic", Username: secret("USERNAME"), Password: secret("PASSWORD")}
+				}
+			case "apiKey":
+				param, _ := scheme["name"].(string)
+				if in, _ := scheme["in"].(string); param != "" && (in == "header" || in == "query") {
+					return &McpAuthConfig{Type: "api_key", Name: param, In: in, Value: secret("API_KEY")}
+				}
+			case "oauth2":
+				flows, _ := scheme["flows"].(map[string]interface{})
+				flow, _ := flows["clientCredentials"].(map[string]interface{})
+				if tokenURL, _ := flow["tokenUrl"].(string); tokenURL != "" {
+					auth := &McpAuthConfig{Type: "oauth2_client_credentials", TokenURL: tokenURL, ClientID: secret("CLIENT_ID"), ClientSecret: secret("CLIENT_SECRET")}
+					for _, s := range asList(scopes) {
+						if scope, ok := s.(string); ok {
+							auth.Scopes = append(auth.Scopes, scope)
+						}
+					}
+					return auth
+				}
+			}
+			utils.Logger.Debug().Str("tool", tool).Str("scheme", name).Msgf("Skipping unsupported %s security scheme", kind)
+		}
+	}
+	return nil
+}
+
+func (s OpenAPISource) matchPath(p string) bool {
+	if len(s.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range s.Paths {
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			n := len(strings.Split(prefix, "/"))
+			parts := strings.Split(p, "/")
+			if len(parts) >= n {
+				if m, _ := path.Match(prefix, strings.Join(parts[:n], "/")); m {
+					return true
+				}
+			}
+			continue
+		}
+		if m, _ := path.Match(pattern, p); m {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OpenAPISource) matchTags(raw interface{}) bool {
+	tags := map[string]bool{}
+	if list, ok := raw.([]interface{}); ok {
+		for _, t := range list {
+			if name, ok := t.(string); ok {
+				tags[name] = true
+			}
+		}
+	}
+	for _, t := range s.ExcludeTags {
+		if tags[t] {
+			return false
+		}
+	}
+	if len(s.Tags) == 0 {
+		return true
+	}
+	for _, t := range s.Tags {
+		if tags[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OpenAPISource) matchOperation(name string) bool {
+	if len(s.Operations) == 0 {
+		return true
+	}
+	for _, n := range s.Operations {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// refResolver inlines local JSON references.
+type refResolver struct {
+	doc map[string]interface{}
+}
+
+func (r *refResolver) lookup(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only local $ref supported, got %q", ref)
+	}
+	var cur interface{} = r.doc
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+// resolve returns v with every $ref replaced by a copy of its target. Refs
+// that are unresolvable or recursive (already on stack) become an
+// unconstrained object, so schemas stay finite.
+func (r *refResolver) resolve(v interface{}, stack []string) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := x["$ref"].(string); ok {
+			for _, s := range stack {
+				if s == ref {
+					return map[string]interface{}{"type": "object"}
+				}
+			}
+			target, err := r.lookup(ref)
+			if err != nil {
+				utils.Logger.Warn().Err(err).Msg("OpenAPI $ref left unresolved")
+				return map[string]interface{}{"type": "object"}
+			}
+			return r.resolve(target, append(stack[:len(stack):len(stack)], ref))
+		}
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[k] = r.resolve(val, stack)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, val := range x {
+			out[i] = r.resolve(val, stack)
+		}
+		return out
+	}
+	return v
+}
+
+// operation builds one McpToolOperation. Path and query parameters become
+// properties; a JSON object body contributes its properties, any other body
+// becomes a "body" argument sent as the whole request body.
+func (r *refResolver) operation(p, method string, raw map[string]interface{}, common []interface{}) (McpToolOperation, error) {
+	op := McpToolOperation{Path: p, Method: strings.ToUpper(method)}
+	op.Name, _ = raw["operationId"].(string)
+	if op.Name == "" {
+		op.Name = method + "_" + strings.Trim(operationNameSanitizer.ReplaceAllString(p, "_"), "_")
+	}
+	op.Name = strings.Trim(operationNameSanitizer.ReplaceAllString(op.Name, "_"), "_")
+	if len(op.Name) > 64 {
+		op.Name = op.Name[:64]
+	}
+	summary, _ := raw["summary"].(string)
+	description, _ := raw["description"].(string)
+	op.Description = summary
+	if op.Description == "" {
+		op.Description = description
+	}
+
+	props := map[string]interface{}{}
+	var required []interface{}
+
+	// Operation-level parameters override path-level ones with the same name+in.
+	params := map[string]map[string]interface{}{}
+	var order []string
+	for _, list := range [][]interface{}{common, asList(raw["parameters"])} {
+		for _, pv := range list {
+			param, _ := r.resolve(pv, nil).(map[string]interface{})
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			if name == "" {
+				continue
+			}
+			key := in + ":" + name
+			if _, dup := params[key]; !dup {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+	for _, key := range order {
+		param := params[key]
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if in != "path" && in != "query" {
+			utils.Logger.Debug().Str("operation", op.Name).Str("param", name).Msgf("Skipping %s parameter", in)
+			continue
+		}
+		schema, _ := param["schema"].(map[string]interface{})
+		if schema == nil {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		schema = copyMap(schema)
+		if d, ok := param["description"].(string); ok && schema["description"] == nil {
+			schema["description"] = d
+		}
+		props[name] = schema
+		if req, _ := param["required"].(bool); req || in == "path" {
+			required = append(required, name)
+		}
+		if in == "query" {
+			op.QueryParams = append(op.QueryParams, name)
+		}
+	}
+
+	// Path templates must always be fillable, even if the spec omits the parameter.
+	for _, m := range pathTemplateParam.FindAllStringSubmatch(p, -1) {
+		if _, ok := props[m[1]]; !ok {
+			props[m[1]] = map[string]interface{}{"type": "string"}
+			required = append(required, m[1])
+		}
+	}
+
+	if body, ok := r.resolve(raw["requestBody"], nil).(map[string]interface{}); ok {
+		content, _ := body["content"].(map[string]interface{})
+		media, _ := content["application/json"].(map[string]interface{})
+		if media == nil {
+			for ct, m := range content {
+				if strings.HasSuffix(ct, "+json") {
+					media, _ = m.(map[string]interface{})
+				}
+			}
+		}
+		schema, _ := media["schema"].(map[string]interface{})
+		bodyRequired, _ := body["required"].(bool)
+		bodyProps, _ := schema["properties"].(map[string]interface{})
+		if schema["type"] == "object" || (schema["type"] == nil && bodyProps != nil) {
+			for name, s := range bodyProps {
+				if _, clash := props[name]; clash {
+					return op, fmt.Errorf("body property %q clashes with a parameter", name)
+				}
+				props[name] = s
+			}
+			required = append(required, asList(schema["required"])...)
+		} else if schema != nil {
+			props["body"] = schema
+			op.BodyParam = "body"
+			if bodyRequired {
+				required = append(required, "body")
+			}
+		}
+	}
+
+	op.Parameters = map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		op.Parameters["required"] = required
+	}
+	return op, nil
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstoreSpec = "testdata/openapi/petstore.yaml"
+
+type schema = map[string]interface{}
+
+func TestLoadOpenAPIOperations(t *testing.T) {
+	imported, err := LoadOpenAPI(petstoreSpec, OpenAPISource{}, "petstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Server != "https://petstore.example.com/v1" {
+		t.Errorf("server = %q, want the first one", imported.Server)
+	}
+
+	petID := schema{"type": "integer", "description": "ID of the pet"}
+	want := []McpToolOperation{
+		{
+			// Names are sanitized.
+			Name: "reset_everything", Path: "/admin/reset", Method: "POST",
+			Parameters: schema{"type": "object", "properties": schema{}},
+		},
+		{
+			// Query parameters, one through a $ref; header parameters are
+			// left out.
+			Name: "listPets", Path: "/pets", Method: "GET", Description: "List pets",
+			Parameters: schema{
+				"type": "object",
+				"properties": schema{
+					"limit": schema{"type": "integer", "maximum": 100, "description": "Max items"},
+					"tag":   schema{"type": "string"},
+				},
+				"required": []interface{}{"tag"},
+			},
+			QueryParams: []string{"limit", "tag"},
+		},
+		{
+			// An object body contributes its properties; the recursive
+			// reference back to NewPet ends in a plain object.
+			Name: "createPet", Path: "/pets", Method: "POST", Description: "Add a pet to the store",
+			Parameters: schema{
+				"type": "object",
+				"properties": schema{
+					"name": schema{"type": "string"},
+					"owner": schema{
+						"type": "object",
+						"properties": schema{
+							"name": schema{"type": "string"},
+							"pets": schema{"type": "array", "items": schema{"type": "object"}},
+						},
+					},
+				},
+				"required": []interface{}{"name"},
+			},
+		},
+		{
+			// Path-level parameters apply to each method; without an
+			// operationId the name comes from the method and path.
+			Name: "get_pets_petId", Path: "/pets/{petId}", Method: "GET", Description: "Get a pet",
+			Parameters: schema{
+				"type":       "object",
+				"properties": schema{"petId": petID},
+				"required":   []interface{}{"petId"},
+			},
+		},
+		{
+			// Any other body (a +json array here) is the "body" argument.
+			Name: "replaceTags", Path: "/pets/{petId}", Method: "PUT", Description: "Replace the tags of a pet",
+			Parameters: schema{
+				"type": "object",
+				"properties": schema{
+					"petId": petID,
+					"body":  schema{"type": "array", "items": schema{"type": "string"}},
+				},
+				"required": []interface{}{"petId", "body"},
+			},
+			BodyParam: "body",
+		},
+	}
+	if len(imported.Operations) != len(want) {
+		t.Fatalf("got %d operations, want %d", len(imported.Operations), len(want))
+	}
+	for i, op := range imported.Operations {
+		if !reflect.DeepEqual(op, want[i]) {
+			t.Errorf("operation %d:\n got %#v\nwant %#v", i, op, want[i])
+		}
+	}
+	if want := (&McpAuthConfig{Type: "api_key", Name: "X-API-Key", In: "header", Value: "${PETSTORE_API_KEY}"}); !reflect.DeepEqual(imported.Auth, want) {
+		t.Errorf("auth = %+v, want %+v", imported.Auth, want)
+	}
+}
+
+func TestLoadOpenAPIFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		src  OpenAPISource
+		want []string
+	}{
+		{"tags", OpenAPISource{Tags: []string{"tags", "admin"}}, []string{"reset_everything", "replaceTags"}},
+		{"exclude tags", OpenAPISource{ExcludeTags: []string{"admin", "tags"}}, []string{"listPets", "createPet", "get_pets_petId"}},
+		{"path segment", OpenAPISource{Paths: []string{"/pets/*"}}, []string{"get_pets_petId", "replaceTags"}},
+		{"path prefix", OpenAPISource{Paths: []string{"/pets/**"}, ExcludeTags: []string{"tags"}}, []string{"listPets", "createPet", "get_pets_petId"}},
+		{"operations", OpenAPISource{Operations: []string{"listPets", "get_pets_petId"}}, []string{"listPets", "get_pets_petId"}},
+	}
+	for _, tt := range tests {
+		imported, err := LoadOpenAPI(petstoreSpec, tt.src, "petstore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, op := range imported.Operations {
+			names = append(names, op.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%s: operations %v, want %v", tt.name, names, tt.want)
+		}
+	}
+}
+
+// securitySpec is a document offering every kind of security scheme;
+// %SECURITY% is replaced by the requirement under test.
+const securitySpec = `openapi: 3.1.0
+%SECURITY%
+paths:
+  /pets:
+    get:
+      operationId: listPets
+%OPSECURITY%
+components:
+  securitySchemes:
+    bearer: {type: http, scheme: Bearer}
+    basic: {type: http, scheme: basic}
+    digest: {type: http, scheme: digest}
+    queryKey: {type: apiKey, in: query, name: api_key}
+    cookieKey: {type: apiKey, in: cookie, name: session}
+    oauth:
+      type: oauth2
+      flows:
+        authorizationCode: {authorizationUrl: https://auth.example.com/authorize, tokenUrl: https://auth.example.com/token, scopes: {}}
+        clientCredentials: {tokenUrl: https://auth.example.com/token, scopes: {pets:read: read, pets:write: write}}
+    userOnly:
+      type: oauth2
+      flows:
+        authorizationCode: {authorizationUrl: https://auth.example.com/authorize, tokenUrl: https://auth.example.com/token, scopes: {}}
+    oidc: {type: openIdConnect, openIdConnectUrl: https://auth.example.com/.well-known/openid-configuration}
+    shared: {$ref: "#/components/securitySchemes/bearer"}
+`
+
+func TestLoadOpenAPISecurity(t *testing.T) {
+	tests := []struct {
+		name       string
+		security   string // document-level
+		opSecurity string // operation-level
+		want       *McpAuthConfig
+	}{
+		{"none", "", "", nil},
+		{"bearer", "security: [{bearer: []}]", "", &McpAuthConfig{Type: "bearer", Token: "${PET_STORE_TOKEN}"}},
+		{"basic", "security: [{basic: []}]", "", &McpAuthConfig{Type: "basic", Username: "${PET_STORE_USERNAME}", Password: "${PET_STORE_PASSWORD}"}},
+		{"query api key", "security: [{queryKey: []}]", "", &McpAuthConfig{Type: "api_key", Name: "api_key", In: "query", Value: "${PET_STORE_API_KEY}"}},
+		{"oauth2 client credentials", "security: [{oauth: [pets:read]}]", "", &McpAuthConfig{
+			Type: "oauth2_client_credentials", TokenURL: "https://auth.example.com/token",
+			ClientID: "${PET_STORE_CLIENT_ID}", ClientSecret: "${PET_STORE_CLIENT_SECRET}", Scopes: []string{"pets:read"},
+		}},
+		{"scheme through a $ref", "security: [{shared: []}]", "", &McpAuthConfig{Type: "bearer", Token: "${PET_STORE_TOKEN}"}},
+		{"unsupported schemes", "security: [{digest: []}, {cookieKey: []}, {userOnly: []}, {oidc: []}]", "", nil},
+		{"first supported alternative", "security: [{oidc: []}, {bearer: [], basic: []}, {basic: []}]", "", &McpAuthConfig{Type: "basic", Username: "${PET_STORE_USERNAME}", Password: "${PET_STORE_PASSWORD}"}},
+		{"optional auth", "security: [{}, {bearer: []}]", "", nil},
+		{"operation level", "", "      security: [{queryKey: []}]", &McpAuthConfig{Type: "api_key", Name: "api_key", In: "query", Value: "${PET_STORE_API_KEY}"}},
+		{"document level wins", "security: [{bearer: []}]", "      security: [{queryKey: []}]", &McpAuthConfig{Type: "bearer", Token: "${PET_STORE_TOKEN}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := strings.NewReplacer("%SECURITY%", tt.security, "%OPSECURITY%", tt.opSecurity).Replace(securitySpec)
+			imported, err := LoadOpenAPI(writeConfig(t, "spec.yaml", spec), OpenAPISource{}, "pet-store")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(imported.Auth, tt.want) {
+				t.Errorf("auth = %+v, want %+v", imported.Auth, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name, spec, want string
+	}{
+		{"swagger 2", "swagger: \"2.0\"\npaths: {}\n", `not an OpenAPI 3 document (openapi: "")`},
+		{"body clashes with a parameter", `openapi: 3.0.0
+paths:
+  /pets/{id}:
+    put:
+      requestBody:
+        content:
+          application/json:
+            schema: {type: object, properties: {id: {type: string}}}
+`, `PUT /pets/{id}: body property "id" clashes with a parameter`},
+		{"duplicate names", `openapi: 3.0.0
+paths:
+  /a:
+    get: {operationId: list}
+  /b:
+    get: {operationId: list}
+`, `duplicate operation name "list" (GET /b)`},
+	}
+	for _, tt := range tests {
+		_, err := LoadOpenAPI(writeConfig(t, "spec.yaml", tt.spec), OpenAPISource{}, "api")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigOpenAPI(t *testing.T) {
+	spec, err := filepath.Abs(petstoreSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, "mcp_tools.yaml", `mcp_tools:
+  - name: petstore
+    openapi:
+      file: `+spec+`
+      operations: [listPets]
+  - name: staging
+    endpoint: https://staging.example.com/v1
+    auth: {type: bearer, token: "${STAGING_TOKEN}"}
+    openapi:
+      file: `+spec+`
+      operations: [createPet]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The document fills in the endpoint and auth unless the tool sets them.
+	petstore, staging := cfg.McpTools[0], cfg.McpTools[1]
+	if petstore.Endpoint != "https://petstore.example.com/v1" || petstore.Auth == nil || petstore.Auth.Value != "${PETSTORE_API_KEY}" {
+		t.Errorf("petstore: endpoint %q, auth %+v", petstore.Endpoint, petstore.Auth)
+	}
+	if staging.Endpoint != "https://staging.example.com/v1" || staging.Auth == nil || staging.Auth.Type != "bearer" {
+		t.Errorf("staging: endpoint %q, auth %+v", staging.Endpoint, staging.Auth)
+	}
+	if len(petstore.Operations) != 1 || petstore.Operations[0].Name != "listPets" {
+		t.Errorf("petstore operations = %+v", petstore.Operations)
+	}
+}
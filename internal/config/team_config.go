@@ -0,0 +1,244 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Agent types understood by the team factory (agent.BuildTeam).
+const (
+	AgentTypeOrchestrator = "orchestrator" // picks the agent for each task
+	AgentTypeAssistant    = "assistant"    // LLM agent that writes code and calls tools
+	AgentTypeToolRunner   = "tool_runner"  // executes tool calls
+	AgentTypeHITL         = "hitl"         // asks a human before running tools
+	AgentTypePlanner      = "planner"      // LLM agent that breaks tasks into steps
+	AgentTypeResearcher   = "researcher"   // LLM agent that answers research questions
+)
+
+var agentTypes = map[string]bool{
+	AgentTypeOrchestrator: true, AgentTypeAssistant: true, AgentTypeToolRunner: true,
+	AgentTypeHITL: true, AgentTypePlanner: true, AgentTypeResearcher: true,
+}
+
+// TeamConfig declares the agents of a chat session. Messages enter at Entry
+// (default: the orchestrator, else the first agent).
+type TeamConfig struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description"`
+	Entry       string            `yaml:"entry" json:"entry"`
+	Agents      []TeamAgentConfig `yaml:"agents" json:"agents"`
+	Routes      []TeamRouteConfig `yaml:"routes" json:"routes"` // explicit tool routes; first match wins
+
+	File string `yaml:"-" json:"-"` // file the team was loaded from
+}
+
+// TeamAgentConfig is one agent of a team. For agents calling tools
+// (assistant, planner, researcher, orchestrator) Tools and DenyTools are the
+// agent's scope: only permitted tools are offered to its LLM, and calls
+// outside the scope are denied when run. For tool_runner and hitl agents
+// Tools lists the tools they execute (see RoutingTable), and they run no
+// others except those routed to them.
+type TeamAgentConfig struct {
+	Name         string   `yaml:"name" json:"name"`
+	Type         string   `yaml:"type" json:"type"`
+	LLM          string   `yaml:"llm" json:"llm"`                     // provider from llm.providers; default llm.default
+	Model        string   `yaml:"model" json:"model"`                 // overrides the provider's model
+	Prompt       string   `yaml:"prompt" json:"prompt"`               // system prompt / persona
+	PromptFile   string   `yaml:"prompt_file" json:"prompt_file"`     // read into Prompt, relative to the team file
+	Tools        []string `yaml:"tools" json:"tools"`                 // allowed tools (globs, e.g. workspace_*); empty allows all
+	DenyTools    []string `yaml:"deny_tools" json:"deny_tools"`       // tools never allowed, even if matched by tools
+	Description  string   `yaml:"description" json:"description"`     // tells the orchestrator when to route here
+	ApproveTools bool     `yaml:"approve_tools" json:"approve_tools"` // hitl: ask before each tool call
+}
+
+// TeamRouteConfig sends calls of the tools matching Tool (a name or glob)
+// to Agent, a tool_runner or hitl agent of the team. Routes take precedence
+// over tool metadata and the agents' tool patterns.
+type TeamRouteConfig struct {
+	Tool  string `yaml:"tool" json:"tool"`
+	Agent string `yaml:"agent" json:"agent"`
+}
+
+// IsExecutor reports whether the agent type runs tool calls.
+func (a TeamAgentConfig) IsExecutor() bool {
+	return a.Type == AgentTypeToolRunner || a.Type == AgentTypeHITL
+}
+
+// UsesLLM reports whether the agent type needs an LLM client.
+func (a TeamAgentConfig) UsesLLM() bool {
+	switch a.Type {
+	case AgentTypeOrchestrator, AgentTypeAssistant, AgentTypePlanner, AgentTypeResearcher:
+		return true
+	}
+	return false
+}
+
+// DefaultTeam is the built-in code team: an orchestrator routing to an
+// assistant (with the configured system prompt), a tool runner, and a hitl
+// agent for tools that require approval.
+func DefaultTeam(app *AppConfig) *TeamConfig {
+	return &TeamConfig{
+		Name:  "code",
+		Entry: "Orchestrator",
+		Agents: []TeamAgentConfig{
+			{Name: "Orchestrator", Type: AgentTypeOrchestrator},
+			{Name: "Assistant", Type: AgentTypeAssistant, Prompt: app.Agents.SystemPrompt,
+				Description: "writes, fixes and runs code"},
+			{Name: "ToolRunner", Type: AgentTypeToolRunner,
+				Description: "executes tool calls"},
+			{Name: "HITL", Type: AgentTypeHITL,
+				Description: "asks the user to approve tool calls that require it"},
+		},
+	}
+}
+
+// LoadTeamConfig reads a team file strictly (unknown keys are errors),
+// loads prompt files and validates the team.
+func LoadTeamConfig(path string) (*TeamConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, yamlErrors(path, err)
+	}
+	v := &validator{file: path, root: &root}
+	var team TeamConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&team); err != nil && err != io.EOF {
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return nil, yamlErrors(path, err)
+		}
+		v.errs = append(v.errs, yamlErrors(path, err)...)
+	}
+	team.File = path
+	for i := range team.Agents {
+		a := &team.Agents[i]
+		if a.PromptFile == "" {
+			continue
+		}
+		p := cfgPath{"agents", i, "prompt_file"}
+		if a.Prompt != "" {
+			v.errorf(p, "set either prompt or prompt_file, not both")
+			continue
+		}
+		file := a.PromptFile
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		prompt, err := os.ReadFile(file)
+		if err != nil {
+			v.errorf(p, "%v", err)
+			continue
+		}
+		a.Prompt = strings.TrimSpace(string(prompt))
+	}
+	v.validateTeam(&team)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// ValidateTeam checks a team built in code; LoadTeamConfig runs the same
+// checks with file positions.
+func ValidateTeam(team *TeamConfig) error {
+	v := &validator{file: team.File}
+	v.validateTeam(team)
+	return v.err()
+}
+
+// EntryAgent returns the agent receiving user messages.
+func (t *TeamConfig) EntryAgent() string {
+	if t.Entry != "" {
+		return t.Entry
+	}
+	for _, a := range t.Agents {
+		if a.Type == AgentTypeOrchestrator {
+			return a.Name
+		}
+	}
+	if len(t.Agents) > 0 {
+		return t.Agents[0].Name
+	}
+	return ""
+}
+
+func (v *validator) validateTeam(team *TeamConfig) {
+	if len(team.Agents) == 0 {
+		v.errorf(cfgPath{"agents"}, "a team needs at least one agent")
+		return
+	}
+	names := map[string]bool{}
+	orchestrators := 0
+	for i, a := range team.Agents {
+		p := cfgPath{"agents", i}
+		switch {
+		case a.Name == "":
+			v.errorf(p, "name is required")
+		case names[a.Name]:
+			v.errorf(p.at("name"), "duplicate agent name %q", a.Name)
+		case a.Name == "Manager" || a.Name == "User":
+			v.errorf(p.at("name"), "%q is reserved", a.Name)
+		}
+		names[a.Name] = true
+		if !agentTypes[a.Type] {
+			v.errorf(p.at("type"), "unknown agent type %q (want orchestrator, assistant, tool_runner, hitl, planner or researcher)", a.Type)
+		}
+		if a.Type == AgentTypeOrchestrator {
+			orchestrators++
+			if orchestrators > 1 {
+				v.errorf(p.at("type"), "a team can have only one orchestrator")
+			}
+		}
+		for _, list := range []struct {
+			key      string
+			patterns []string
+		}{{"tools", a.Tools}, {"deny_tools", a.DenyTools}} {
+			for k, pattern := range list.patterns {
+				if !validToolPattern(pattern) {
+					v.errorf(p.at(list.key, k), "invalid tool pattern %q", pattern)
+				}
+			}
+		}
+		if len(a.DenyTools) > 0 && a.IsExecutor() {
+			v.errorf(p.at("deny_tools"), "%s agents run tools for others; restrict the calling agents instead", a.Type)
+		}
+	}
+	executors := map[string]bool{}
+	for _, a := range team.Agents {
+		if a.IsExecutor() {
+			executors[a.Name] = true
+		}
+	}
+	for i, r := range team.Routes {
+		p := cfgPath{"routes", i}
+		if !validToolPattern(r.Tool) {
+			v.errorf(p.at("tool"), "invalid tool pattern %q", r.Tool)
+		}
+		switch {
+		case !names[r.Agent]:
+			v.errorf(p.at("agent"), "agent %q is not in the team", r.Agent)
+		case !executors[r.Agent]:
+			v.errorf(p.at("agent"), "agent %q does not run tools (want a tool_runner or hitl agent)", r.Agent)
+		}
+	}
+	if team.Entry != "" && !names[team.Entry] {
+		v.errorf(cfgPath{"entry"}, "entry agent %q is not in the team", team.Entry)
+	}
+}
+
+// validToolPattern checks a tool glob in the path.Match syntax that
+// tools.MatchToolName applies at run time (config cannot import tools).
+func validToolPattern(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return pattern != "" && err == nil
+}
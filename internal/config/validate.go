@@ -0,0 +1,540 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is one problem found in a config file. Line and Column are 0
+// when the config was not read from YAML (or the value was generated, e.g.
+// from an OpenAPI document).
+type ConfigError struct {
+	File   string
+	Line   int
+	Column int
+	Path   string // e.g. mcp_tools[1].operations[0].parameters.type
+	Msg    string
+}
+
+func (e ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+			if e.Column > 0 {
+				fmt.Fprintf(&b, ":%d", e.Column)
+			}
+		}
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// ValidationErrors aggregates every problem found in a config, one per line.
+type ValidationErrors []ConfigError
+
+func (v ValidationErrors) Error() string {
+	lines := make([]string, len(v))
+	for i, e := range v {
+		lines[i] = e.Error()
+	}
+	return fmt.Sprintf("%d config error(s):\n  %s", len(v), strings.Join(lines, "\n  "))
+}
+
+var (
+	toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	yamlErrorLine   = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+)
+
+// yamlErrors converts a yaml.v3 decode error ("line 3: field x not found
+// in type ...", possibly several) into positioned ConfigErrors.
+func yamlErrors(file string, err error) ValidationErrors {
+	msgs := []string{err.Error()}
+	if te, ok := err.(*yaml.TypeError); ok {
+		msgs = te.Errors
+	}
+	var errs ValidationErrors
+	for _, msg := range msgs {
+		e := ConfigError{File: file, Msg: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			e.Msg = m[2]
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// cfgPath addresses a value in the config: string segments are mapping keys,
+// int segments sequence indexes.
+type cfgPath []interface{}
+
+func (p cfgPath) at(seg ...interface{}) cfgPath {
+	return append(p[:len(p):len(p)], seg...)
+}
+
+func (p cfgPath) String() string {
+	var b strings.Builder
+	for _, seg := range p {
+		switch s := seg.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// validator collects config errors, positioned via the parsed YAML when present.
+type validator struct {
+	file string
+	root *yaml.Node
+	errs ValidationErrors
+}
+
+// node returns the YAML node at p, or the deepest existing ancestor (e.g.
+// the tool entry for an operation generated from OpenAPI).
+func (v *validator) node(p cfgPath) *yaml.Node {
+	n := v.root
+	if n == nil {
+		return nil
+	}
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	for _, seg := range p {
+		var next *yaml.Node
+		switch s := seg.(type) {
+		case string:
+			if n.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(n.Content); i += 2 {
+					if n.Content[i].Value == s {
+						next = n.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if n.Kind == yaml.SequenceNode && s < len(n.Content) {
+				next = n.Content[s]
+			}
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+	return n
+}
+
+func (v *validator) errorf(p cfgPath, format string, args ...interface{}) {
+	e := ConfigError{File: v.file, Path: p.String(), Msg: fmt.Sprintf(format, args...)}
+	if n := v.node(p); n != nil {
+		e.Line, e.Column = n.Line, n.Column
+	}
+	v.errs = append(v.errs, e)
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Line < v.errs[j].Line })
+	return v.errs
+}
+
+// ValidateMcpConfig checks a loaded config and returns every problem found
+// as ValidationErrors. LoadConfig already runs it with file positions.
+func ValidateMcpConfig(cfg *McpConfig) error {
+	v := &validator{}
+	v.validateMcp(cfg)
+	return v.err()
+}
+
+func (v *validator) validateMcp(cfg *McpConfig) {
+	toolNames := map[string]bool{}
+	opNames := map[string]string{}
+	for i, t := range cfg.McpTools {
+		p := cfgPath{"mcp_tools", i}
+		switch {
+		case t.Name == "":
+			v.errorf(p, "name is required")
+		case toolNames[t.Name]:
+			v.errorf(p.at("name"), "duplicate tool name %q", t.Name)
+		}
+		toolNames[t.Name] = true
+
+		if t.Builtin {
+			if t.Endpoint != "" || t.OpenAPI != nil || t.Auth != nil {
+				v.errorf(p, "builtin tool %q takes no endpoint, openapi or auth", t.Name)
+			}
+		} else {
+			v.validateHTTPSettings(p, t)
+		}
+		if len(t.Operations) == 0 {
+			v.errorf(p, "tool %q has no operations", t.Name)
+		}
+		for j, op := range t.Operations {
+			op := op
+			if prev, dup := opNames[op.Name]; dup && op.Name != "" {
+				v.errorf(p.at("operations", j, "name"), "operation name %q already used by tool %q", op.Name, prev)
+			}
+			opNames[op.Name] = t.Name
+			v.validateOperation(p.at("operations", j), t, &op)
+		}
+	}
+
+	serverNames := map[string]bool{}
+	for i, s := range cfg.McpServers {
+		p := cfgPath{"mcp_servers", i}
+		switch {
+		case s.Name == "":
+			v.errorf(p, "name is required")
+		case serverNames[s.Name]:
+			v.errorf(p.at("name"), "duplicate server name %q", s.Name)
+		}
+		serverNames[s.Name] = true
+		if s.Command != "" && s.URL != "" {
+			v.errorf(p, "set either command (stdio) or url, not both")
+		}
+		switch s.Transport {
+		case "":
+			if s.Command == "" && s.URL == "" {
+				v.errorf(p, "command or url is required")
+			}
+		case "stdio":
+			if s.Command == "" {
+				v.errorf(p.at("transport"), "stdio transport needs a command")
+			}
+		case "http", "sse":
+			if s.URL == "" {
+				v.errorf(p.at("transport"), "%s transport needs a url", s.Transport)
+			}
+		default:
+			v.errorf(p.at("transport"), "unknown transport %q (want stdio, http or sse)", s.Transport)
+		}
+		if s.TimeoutSeconds < 0 {
+			v.errorf(p.at("timeout_seconds"), "must not be negative")
+		}
+	}
+
+	pluginNames := map[string]bool{}
+	for i, pl := range cfg.Plugins {
+		p := cfgPath{"plugins", i}
+		switch {
+		case pl.Name == "":
+			v.errorf(p, "name is required")
+		case pluginNames[pl.Name]:
+			v.errorf(p.at("name"), "duplicate plugin name %q", pl.Name)
+		case toolNames[pl.Name]:
+			v.errorf(p.at("name"), "plugin name %q already used by an mcp_tools entry", pl.Name)
+		}
+		pluginNames[pl.Name] = true
+		if pl.Command == "" {
+			v.errorf(p, "command is required")
+		}
+		if pl.TimeoutSeconds < 0 {
+			v.errorf(p.at("timeout_seconds"), "must not be negative")
+		}
+		if pl.MaxRestarts != nil && *pl.MaxRestarts < 0 {
+			v.errorf(p.at("max_restarts"), "must not be negative")
+		}
+	}
+
+	compositeNames := map[string]bool{}
+	for i, c := range cfg.CompositeTools {
+		p := cfgPath{"composite_tools", i}
+		switch {
+		case !toolNamePattern.MatchString(c.Name):
+			v.errorf(p.at("name"), "composite tool name %q must be 1-64 letters, digits, '_' or '-'", c.Name)
+		case compositeNames[c.Name]:
+			v.errorf(p.at("name"), "duplicate composite tool name %q", c.Name)
+		case opNames[c.Name] != "":
+			v.errorf(p.at("name"), "composite tool name %q already used by an operation of tool %q", c.Name, opNames[c.Name])
+		case pluginNames[c.Name]:
+			v.errorf(p.at("name"), "composite tool name %q already used by a plugin", c.Name)
+		}
+		compositeNames[c.Name] = true
+		v.validateComposite(p, c)
+	}
+}
+
+func (v *validator) validateHTTPSettings(p cfgPath, t McpToolConfig) {
+	if t.Endpoint == "" {
+		v.errorf(p, "endpoint is required (set builtin: true for tools implemented in code)")
+	} else if u, err := url.Parse(t.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(p.at("endpoint"), "endpoint %q is not an http(s) URL", t.Endpoint)
+	}
+	if t.TimeoutSeconds < 0 {
+		v.errorf(p.at("timeout_seconds"), "must not be negative")
+	}
+	if t.MaxResponseBytes < 0 {
+		v.errorf(p.at("max_response_bytes"), "must not be negative")
+	}
+	if a := t.Auth; a != nil {
+		ap := p.at("auth")
+		need := func(key, val string) {
+			if val == "" {
+				v.errorf(ap, "%s auth needs %s", a.Type, key)
+			}
+		}
+		switch a.Type {
+		case "bearer":
+			need("token", a.Token)
+		case "basic":
+			need("username", a.Username)
+		case "api_key":
+			need("value", a.Value)
+			if a.In != "" && a.In != "header" && a.In != "query" {
+				v.errorf(ap.at("in"), "must be header or query, got %q", a.In)
+			}
+		case "oauth2_client_credentials":
+			need("token_url", a.TokenURL)
+			need("client_id", a.ClientID)
+			need("client_secret", a.ClientSecret)
+		default:
+			v.errorf(ap.at("type"), "unknown auth type %q (want bearer, basic, api_key or oauth2_client_credentials)", a.Type)
+		}
+	}
+	if r := t.Retry; r != nil {
+		rp := p.at("retry")
+		if r.MaxAttempts < 0 || r.InitialBackoffMs < 0 || r.MaxBackoffMs < 0 {
+			v.errorf(rp, "attempts and backoffs must not be negative")
+		}
+		if r.InitialBackoffMs > 0 && r.MaxBackoffMs > 0 && r.InitialBackoffMs > r.MaxBackoffMs {
+			v.errorf(rp, "initial_backoff_ms exceeds max_backoff_ms")
+		}
+		for k, code := range r.RetryOn {
+			if code < 100 || code > 599 {
+				v.errorf(rp.at("retry_on", k), "%d is not an HTTP status code", code)
+			}
+		}
+	}
+	if tls := t.TLS; tls != nil && (tls.CertFile == "") != (tls.KeyFile == "") {
+		v.errorf(p.at("tls"), "cert_file and key_file must be set together")
+	}
+}
+
+func (v *validator) validateOperation(p cfgPath, t McpToolConfig, op *McpToolOperation) {
+	if !toolNamePattern.MatchString(op.Name) {
+		v.errorf(p.at("name"), "operation name %q must be 1-64 letters, digits, '_' or '-'", op.Name)
+	}
+	if !t.Builtin {
+		if !strings.HasPrefix(op.Path, "/") {
+			v.errorf(p.at("path"), "operation %q: path must start with '/'", op.Name)
+		}
+		switch strings.ToUpper(op.Method) {
+		case "", "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+		default:
+			v.errorf(p.at("method"), "operation %q: unknown HTTP method %q", op.Name, op.Method)
+		}
+	}
+	if op.Parameters == nil {
+		v.errorf(p, "operation %q: parameters schema is required", op.Name)
+		return
+	}
+	pp := p.at("parameters")
+	if op.Parameters["type"] != "object" {
+		v.errorf(pp.at("type"), "operation %q: parameters.type must be 'object'", op.Name)
+	}
+	v.validateSchema(pp, op.Parameters)
+
+	props, _ := op.Parameters["properties"].(map[string]interface{})
+	for _, m := range pathTemplateParam.FindAllStringSubmatch(op.Path, -1) {
+		if _, ok := props[m[1]]; !ok {
+			v.errorf(p.at("path"), "operation %q: path parameter {%s} is not a property", op.Name, m[1])
+		}
+	}
+	for k, q := range op.QueryParams {
+		if _, ok := props[q]; !ok {
+			v.errorf(p.at("query_params", k), "operation %q: query parameter %q is not a property", op.Name, q)
+		}
+	}
+	if op.BodyParam != "" {
+		if _, ok := props[op.BodyParam]; !ok {
+			v.errorf(p.at("body_param"), "operation %q: body_param %q is not a property", op.Name, op.BodyParam)
+		}
+	}
+}
+
+var jsonSchemaTypeNames = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true, "null": true,
+}
+
+// validateSchema checks that s is a well-formed JSON Schema: known keywords
+// must have values of the right kind and nested schemas are checked in
+// turn. Unknown keywords are ignored, as JSON Schema specifies.
+func (v *validator) validateSchema(p cfgPath, s interface{}) {
+	if _, ok := s.(bool); ok {
+		return
+	}
+	m, ok := s.(map[string]interface{})
+	if !ok {
+		v.errorf(p, "schema must be an object")
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		val, kp := m[k], p.at(k)
+		switch k {
+		case "type":
+			v.validateSchemaType(kp, val)
+		case "properties", "patternProperties", "$defs", "definitions":
+			sub, ok := val.(map[string]interface{})
+			if !ok {
+				v.errorf(kp, "must be a map of schemas")
+				continue
+			}
+			for _, name := range sortedKeys(sub) {
+				if k == "patternProperties" {
+					if _, err := regexp.Compile(name); err != nil {
+						v.errorf(kp.at(name), "invalid pattern: %v", err)
+					}
+				}
+				v.validateSchema(kp.at(name), sub[name])
+			}
+		case "items":
+			if list, ok := val.([]interface{}); ok {
+				for i, item := range list {
+					v.validateSchema(kp.at(i), item)
+				}
+				continue
+			}
+			v.validateSchema(kp, val)
+		case "additionalProperties", "additionalItems", "not", "contains", "propertyNames", "if", "then", "else":
+			v.validateSchema(kp, val)
+		case "allOf", "anyOf", "oneOf", "prefixItems":
+			list, ok := val.([]interface{})
+			if !ok || len(list) == 0 {
+				v.errorf(kp, "must be a non-empty list of schemas")
+				continue
+			}
+			for i, item := range list {
+				v.validateSchema(kp.at(i), item)
+			}
+		case "required":
+			list, ok := val.([]interface{})
+			if !ok {
+				v.errorf(kp, "must be a list of property names")
+				continue
+			}
+			props, hasProps := m["properties"].(map[string]interface{})
+			seen := map[string]bool{}
+			for i, r := range list {
+				name, ok := r.(string)
+				switch {
+				case !ok || name == "":
+					v.errorf(kp.at(i), "must be a property name")
+				case seen[name]:
+					v.errorf(kp.at(i), "duplicate required property %q", name)
+				case hasProps && props[name] == nil:
+					v.errorf(kp.at(i), "required property %q is not in properties", name)
+				}
+				seen[name] = true
+			}
+		case "enum":
+			if list, ok := val.([]interface{}); !ok || len(list) == 0 {
+				v.errorf(kp, "must be a non-empty list")
+			}
+		case "minimum", "maximum":
+			if !isNumber(val) {
+				v.errorf(kp, "must be a number")
+			}
+		case "exclusiveMinimum", "exclusiveMaximum":
+			// Numbers since draft 6; booleans in draft 4 and OpenAPI 3.0.
+			if _, isBool := val.(bool); !isBool && !isNumber(val) {
+				v.errorf(kp, "must be a number")
+			}
+		case "multipleOf":
+			if n, ok := toFloat(val); !ok || n <= 0 {
+				v.errorf(kp, "must be a number greater than 0")
+			}
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+			if n, ok := toFloat(val); !ok || n < 0 || n != float64(int64(n)) {
+				v.errorf(kp, "must be a non-negative integer")
+			}
+		case "pattern":
+			str, ok := val.(string)
+			if !ok {
+				v.errorf(kp, "must be a string")
+			} else if _, err := regexp.Compile(str); err != nil {
+				v.errorf(kp, "invalid pattern: %v", err)
+			}
+		case "uniqueItems":
+			if _, ok := val.(bool); !ok {
+				v.errorf(kp, "must be true or false")
+			}
+		case "title", "description", "format", "$ref", "$schema", "$id", "$comment":
+			if _, ok := val.(string); !ok {
+				v.errorf(kp, "must be a string")
+			}
+		}
+	}
+}
+
+func (v *validator) validateSchemaType(p cfgPath, val interface{}) {
+	switch t := val.(type) {
+	case string:
+		if !jsonSchemaTypeNames[t] {
+			v.errorf(p, "unknown type %q", t)
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			v.errorf(p, "must not be empty")
+		}
+		for i, item := range t {
+			if name, ok := item.(string); !ok || !jsonSchemaTypeNames[name] {
+				v.errorf(p.at(i), "unknown type %v", item)
+			}
+		}
+	default:
+		v.errorf(p, "must be a type name or a list of type names")
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := toFloat(v)
+	return ok
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
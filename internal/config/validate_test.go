@@ -0,0 +1,250 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes data to name in a temp dir and returns its path.
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkErrors compares the ConfigErrors in err, one per line, with want;
+// FILE in want stands for path.
+func checkErrors(t *testing.T, path string, err error, want []string) {
+	t.Helper()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ValidationErrors", err)
+	}
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Error()
+		if path != "" {
+			got[i] = strings.ReplaceAll(got[i], path, "FILE")
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "unknown key",
+			yaml: `mcp_tools:
+  - name: weather
+    endpont: https://api.example.com
+    operations:
+      - name: get_weather
+        path: /weather
+        parameters: {type: object}
+`,
+			want: []string{
+				"FILE:2:5: mcp_tools[0]: endpoint is required (set builtin: true for tools implemented in code)",
+				"FILE:3: field endpont not found in type config.McpToolConfig",
+			},
+		},
+		{
+			name: "wrong type",
+			yaml: `mcp_servers:
+  - name: github
+    command: github-mcp
+    timeout_seconds: soon
+`,
+			want: []string{"FILE:4: cannot unmarshal !!str `soon` into int"},
+		},
+		{
+			name: "missing required fields",
+			yaml: `mcp_servers:
+  - transport: stdio
+plugins:
+  - name: echo
+`,
+			want: []string{
+				"FILE:2:5: mcp_servers[0]: name is required",
+				"FILE:2:16: mcp_servers[0].transport: stdio transport needs a command",
+				"FILE:4:5: plugins[0]: command is required",
+			},
+		},
+		{
+			name: "bad schema and references",
+			yaml: `mcp_tools:
+  - name: weather
+    endpoint: https://api.example.com
+    operations:
+      - name: get_weather
+        method: FETCH
+        path: /weather/{city}
+        query_params: [units]
+        parameters:
+          type: object
+          properties:
+            days: {type: int}
+          required: [days, city]
+`,
+			want: []string{
+				"FILE:6:17: mcp_tools[0].operations[0].method: operation \"get_weather\": unknown HTTP method \"FETCH\"",
+				"FILE:7:15: mcp_tools[0].operations[0].path: operation \"get_weather\": path parameter {city} is not a property",
+				"FILE:8:24: mcp_tools[0].operations[0].query_params[0]: operation \"get_weather\": query parameter \"units\" is not a property",
+				"FILE:12:26: mcp_tools[0].operations[0].parameters.properties.days.type: unknown type \"int\"",
+				"FILE:13:28: mcp_tools[0].operations[0].parameters.required[1]: required property \"city\" is not in properties",
+			},
+		},
+		{
+			name: "duplicates",
+			yaml: `plugins:
+  - name: echo
+    command: ./echo
+  - name: echo
+    command: ./echo2
+composite_tools:
+  - name: echo
+    steps:
+      - id: a
+        tool: echo
+`,
+			want: []string{
+				"FILE:4:11: plugins[1].name: duplicate plugin name \"echo\"",
+				"FILE:7:11: composite_tools[0].name: composite tool name \"echo\" already used by a plugin",
+				"FILE:10:15: composite_tools[0].steps[0].tool: step \"a\" calls the composite tool itself",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "mcp_tools.yaml", tt.yaml)
+			_, err := LoadConfig(path)
+			checkErrors(t, path, err, tt.want)
+		})
+	}
+}
+
+func TestValidateMcpConfigWithoutFile(t *testing.T) {
+	// Configs built in code have paths but no positions.
+	err := ValidateMcpConfig(&McpConfig{McpServers: []McpServerConfig{{Name: "x", Transport: "grpc"}}})
+	checkErrors(t, "", err, []string{`mcp_servers[0].transport: unknown transport "grpc" (want stdio, http or sse)`})
+}
+
+func TestLoadTeamConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "unknown key",
+			yaml: `agents:
+  - name: Coder
+    type: assistant
+    toolz: [workspace_*]
+`,
+			want: []string{"FILE:4: field toolz not found in type config.TeamAgentConfig"},
+		},
+		{
+			name: "wrong type",
+			yaml: `agents:
+  - name: Coder
+    type: assistant
+    approve_tools: maybe
+`,
+			want: []string{"FILE:4: cannot unmarshal !!str `maybe` into bool"},
+		},
+		{
+			name: "no agents",
+			yaml: "name: empty\n",
+			want: []string{"FILE:1:1: agents: a team needs at least one agent"},
+		},
+		{
+			name: "invalid agents and routes",
+			yaml: `entry: Lead
+agents:
+  - type: assistant
+  - name: Runner
+    type: runner
+  - name: Coder
+    type: assistant
+    tools: ["[bad"]
+routes:
+  - tool: docker_exec
+    agent: Coder
+  - tool: mcp__*
+    agent: Missing
+`,
+			want: []string{
+				"FILE:1:8: entry: entry agent \"Lead\" is not in the team",
+				"FILE:3:5: agents[0]: name is required",
+				"FILE:5:11: agents[1].type: unknown agent type \"runner\" (want orchestrator, assistant, tool_runner, hitl, planner or researcher)",
+				"FILE:8:13: agents[2].tools[0]: invalid tool pattern \"[bad\"",
+				"FILE:11:12: routes[0].agent: agent \"Coder\" does not run tools (want a tool_runner or hitl agent)",
+				"FILE:13:12: routes[1].agent: agent \"Missing\" is not in the team",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "team.yaml", tt.yaml)
+			_, err := LoadTeamConfig(path)
+			checkErrors(t, path, err, tt.want)
+		})
+	}
+}
+
+func TestLoadAppConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "unknown key",
+			yaml: "limits:\n  max_turn: 3\n",
+			want: []string{"FILE:2: field max_turn not found in type config.LimitsConfig"},
+		},
+		{
+			name: "wrong type",
+			yaml: "sandbox:\n  pool:\n    size: big\n",
+			want: []string{"FILE:3: cannot unmarshal !!str `big` into int"},
+		},
+		{
+			name: "invalid values",
+			yaml: `llm:
+  default: claude
+limits:
+  max_turns: 0
+sandbox:
+  pool:
+    size: -1
+`,
+			want: []string{
+				"FILE:2:12: llm.default: provider \"claude\" is not defined in llm.providers",
+				"FILE:4:14: limits.max_turns: must be greater than 0",
+				"FILE:7:11: sandbox.pool.size: must not be negative",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "gogen.yaml", tt.yaml)
+			cfg, err := LoadAppConfig(path)
+			if err == nil {
+				err = cfg.Validate()
+			}
+			checkErrors(t, path, err, tt.want)
+		})
+	}
+}
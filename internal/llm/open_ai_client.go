@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/tools"
 	"aiupstart.com/go-gen/internal/utils"
 	openai "github.com/sashabaranov/go-openai"
     "aiupstart.com/go-gen/internal/metrics"
@@ -20,7 +22,9 @@ type OpenAIClient struct {
 // OpenAILLMClient definition
 type OpenAILLMClient struct {
     client *openai.Client
-    tools  []openai.Tool // your full tool definitions (schema)
+    model  string
+    mu     sync.RWMutex
+    tools  []openai.Tool // your full tool definitions (schema); replaced by SetTools
 }
 
 func NewOpenAIClient(apiKey, model string, cfg *config.McpConfig) *OpenAIClient {
@@ -32,8 +36,26 @@ func NewOpenAIClient(apiKey, model string, cfg *config.McpConfig) *OpenAIClient
 	}
 }
 
+// DefaultOpenAIModel is used until SetModel is called.
+const DefaultOpenAIModel = "gpt-4.1"
+
 func NewOpenAILLMClient(client *openai.Client, tools []openai.Tool) *OpenAILLMClient {
-    return &OpenAILLMClient{client: client, tools: tools}
+    return &OpenAILLMClient{client: client, model: DefaultOpenAIModel, tools: tools}
+}
+
+// SetTools replaces the function definitions sent with each request, e.g.
+// after tool definitions were reloaded.
+func (c *OpenAILLMClient) SetTools(tools []openai.Tool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.tools = tools
+}
+
+// SetModel selects the chat completion model.
+func (c *OpenAILLMClient) SetModel(model string) {
+    if model != "" {
+        c.model = model
+    }
 }
 
 func BuildOpenAIToolsFromConfig(cfg *config.McpConfig) []openai.Tool {
@@ -67,19 +89,62 @@ func BuildOpenAIToolsFromConfig(cfg *config.McpConfig) []openai.Tool {
     return tools
 }
 
+// AppendRegistryTools adds function definitions for registered tools that are not
+// already advertised (e.g. by mcp_tools.yaml) and expose a JSON schema.
+func AppendRegistryTools(existing []openai.Tool, registry *tools.ToolRegistry) []openai.Tool {
+    seen := map[string]bool{}
+    for _, t := range existing {
+        if t.Function != nil {
+            seen[t.Function.Name] = true
+        }
+    }
+    for _, t := range registry.List() {
+        params := t.Parameters()
+        if seen[t.Name()] || params["type"] == nil {
+            continue
+        }
+        existing = append(existing, openai.Tool{
+            Type: "function",
+            Function: &openai.FunctionDefinition{
+                Name:        t.Name(),
+                Description: t.Description(),
+                Parameters:  params,
+            },
+        })
+    }
+    return existing
+}
+
+// FilterTools keeps the tools whose name is accepted by keep, e.g. an
+// agent's tools.ToolScope.Permits.
+func FilterTools(all []openai.Tool, keep func(name string) bool) []openai.Tool {
+    var out []openai.Tool
+    for _, t := range all {
+        if t.Function != nil && keep(t.Function.Name) {
+            out = append(out, t)
+        }
+    }
+    return out
+}
+
 func (c *OpenAILLMClient) Generate(prompt string) (LLMResponse, error) {
 	// ctx := context.Background()
 	utils.Logger.Debug().Str("module", "llm").Msgf("Generating response with OpenAI model for prompt: %s", prompt)
 	// tools := BuildOpenAIToolsFromConfig(c.cfg)
 	// utils.Logger.Debug().Str("module", "llm").Msgf("Using tools: %v", tools)
 
+	c.mu.RLock()
+	defs := c.tools
+	c.mu.RUnlock()
 	req := openai.ChatCompletionRequest{
-        Model:   "gpt-4.1", // or your configured model
+        Model:   c.model,
         Messages: []openai.ChatCompletionMessage{
             {Role: openai.ChatMessageRoleSystem, Content: prompt},
         },
-        Tools:      c.tools,
-        ToolChoice: "auto",
+        Tools:      defs,
+    }
+    if len(defs) > 0 {
+        req.ToolChoice = "auto" // rejected by the API without tools
     }
     resp, err := c.client.CreateChatCompletion(context.Background(), req)
 
@@ -30,6 +30,13 @@ var (
         },
         []string{"tool", "agent"},
     )
+    ToolDeniedTotal = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "tool_denied_total",
+            Help: "Total number of tool calls denied by the calling agent's tool scope",
+        },
+        []string{"tool", "agent"},
+    )
     ToolLatencySeconds = promauto.NewHistogramVec(
         prometheus.HistogramOpts{
             Name:    "tool_latency_seconds",
@@ -45,9 +52,43 @@ var (
 		},
 		[]string{"type"}, // type: prompt, completion, total
 	)
+    PoolContainers = promauto.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "pool_containers",
+            Help: "Number of pooled containers by image and state",
+        },
+        []string{"image", "state"}, // state: idle, in_use
+    )
+    PoolAcquireTotal = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "pool_acquire_total",
+            Help: "Total container acquisitions from the pool",
+        },
+        []string{"image", "result"}, // result: hit, miss, error
+    )
+    PoolAcquireSeconds = promauto.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "pool_acquire_seconds",
+            Help:    "Time to hand out a container from the pool in seconds",
+            Buckets: prometheus.DefBuckets,
+        },
+        []string{"image"},
+    )
+    PoolReleaseTotal = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "pool_release_total",
+            Help: "Total containers handed back to the pool",
+        },
+        []string{"image", "result"}, // result: recycled, destroyed
+    )
 )
 
-func StartMetricsServer(addr string) {
-    http.Handle("/metrics", promhttp.Handler())
-    go http.ListenAndServe(addr, nil)
+// StartMetricsServer serves /metrics on addr; call Shutdown on the returned
+// server to stop it.
+func StartMetricsServer(addr string) *http.Server {
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", promhttp.Handler())
+    srv := &http.Server{Addr: addr, Handler: mux}
+    go srv.ListenAndServe()
+    return srv
 }
\ No newline at end of file
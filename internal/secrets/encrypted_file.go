@@ -0,0 +1,146 @@
+// internal/secrets/encrypted_file.go
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyEnvVar holds the passphrase for encrypted secrets files.
+const KeyEnvVar = "GOGEN_SECRETS_KEY"
+
+// Files are "gogen-secrets-v2:<salt>:<nonce+ciphertext>" (both base64). The
+// AES-256 key is derived from the passphrase with scrypt and the random
+// per-file salt. v1 files (unsalted SHA-256 key) can still be read; writing
+// them again with `playground secrets seal` upgrades them.
+const (
+	encryptedFileHeader   = "gogen-secrets-v2:"
+	encryptedFileHeaderV1 = "gogen-secrets-v1:"
+	saltSize              = 16
+)
+
+// scrypt cost parameters (the interactive-login values recommended by the
+// scrypt package); variables so tests can lower them.
+var scryptN, scryptR, scryptP = 1 << 15, 8, 1
+
+// NewEncryptedFileProvider loads a file written by EncryptSecrets. The key is a
+// passphrase taken from $GOGEN_SECRETS_KEY.
+func NewEncryptedFileProvider(path string) (*MapProvider, error) {
+	pass := os.Getenv(KeyEnvVar)
+	if pass == "" {
+		return nil, fmt.Errorf("%s must be set to read encrypted secrets file %s", KeyEnvVar, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := DecryptSecrets(data, pass)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", path, err)
+	}
+	return &MapProvider{source: "encrypted:" + path, values: values}, nil
+}
+
+// EncryptSecrets seals a name -> value map with AES-256-GCM under a key
+// derived from passphrase and a fresh random salt.
+func EncryptSecrets(values map[string]string, passphrase string) ([]byte, error) {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, salt)
+	return []byte(encryptedFileHeader + base64.StdEncoding.EncodeToString(salt) + ":" +
+		base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// DecryptSecrets opens data produced by EncryptSecrets.
+func DecryptSecrets(data []byte, passphrase string) (map[string]string, error) {
+	text := strings.TrimSpace(string(data))
+	var salt, sealed []byte
+	var gcm cipher.AEAD
+	var err error
+	switch {
+	case strings.HasPrefix(text, encryptedFileHeader):
+		saltText, sealedText, ok := strings.Cut(strings.TrimPrefix(text, encryptedFileHeader), ":")
+		if !ok {
+			return nil, errors.New("missing salt")
+		}
+		if salt, err = base64.StdEncoding.DecodeString(saltText); err != nil {
+			return nil, err
+		}
+		if len(salt) != saltSize {
+			return nil, errors.New("invalid salt")
+		}
+		if sealed, err = base64.StdEncoding.DecodeString(sealedText); err != nil {
+			return nil, err
+		}
+		gcm, err = newGCM(passphrase, salt)
+	case strings.HasPrefix(text, encryptedFileHeaderV1):
+		if sealed, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(text, encryptedFileHeaderV1)); err != nil {
+			return nil, err
+		}
+		gcm, err = newGCMv1(passphrase)
+	default:
+		return nil, errors.New("not a gogen secrets file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("file is truncated")
+	}
+	// The salt is authenticated as additional data (nil for v1 files).
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], salt)
+	if err != nil {
+		return nil, errors.New("wrong key or corrupted file")
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	return gcmFor(key)
+}
+
+// newGCMv1 derives the unsalted key of v1 files; only used to read them.
+func newGCMv1(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	return gcmFor(key[:])
+}
+
+func gcmFor(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Cheap key derivation; the cost parameters do not change the format.
+	scryptN = 1 << 10
+	os.Exit(m.Run())
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	values := map[string]string{"STRIPE_API_KEY": "sk_test_123", "EMPTY": ""}
+	data, err := EncryptSecrets(values, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(encryptedFileHeader)) || bytes.Contains(data, []byte("sk_test_123")) {
+		t.Fatalf("encrypted file = %q", data)
+	}
+	got, err := DecryptSecrets(data, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["STRIPE_API_KEY"] != "sk_test_123" || got["EMPTY"] != "" {
+		t.Errorf("decrypted %v", got)
+	}
+
+	// Every file gets its own salt, so equal inputs encrypt differently.
+	again, _ := EncryptSecrets(values, "correct horse")
+	salt := func(d []byte) string { return strings.SplitN(string(d), ":", 3)[1] }
+	if salt(data) == salt(again) {
+		t.Error("two files share a salt")
+	}
+}
+
+func TestDecryptWrongKeyAndTampering(t *testing.T) {
+	data, err := EncryptSecrets(map[string]string{"A": "secret-a"}, "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptSecrets(data, "wrong"); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("wrong key: err = %v", err)
+	}
+
+	// Swapping the salt changes the key and fails authentication.
+	parts := strings.SplitN(strings.TrimSpace(string(data)), ":", 3)
+	other := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, saltSize))
+	swapped := []byte(parts[0] + ":" + other + ":" + parts[2])
+	if _, err := DecryptSecrets(swapped, "right"); err == nil {
+		t.Error("file with a replaced salt decrypted")
+	}
+
+	for name, bad := range map[string]string{
+		"not a gogen secrets file": "hello",
+		"missing salt":             encryptedFileHeader + "AAAA",
+		"invalid salt":             encryptedFileHeader + "AAAA:AAAA",
+		"truncated":                encryptedFileHeader + base64.StdEncoding.EncodeToString(make([]byte, saltSize)) + ":AAAA",
+	} {
+		if _, err := DecryptSecrets([]byte(bad), "right"); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestDecryptV1File(t *testing.T) {
+	// v1 files sealed with the unsalted SHA-256 key still load.
+	key := sha256.Sum256([]byte("old pass"))
+	block, _ := aes.NewCipher(key[:])
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nonce, nonce, []byte(`{"A":"legacy-value"}`), nil)
+	data := []byte(encryptedFileHeaderV1 + base64.StdEncoding.EncodeToString(sealed) + "\n")
+
+	got, err := DecryptSecrets(data, "old pass")
+	if err != nil || got["A"] != "legacy-value" {
+		t.Fatalf("v1 file = %v, %v", got, err)
+	}
+}
+
+func TestNewEncryptedFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	data, err := EncryptSecrets(map[string]string{"TOKEN": "tok-123"}, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(KeyEnvVar, "")
+	if _, err := NewEncryptedFileProvider(path); err == nil || !strings.Contains(err.Error(), KeyEnvVar) {
+		t.Errorf("without a key: err = %v", err)
+	}
+	t.Setenv(KeyEnvVar, "pass")
+	p, err := NewEncryptedFileProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := p.Get("TOKEN"); !ok || v != "tok-123" || p.Name() != "encrypted:"+path {
+		t.Errorf("provider %s: TOKEN = %q, %v", p.Name(), v, ok)
+	}
+}
@@ -0,0 +1,162 @@
+// internal/secrets/secrets.go
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// minRedactLen avoids redacting short values like "1" or "true" everywhere.
+const minRedactLen = 4
+
+// Provider is a source of named secret values.
+type Provider interface {
+	Name() string
+	Get(name string) (string, bool)
+}
+
+// EnvProvider reads secrets from the process environment.
+type EnvProvider struct{}
+
+func (EnvProvider) Name() string { return "env" }
+func (EnvProvider) Get(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// MapProvider serves secrets from an in-memory map (env files, decrypted files).
+type MapProvider struct {
+	source string
+	values map[string]string
+}
+
+func (p *MapProvider) Name() string { return p.source }
+func (p *MapProvider) Get(name string) (string, bool) {
+	v, ok := p.values[name]
+	return v, ok
+}
+
+// NewEnvFileProvider loads KEY=VALUE pairs from a dotenv-style file.
+func NewEnvFileProvider(path string) (*MapProvider, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading env file %s: %w", path, err)
+	}
+	return &MapProvider{source: "envfile:" + path, values: values}, nil
+}
+
+// Store resolves secrets from providers in order, hands them out per tool
+// according to an allowlist, and remembers every value it has handed out so
+// it can be redacted from logs and tool output.
+type Store struct {
+	providers []Provider
+	allow     map[string][]string // tool name -> secret names
+	mu        sync.RWMutex
+	known     map[string]string // secret value -> secret name
+	redactor  *strings.Replacer
+}
+
+func NewStore(allow map[string][]string, providers ...Provider) *Store {
+	if allow == nil {
+		allow = map[string][]string{}
+	}
+	return &Store{providers: providers, allow: allow, known: map[string]string{}}
+}
+
+// Get looks a secret up in each provider in order.
+func (s *Store) Get(name string) (string, bool) {
+	for _, p := range s.providers {
+		if v, ok := p.Get(name); ok {
+			s.track(name, v)
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// EnvFor returns the secrets allowlisted for a tool. A missing secret is an
+// error so misconfiguration shows up at startup rather than inside a container.
+func (s *Store) EnvFor(tool string) (map[string]string, error) {
+	env := map[string]string{}
+	var missing []string
+	for _, name := range s.allow[tool] {
+		v, ok := s.Get(name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		env[name] = v
+	}
+	if len(missing) > 0 {
+		return env, fmt.Errorf("secrets for %s not found in any provider: %s", tool, strings.Join(missing, ", "))
+	}
+	return env, nil
+}
+
+// Preload resolves every allowlisted secret so redaction covers them even
+// before a tool asks for them.
+func (s *Store) Preload() {
+	for _, names := range s.allow {
+		for _, n := range names {
+			s.Get(n)
+		}
+	}
+}
+
+func (s *Store) track(name, value string) {
+	if len(value) < minRedactLen {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.known[value]; ok {
+		return
+	}
+	s.known[value] = name
+	// Longest values first so a secret containing another is fully masked.
+	values := make([]string, 0, len(s.known))
+	for v := range s.known {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, "[REDACTED:"+s.known[v]+"]")
+	}
+	s.redactor = strings.NewReplacer(pairs...)
+}
+
+// Redact masks every known secret value in text.
+func (s *Store) Redact(text string) string {
+	if s == nil {
+		return text
+	}
+	s.mu.RLock()
+	r := s.redactor
+	s.mu.RUnlock()
+	if r == nil {
+		return text
+	}
+	return r.Replace(text)
+}
+
+// ParseAllowlist parses "tool=NAME,NAME;tool2=NAME" into a tool -> names map.
+func ParseAllowlist(spec string) map[string][]string {
+	out := map[string][]string{}
+	for _, entry := range strings.Split(spec, ";") {
+		tool, names, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || strings.TrimSpace(tool) == "" {
+			continue
+		}
+		for _, n := range strings.Split(names, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				out[strings.TrimSpace(tool)] = append(out[strings.TrimSpace(tool)], n)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewEnvFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".secrets.env")
+	content := "# comment\nSTRIPE_API_KEY=sk_live_abc\nexport DB_URL=\"postgres://u:p@db/app\"\nQUOTED='a b'\nEMPTY=\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewEnvFileProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"STRIPE_API_KEY": "sk_live_abc",
+		"DB_URL":         "postgres://u:p@db/app",
+		"QUOTED":         "a b",
+		"EMPTY":          "",
+	} {
+		if got, ok := p.Get(name); !ok || got != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := p.Get("MISSING"); ok {
+		t.Error("MISSING found")
+	}
+
+	if _, err := NewEnvFileProvider(filepath.Join(t.TempDir(), "nope.env")); err == nil {
+		t.Error("missing env file accepted")
+	}
+}
+
+func TestStoreEnvForAndRedact(t *testing.T) {
+	first := &MapProvider{source: "first", values: map[string]string{"API_KEY": "key-from-first", "SHORT": "ab"}}
+	second := &MapProvider{source: "second", values: map[string]string{"API_KEY": "key-from-second", "DB_PASS": "hunter2-long"}}
+	s := NewStore(map[string][]string{"docker_exec": {"API_KEY", "DB_PASS"}, "broken": {"NOPE"}}, first, second)
+
+	if got := s.Redact("key-from-first"); got != "key-from-first" {
+		t.Errorf("redacted %q before the secret was handed out", got)
+	}
+	env, err := s.EnvFor("docker_exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env, map[string]string{"API_KEY": "key-from-first", "DB_PASS": "hunter2-long"}) {
+		t.Errorf("EnvFor = %v, want the first provider's value first", env)
+	}
+	if _, err := s.EnvFor("broken"); err == nil || !strings.Contains(err.Error(), "NOPE") {
+		t.Errorf("missing secret: err = %v", err)
+	}
+	if env, _ := s.EnvFor("other"); len(env) != 0 {
+		t.Errorf("tool without an allowlist got %v", env)
+	}
+
+	got := s.Redact("using key-from-first and hunter2-long")
+	if got != "using [REDACTED:API_KEY] and [REDACTED:DB_PASS]" {
+		t.Errorf("Redact = %q", got)
+	}
+	// Values shorter than minRedactLen are never masked.
+	s.Get("SHORT")
+	if got := s.Redact("ab cd"); got != "ab cd" {
+		t.Errorf("short value redacted: %q", got)
+	}
+	var nilStore *Store
+	if nilStore.Redact("x") != "x" {
+		t.Error("nil store changed text")
+	}
+}
+
+func TestRedactLongestFirst(t *testing.T) {
+	p := &MapProvider{values: map[string]string{"INNER": "abcd", "OUTER": "abcd-efgh"}}
+	s := NewStore(map[string][]string{"t": {"INNER", "OUTER"}}, p)
+	s.Preload()
+	if got := s.Redact("abcd-efgh abcd"); got != "[REDACTED:OUTER] [REDACTED:INNER]" {
+		t.Errorf("Redact = %q", got)
+	}
+}
+
+func TestParseAllowlist(t *testing.T) {
+	tests := []struct {
+		spec string
+		want map[string][]string
+	}{
+		{"", map[string][]string{}},
+		{"docker_exec=STRIPE_API_KEY", map[string][]string{"docker_exec": {"STRIPE_API_KEY"}}},
+		{" docker_exec = A, B ;fetch_arxiv=C", map[string][]string{"docker_exec": {"A", "B"}, "fetch_arxiv": {"C"}}},
+		{"t=A;t=B", map[string][]string{"t": {"A", "B"}}},
+		{"novalue;=A;t=,;u=X", map[string][]string{"u": {"X"}}},
+	}
+	for _, tt := range tests {
+		if got := ParseAllowlist(tt.spec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAllowlist(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
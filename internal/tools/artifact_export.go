@@ -0,0 +1,277 @@
+// internal/tools/artifact_export.go
+package tools
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// DefaultExportExcludes are skipped unless the caller sets Exclude.
+var DefaultExportExcludes = []string{"node_modules", ".angular", "__pycache__"}
+
+// ExportOptions controls how a session workspace is exported to the host.
+type ExportOptions struct {
+	Dest    string   // output directory on the host
+	Include []string // glob patterns; empty means everything
+	Exclude []string // glob patterns; empty means DefaultExportExcludes
+	Archive bool     // write a single .tar.gz instead of a directory copy
+}
+
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type ExportManifest struct {
+	Session   string          `json:"session"`
+	Source    string          `json:"source"`
+	Output    string          `json:"output"`
+	CreatedAt time.Time       `json:"created_at"`
+	TotalSize int64           `json:"total_size"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// ExportArtifacts copies the current session workspace into opts.Dest.
+// Must be called before CleanupContainer, which deletes the workspace.
+func (t *DockerExecTool) ExportArtifacts(opts ExportOptions) (*ExportManifest, error) {
+	t.mu.Lock()
+	workspace := t.workspace
+	injected := append([]string(nil), t.injected...)
+	t.mu.Unlock()
+	if workspace == "" {
+		return nil, fmt.Errorf("no workspace to export: container was never started")
+	}
+	// Mirror configs point at local infrastructure and are not part of the project.
+	opts.Exclude = append(append([]string(nil), opts.excludes()...), injected...)
+	return ExportWorkspace(workspace, opts)
+}
+
+// ExportWorkspace copies (or tars) the files under workspace that pass the
+// include/exclude filters and writes a manifest.json with sizes and hashes.
+func ExportWorkspace(workspace string, opts ExportOptions) (*ExportManifest, error) {
+	if opts.Dest == "" {
+		return nil, fmt.Errorf("export destination is required")
+	}
+	opts.Exclude = opts.excludes()
+	session := strings.TrimPrefix(filepath.Base(workspace), "dockerexec-")
+	if err := os.MkdirAll(opts.Dest, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating export directory %s: %w", opts.Dest, err)
+	}
+
+	files, err := collectExportFiles(workspace, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ExportManifest{
+		Session:   session,
+		Source:    workspace,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var manifestPath string
+	if opts.Archive {
+		manifest.Output = filepath.Join(opts.Dest, session+".tar.gz")
+		manifestPath = filepath.Join(opts.Dest, session+".manifest.json")
+		err = writeExportArchive(workspace, files, manifest)
+	} else {
+		manifest.Output = filepath.Join(opts.Dest, session)
+		manifestPath = filepath.Join(manifest.Output, "manifest.json")
+		err = writeExportDir(workspace, files, manifest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	utils.Logger.Info().
+		Str("session", session).
+		Str("output", manifest.Output).
+		Int("files", len(manifest.Files)).
+		Int64("bytes", manifest.TotalSize).
+		Msg("Exported workspace artifacts")
+	return manifest, nil
+}
+
+func (o ExportOptions) excludes() []string {
+	if len(o.Exclude) == 0 {
+		return DefaultExportExcludes
+	}
+	return o.Exclude
+}
+
+// collectExportFiles returns the slash-separated relative paths to export, sorted.
+func collectExportFiles(workspace string, opts ExportOptions) ([]string, error) {
+	var files []string
+	err := filepath.Walk(workspace, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(workspace, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAnyGlob(opts.Exclude, rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Only regular files are exported; symlinks could point outside the workspace.
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if len(opts.Include) > 0 && !matchAnyGlob(opts.Include, rel) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking workspace %s: %w", workspace, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func writeExportDir(workspace string, files []string, manifest *ExportManifest) error {
+	for _, rel := range files {
+		dest := filepath.Join(manifest.Output, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return fmt.Errorf("error creating directories for %s: %w", dest, err)
+		}
+		out, err := os.Create(dest)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", dest, err)
+		}
+		entry, err := copyAndHash(out, filepath.Join(workspace, filepath.FromSlash(rel)), rel)
+		out.Close()
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, entry)
+		manifest.TotalSize += entry.Size
+	}
+	return nil
+}
+
+func writeExportArchive(workspace string, files []string, manifest *ExportManifest) error {
+	f, err := os.Create(manifest.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create archive %s: %w", manifest.Output, err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for _, rel := range files {
+		src := filepath.Join(workspace, filepath.FromSlash(rel))
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %w", rel, err)
+		}
+		entry, err := copyAndHash(tw, src, rel)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, entry)
+		manifest.TotalSize += entry.Size
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func copyAndHash(w io.Writer, src, rel string) (ManifestEntry, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return ManifestEntry{}, fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), in)
+	if err != nil {
+		return ManifestEntry{}, fmt.Errorf("failed to copy %s: %w", rel, err)
+	}
+	return ManifestEntry{Path: rel, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func matchAnyGlob(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash path against a glob. Patterns without a slash
+// (e.g. "node_modules", "*.log") match any single path element; patterns with
+// a slash match from the workspace root and may use "**" for any depth.
+func matchGlob(pattern, rel string) bool {
+	pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		for _, seg := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(pattern, seg); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	// A pattern that matches a directory also matches everything beneath it.
+	return true
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		// Without a slash a pattern matches any path element.
+		{"node_modules", "node_modules", true},
+		{"node_modules", "web/node_modules/x/index.js", true},
+		{"*.log", "logs/build.log", true},
+		{"*.log", "build.log.txt", false},
+		// With a slash it matches from the root, "**" spanning any depth.
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/pkg/main.go", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"src/**/*.go", "lib/src/a.go", false},
+		{"**/dist", "dist", true},
+		{"**/dist", "web/app/dist", true},
+		{"**/dist", "web/app/dist/main.js", true}, // beneath a matched directory
+		{"**/*.py", "a/b/c.txt", false},
+		{"/reports/", "reports/r.md", true},
+		{"", "anything", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+// exportWorkspace returns a workspace with a project, dependency
+// directories and a symlink.
+func exportWorkspace(t *testing.T) string {
+	t.Helper()
+	ws := t.TempDir()
+	for name, content := range map[string]string{
+		"main.py":                     "print('hi')\n",
+		"src/app/util.py":             "x = 1\n",
+		"reports/summary.md":          "# Summary\n",
+		"node_modules/left-pad/i.js":  "module.exports = 1\n",
+		"web/node_modules/a/index.js": "1\n",
+		"src/__pycache__/util.pyc":    "\x00",
+		".angular/cache/x":            "c",
+	} {
+		p := filepath.Join(ws, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(ws, "passwd")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	return ws
+}
+
+func manifestPaths(m *ExportManifest) []string {
+	var paths []string
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExportWorkspaceDir(t *testing.T) {
+	ws := exportWorkspace(t)
+	dest := t.TempDir()
+	m, err := ExportWorkspace(ws, ExportOptions{Dest: dest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Default excludes drop dependencies and caches; symlinks are skipped.
+	want := []string{"main.py", "reports/summary.md", "src/app/util.py"}
+	if got := manifestPaths(m); !reflect.DeepEqual(got, want) {
+		t.Fatalf("exported %v, want %v", got, want)
+	}
+	if m.Output != filepath.Join(dest, filepath.Base(ws)) {
+		t.Errorf("output = %s", m.Output)
+	}
+	var total int64
+	for _, f := range m.Files {
+		data, err := os.ReadFile(filepath.Join(m.Output, filepath.FromSlash(f.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.SHA256 != sha256Hex(string(data)) || f.Size != int64(len(data)) {
+			t.Errorf("%s: manifest %s/%d, copy %s/%d", f.Path, f.SHA256, f.Size, sha256Hex(string(data)), len(data))
+		}
+		total += f.Size
+	}
+	if f := m.Files[0]; f.SHA256 != sha256Hex("print('hi')\n") {
+		t.Errorf("main.py sha256 = %s", f.SHA256)
+	}
+	if m.TotalSize != total {
+		t.Errorf("total size = %d, want %d", m.TotalSize, total)
+	}
+
+	// manifest.json is written next to the files.
+	data, err := os.ReadFile(filepath.Join(m.Output, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved ExportManifest
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved.Files, m.Files) {
+		t.Errorf("manifest.json files = %+v, want %+v", saved.Files, m.Files)
+	}
+}
+
+func TestExportWorkspaceFilters(t *testing.T) {
+	ws := exportWorkspace(t)
+	tests := []struct {
+		name string
+		opts ExportOptions
+		want []string
+	}{
+		{"include", ExportOptions{Include: []string{"src/**/*.py"}}, []string{"src/app/util.py"}},
+		{"exclude replaces the defaults", ExportOptions{Exclude: []string{"reports", "*.py"}}, []string{
+			".angular/cache/x", "node_modules/left-pad/i.js", "src/__pycache__/util.pyc", "web/node_modules/a/index.js",
+		}},
+		{"include and exclude", ExportOptions{Include: []string{"**/*.py"}, Exclude: []string{"app"}}, []string{"main.py"}},
+	}
+	for _, tt := range tests {
+		tt.opts.Dest = t.TempDir()
+		m, err := ExportWorkspace(ws, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := manifestPaths(m); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: exported %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportWorkspaceArchive(t *testing.T) {
+	ws := exportWorkspace(t)
+	dest := t.TempDir()
+	m, err := ExportWorkspace(ws, ExportOptions{Dest: dest, Archive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := filepath.Base(ws)
+	if m.Output != filepath.Join(dest, session+".tar.gz") {
+		t.Errorf("output = %s", m.Output)
+	}
+	if _, err := os.Stat(filepath.Join(dest, session+".manifest.json")); err != nil {
+		t.Error(err)
+	}
+
+	f, err := os.Open(m.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	var got []ManifestEntry
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, ManifestEntry{Path: h.Name, Size: int64(len(data)), SHA256: sha256Hex(string(data))})
+	}
+	if !reflect.DeepEqual(got, m.Files) {
+		t.Errorf("archive %+v, manifest %+v", got, m.Files)
+	}
+}
+
+func TestExportArtifactsSkipsInjectedConfigs(t *testing.T) {
+	exec := workspaceExec(t, map[string]string{"app.py": "1", ".npmrc": "registry=http://mirror"})
+	exec.injected = []string{".npmrc"}
+	m, err := exec.ExportArtifacts(ExportOptions{Dest: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifestPaths(m); !reflect.DeepEqual(got, []string{"app.py"}) {
+		t.Errorf("exported %v, want the mirror config left out", got)
+	}
+
+	if _, err := NewDockerExecTool("test", "").ExportArtifacts(ExportOptions{Dest: t.TempDir()}); err == nil {
+		t.Error("export without a workspace succeeded")
+	}
+}
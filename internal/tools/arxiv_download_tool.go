@@ -0,0 +1,303 @@
+// internal/tools/arxiv_download_tool.go
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"aiupstart.com/go-gen/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	arxivWorkspaceDir    = "papers"
+	maxArxivDownload     = 50 << 20
+	defaultArxivMaxChars = 20000
+)
+
+var (
+	arxivNewID = regexp.MustCompile(`^\d{4}\.\d{4,5}(v\d+)?$`)
+	arxivOldID = regexp.MustCompile(`^[a-z-]+(\.[A-Z]{2})?/\d{7}(v\d+)?$`)
+	arxivIDURL = regexp.MustCompile(`arxiv\.org/(?:abs|pdf|e-print)/(.+?)(?:\.pdf)?/?$`)
+)
+
+// ArxivDownloadTool downloads the PDF or LaTeX source of an arXiv paper and
+// extracts its plain text. Files go to papers/ in the docker_exec session
+// workspace when there is one, and to the download directory otherwise.
+// PDF text comes from pdftotext when it is installed, else from a built-in
+// extractor that reads the text operators of the PDF's content streams.
+type ArxivDownloadTool struct {
+	exec *DockerExecTool // may be nil
+	dir  string
+}
+
+func NewArxivDownloadTool(exec *DockerExecTool, dir string) *ArxivDownloadTool {
+	if dir == "" {
+		dir = "downloads"
+	}
+	return &ArxivDownloadTool{exec: exec, dir: dir}
+}
+
+func (t *ArxivDownloadTool) Name() string { return "arxiv_download" }
+func (t *ArxivDownloadTool) Description() string {
+	return "Download an arXiv paper (PDF or LaTeX source) by id into the workspace (papers/) and return its extracted plain text."
+}
+func (t *ArxivDownloadTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":        map[string]interface{}{"type": "string", "description": "arXiv id (e.g. 2210.03629 or hep-th/9901001) or abs/pdf URL"},
+			"format":    map[string]interface{}{"type": "string", "enum": []string{"pdf", "source"}, "description": "pdf (default) or source (LaTeX)"},
+			"max_chars": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Characters of extracted text to return (default %d); the full text is saved next to the download", defaultArxivMaxChars)},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *ArxivDownloadTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	res, err := t.download(ctx, call.Args)
+	if err != nil {
+		metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+		return ToolResult{Error: err}
+	}
+	return ToolResult{Output: res}
+}
+
+func (t *ArxivDownloadTool) download(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	raw, _ := args["id"].(string)
+	id, err := parseArxivID(raw)
+	if err != nil {
+		return nil, err
+	}
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = "pdf"
+	}
+	maxChars := intArg(args, "max_chars", defaultArxivMaxChars)
+	if maxChars <= 0 {
+		maxChars = defaultArxivMaxChars
+	}
+	w, prefix, shown, err := t.target()
+	if err != nil {
+		return nil, err
+	}
+	base := strings.ReplaceAll(id, "/", "_")
+
+	var saved []string
+	var text string
+	switch format {
+	case "pdf":
+		data, err := arxivGet(ctx, arxivBaseURL+"/pdf/"+id, 2*time.Minute, maxArxivDownload)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(data, []byte("%PDF")) {
+			return nil, fmt.Errorf("arXiv did not return a PDF for %s", id)
+		}
+		name := base + ".pdf"
+		dest, err := writeResolved(w, path.Join(prefix, name), data)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, name)
+		text = pdfText(ctx, dest, data)
+	case "source":
+		data, err := arxivGet(ctx, arxivBaseURL+"/e-print/"+id, 2*time.Minute, maxArxivDownload)
+		if err != nil {
+			return nil, err
+		}
+		files, err := unpackArxivSource(data)
+		if err != nil {
+			return nil, fmt.Errorf("source of %s: %w", id, err)
+		}
+		var tex []string
+		for _, name := range sortedFileNames(files) {
+			rel := path.Join(base, name)
+			if _, err := writeResolved(w, path.Join(prefix, rel), files[name]); err != nil {
+				return nil, fmt.Errorf("%s: %w", rel, err)
+			}
+			saved = append(saved, rel)
+			if strings.HasSuffix(name, ".tex") {
+				tex = append(tex, latexText(string(files[name])))
+			}
+		}
+		text = strings.Join(tex, "\n\n")
+	default:
+		return nil, fmt.Errorf("invalid format %q (want pdf or source)", format)
+	}
+
+	text = strings.TrimSpace(text)
+	textName := base + ".txt"
+	if format == "source" {
+		textName = base + ".source.txt"
+	}
+	if _, err := writeResolved(w, path.Join(prefix, textName), []byte(text)); err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{
+		"id":        id,
+		"format":    format,
+		"files":     prefixAll(shown, saved),
+		"text_file": shown + textName,
+		"chars":     len(text),
+	}
+	if text == "" {
+		out["text"] = ""
+		out["note"] = "no text could be extracted"
+		return out, nil
+	}
+	if len(text) > maxChars {
+		cut := maxChars
+		for cut > 0 && !utf8Start(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+		out["truncated"] = true
+	}
+	out["text"] = text
+	return out, nil
+}
+
+// target returns the writer, the path prefix inside it and the prefix of
+// the paths reported to the model.
+func (t *ArxivDownloadTool) target() (*WorkspaceWriter, string, string, error) {
+	if t.exec != nil {
+		if ws := t.exec.Workspace(); ws != "" {
+			return NewWorkspaceWriter(ws), arxivWorkspaceDir, "/workspace/" + arxivWorkspaceDir + "/", nil
+		}
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, "", "", err
+	}
+	return NewWorkspaceWriter(t.dir), "", filepath.ToSlash(t.dir) + "/", nil
+}
+
+func writeResolved(w *WorkspaceWriter, name string, data []byte) (string, error) {
+	dest, err := w.Resolve(name)
+	if err != nil {
+		return "", err
+	}
+	return dest, w.WriteFile(name, data)
+}
+
+func prefixAll(prefix string, names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = prefix + n
+	}
+	return out
+}
+
+func sortedFileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func utf8Start(b byte) bool { return b&0xC0 != 0x80 }
+
+// parseArxivID accepts bare ids and abs/pdf/e-print URLs.
+func parseArxivID(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("missing argument: id")
+	}
+	id := arxivIDFromURL(s)
+	id = strings.TrimPrefix(strings.TrimPrefix(id, "arXiv:"), "arxiv:")
+	if !arxivNewID.MatchString(id) && !arxivOldID.MatchString(id) {
+		return "", fmt.Errorf("invalid arXiv id %q (want e.g. 2210.03629 or hep-th/9901001)", s)
+	}
+	return id, nil
+}
+
+// arxivIDFromURL returns the id of an arXiv URL, or s unchanged.
+func arxivIDFromURL(s string) string {
+	if m := arxivIDURL.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// unpackArxivSource returns the files of an e-print download: a gzipped tar
+// of the source tree, or a single gzipped .tex file.
+func unpackArxivSource(data []byte) (map[string][]byte, error) {
+	if bytes.HasPrefix(data, []byte("%PDF")) {
+		return nil, errors.New("no LaTeX source available (the paper was submitted as PDF); use format pdf")
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		unzipped, err := io.ReadAll(io.LimitReader(zr, maxArxivDownload+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(unzipped) > maxArxivDownload {
+			return nil, fmt.Errorf("source larger than %d bytes", maxArxivDownload)
+		}
+		data = unzipped
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(files) == 0 { // not a tar: a single source file
+				return map[string][]byte{"main.tex": data}, nil
+			}
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue // directories are created as needed; links are skipped
+		}
+		name, err := NormalizeWorkspacePath(h.Name)
+		if err != nil {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = b
+	}
+	if len(files) == 0 {
+		return map[string][]byte{"main.tex": data}, nil
+	}
+	return files, nil
+}
+
+// pdfText extracts the text of a PDF saved at file.
+func pdfText(ctx context.Context, file string, data []byte) string {
+	if bin, err := exec.LookPath("pdftotext"); err == nil {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, bin, "-layout", "-enc", "UTF-8", file, "-").Output(); err == nil && len(bytes.TrimSpace(out)) > 0 {
+			return string(out)
+		}
+	}
+	return extractPDFText(data)
+}
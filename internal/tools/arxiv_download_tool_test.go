@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gzippedTar packs files, in order, into a .tar.gz like arXiv's e-prints.
+func gzippedTar(t *testing.T, files [][2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f[0], Mode: 0o644, Size: int64(len(f[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f[1]))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	got := extractPDFText(arxivFixture(t, "paper.pdf"))
+	if want := "Attention Is All You Need\nThe Transformer dispenses with recurrence."; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestArxivDownloadPDF(t *testing.T) {
+	pdf := arxivFixture(t, "paper.pdf")
+	requests := arxivServer(t, map[string][]byte{"/pdf/1706.03762": pdf})
+	dir := t.TempDir()
+	tool := NewArxivDownloadTool(nil, dir)
+
+	res := tool.Call(context.Background(), ToolCall{Args: map[string]interface{}{"id": "https://arxiv.org/abs/1706.03762", "max_chars": float64(9)}})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if reqs := requests(); len(reqs) != 1 || reqs[0].Path != "/pdf/1706.03762" {
+		t.Errorf("requests = %v", reqs)
+	}
+	out := res.Output.(map[string]interface{})
+	shown := filepath.ToSlash(dir) + "/"
+	if !reflect.DeepEqual(out["files"], []string{shown + "1706.03762.pdf"}) || out["text_file"] != shown+"1706.03762.txt" {
+		t.Errorf("files %v, text_file %v", out["files"], out["text_file"])
+	}
+	if out["text"] != "Attention" || out["truncated"] != true {
+		t.Errorf("text %q, truncated %v; want the first 9 characters", out["text"], out["truncated"])
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "1706.03762.pdf")); !bytes.Equal(data, pdf) {
+		t.Error("saved PDF differs from the download")
+	}
+	// The full text is saved next to the PDF.
+	if text, _ := os.ReadFile(filepath.Join(dir, "1706.03762.txt")); !strings.HasPrefix(string(text), "Attention Is All You Need") || len(text) != out["chars"] {
+		t.Errorf("saved text = %q (chars %v)", text, out["chars"])
+	}
+}
+
+func TestArxivDownloadIntoWorkspace(t *testing.T) {
+	arxivServer(t, map[string][]byte{"/pdf/hep-th/9901001": arxivFixture(t, "paper.pdf")})
+	exec := workspaceExec(t, nil)
+	tool := NewArxivDownloadTool(exec, t.TempDir())
+
+	res := tool.Call(context.Background(), ToolCall{Args: map[string]interface{}{"id": "arXiv:hep-th/9901001"}})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	out := res.Output.(map[string]interface{})
+	if !reflect.DeepEqual(out["files"], []string{"/workspace/papers/hep-th_9901001.pdf"}) {
+		t.Errorf("files = %v", out["files"])
+	}
+	if _, err := os.Stat(filepath.Join(exec.Workspace(), "papers", "hep-th_9901001.txt")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestArxivDownloadSource(t *testing.T) {
+	tex := `\documentclass{article}
+\usepackage{amsmath}
+\begin{document}
+\section{Introduction}
+Recurrent models~\cite{lstm} are slow. % TODO: cite more
+\end{document}
+`
+	arxivServer(t, map[string][]byte{"/e-print/1706.03762": gzippedTar(t, [][2]string{
+		{"main.tex", tex},
+		{"figs/plot.png", "png"},
+		{"../escape.tex", "x"},
+	})})
+	dir := t.TempDir()
+	tool := NewArxivDownloadTool(nil, dir)
+
+	res := tool.Call(context.Background(), ToolCall{Args: map[string]interface{}{"id": "1706.03762", "format": "source"}})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	out := res.Output.(map[string]interface{})
+	shown := filepath.ToSlash(dir) + "/"
+	wantFiles := []string{shown + "1706.03762/figs/plot.png", shown + "1706.03762/main.tex"}
+	if !reflect.DeepEqual(out["files"], wantFiles) || out["text_file"] != shown+"1706.03762.source.txt" {
+		t.Errorf("files %v, text_file %v", out["files"], out["text_file"])
+	}
+	if want := "Introduction\n\nRecurrent models are slow."; out["text"] != want {
+		t.Errorf("text = %q, want %q", out["text"], want)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.tex")); !os.IsNotExist(err) {
+		t.Error("a tar entry escaped the download directory")
+	}
+}
+
+func TestArxivDownloadErrors(t *testing.T) {
+	arxivServer(t, map[string][]byte{
+		"/pdf/2101.00001":     []byte("<html>captcha</html>"),
+		"/e-print/2101.00002": arxivFixture(t, "paper.pdf"),
+	})
+	tool := NewArxivDownloadTool(nil, t.TempDir())
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{}, "missing argument: id"},
+		{map[string]interface{}{"id": "not an id"}, `invalid arXiv id "not an id"`},
+		{map[string]interface{}{"id": "2101.00001", "format": "html"}, `invalid format "html"`},
+		{map[string]interface{}{"id": "2101.00001"}, "arXiv did not return a PDF for 2101.00001"},
+		{map[string]interface{}{"id": "2101.00002", "format": "source"}, "no LaTeX source available"},
+		{map[string]interface{}{"id": "2101.00003"}, "arXiv returned 404 Not Found"},
+	}
+	for _, tt := range tests {
+		res := tool.Call(context.Background(), ToolCall{Args: tt.args})
+		if res.Error == nil || !strings.Contains(res.Error.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.args, res.Error, tt.want)
+		}
+	}
+}
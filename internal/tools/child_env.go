@@ -0,0 +1,43 @@
+// internal/tools/child_env.go
+package tools
+
+import (
+	"os"
+	"sort"
+)
+
+// childEnvPassthrough lists the variables child processes (stdio MCP servers,
+// plugins) inherit from us. Anything else, secrets and provider API keys
+// included, reaches a child only through its configured env.
+var childEnvPassthrough = []string{"PATH", "HOME", "TMPDIR"}
+
+// childEnv returns the minimal inherited environment plus env.
+func childEnv(env map[string]string) []string {
+	var out []string
+	for _, name := range childEnvPassthrough {
+		if v, ok := os.LookupEnv(name); ok {
+			out = append(out, name+"="+v)
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, k+"="+env[k])
+	}
+	return out
+}
+
+// expandSecrets resolves ${NAME} references in s through lookup, the secret
+// store in production; nil falls back to the process environment.
+func expandSecrets(s string, lookup SecretLookup) string {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return os.Expand(s, func(name string) string {
+		v, _ := lookup(name)
+		return v
+	})
+}
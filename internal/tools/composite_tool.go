@@ -0,0 +1,286 @@
+// internal/tools/composite_tool.go
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/metrics"
+	"aiupstart.com/go-gen/internal/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxCompositeDepth bounds composite tools calling composite tools.
+const maxCompositeDepth = 8
+
+// rawTemplate matches a template that is a single field reference, e.g.
+// "{{ .steps.search.output }}"; its value is passed as is instead of being
+// rendered as text, so lists and objects reach the next tool intact.
+var rawTemplate = regexp.MustCompile(`^\s*\{\{-?\s*\.([A-Za-z_][A-Za-z0-9_.]*)\s*-?\}\}\s*$`)
+
+// CompositeTool runs the steps of a composite_tools entry (see
+// config.CompositeToolConfig) through the registry. Steps are called with
+// the composite as caller, so agent scopes apply to the composite tool, not
+// to its steps; the call trace records the chain.
+type CompositeTool struct {
+	cfg      config.CompositeToolConfig
+	registry *ToolRegistry
+	deps     map[string][]string
+}
+
+func NewCompositeTool(cfg config.CompositeToolConfig, registry *ToolRegistry) *CompositeTool {
+	deps := map[string][]string{}
+	for _, s := range cfg.Steps {
+		deps[s.ID] = s.Dependencies()
+	}
+	return &CompositeTool{cfg: cfg, registry: registry, deps: deps}
+}
+
+func (t *CompositeTool) Name() string        { return t.cfg.Name }
+func (t *CompositeTool) Description() string { return t.cfg.Description }
+
+func (t *CompositeTool) Parameters() map[string]interface{} {
+	if t.cfg.Parameters == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.cfg.Parameters
+}
+
+// Metadata carries the routing settings of the tool's composite_tools entry.
+func (t *CompositeTool) Metadata() ToolMetadata {
+	return ToolMetadata{Executor: t.cfg.Executor, RequiresApproval: t.cfg.RequiresApproval}
+}
+
+// stepResult is what templates see as .steps.<id>.
+type stepResult struct {
+	Output interface{}
+	Error  string
+}
+
+func (r *stepResult) data() map[string]interface{} {
+	return map[string]interface{}{"output": r.Output, "error": r.Error}
+}
+
+func (t *CompositeTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	res := t.run(ctx, call)
+	if res.Error != nil {
+		metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	}
+	return res
+}
+
+func (t *CompositeTool) run(ctx context.Context, call ToolCall) ToolResult {
+	// CallTool has already appended our name when the trace is in use.
+	trace := append([]string{}, call.Trace...)
+	if n := len(trace); n == 0 || trace[n-1] != t.Name() {
+		trace = append(trace, t.Name())
+	}
+	for _, name := range trace[:len(trace)-1] {
+		if name == t.Name() {
+			return ToolResult{Error: fmt.Errorf("composite tool %s calls itself (trace %s)", t.Name(), strings.Join(trace, " > "))}
+		}
+	}
+	if len(trace) > maxCompositeDepth {
+		return ToolResult{Error: fmt.Errorf("composite tools nested more than %d deep (trace %s)", maxCompositeDepth, strings.Join(trace, " > "))}
+	}
+
+	args := call.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	results := map[string]*stepResult{}
+	var mu sync.Mutex // guards results while a wave of steps runs
+	data := func() map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		steps := map[string]interface{}{}
+		for id, r := range results {
+			steps[id] = r.data()
+		}
+		return map[string]interface{}{"args": args, "steps": steps}
+	}
+
+	// Run the steps in waves: every step whose dependencies are done.
+	pending := append([]config.CompositeStepConfig{}, t.cfg.Steps...)
+	for len(pending) > 0 {
+		var ready, waiting []config.CompositeStepConfig
+		for _, s := range pending {
+			if t.depsDone(s.ID, results) {
+				ready = append(ready, s)
+			} else {
+				waiting = append(waiting, s)
+			}
+		}
+		if len(ready) == 0 { // guarded by config validation
+			return ToolResult{Error: fmt.Errorf("composite tool %s: steps wait on each other", t.Name())}
+		}
+		snapshot := data()
+		failures := make([]error, len(ready))
+		var wg sync.WaitGroup
+		for i, s := range ready {
+			wg.Add(1)
+			go func(i int, s config.CompositeStepConfig) {
+				defer wg.Done()
+				out, err := t.runStep(ctx, s, snapshot, trace)
+				r := &stepResult{Output: out}
+				if err != nil {
+					r.Error = err.Error()
+					if s.OnError != config.OnErrorContinue {
+						failures[i] = fmt.Errorf("step %s (%s): %w", s.ID, s.Tool, err)
+					} else {
+						utils.Logger.Warn().Str("tool", t.Name()).Str("step", s.ID).Err(err).Msg("Composite step failed; continuing")
+					}
+				}
+				mu.Lock()
+				results[s.ID] = r
+				mu.Unlock()
+			}(i, s)
+		}
+		wg.Wait()
+		for _, err := range failures {
+			if err != nil {
+				return ToolResult{
+					Error:       fmt.Errorf("composite tool %s: %w", t.Name(), err),
+					ErrorDetail: &ExecErrorDetail{Phase: "composite", Command: t.Name(), Output: t.progress(results), ErrMsg: err.Error()},
+				}
+			}
+		}
+		pending = waiting
+	}
+
+	if t.cfg.Output == "" {
+		last := t.cfg.Steps[len(t.cfg.Steps)-1].ID
+		return ToolResult{Output: results[last].Output}
+	}
+	out, err := renderTemplate(t.cfg.Output, data())
+	if err != nil {
+		return ToolResult{Error: fmt.Errorf("composite tool %s: output: %w", t.Name(), err)}
+	}
+	return ToolResult{Output: out}
+}
+
+func (t *CompositeTool) depsDone(id string, results map[string]*stepResult) bool {
+	for _, dep := range t.deps[id] {
+		if _, ok := results[dep]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// runStep renders the step's arguments and calls its tool, retrying failed
+// calls s.Retries times.
+func (t *CompositeTool) runStep(ctx context.Context, s config.CompositeStepConfig, data map[string]interface{}, trace []string) (interface{}, error) {
+	rendered, err := renderValue(s.Args, data)
+	if err != nil {
+		return nil, fmt.Errorf("args: %w", err)
+	}
+	args, _ := rendered.(map[string]interface{})
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	if tool, ok := t.registry.Get(s.Tool); ok && MetadataOf(tool).RequiresApproval && !t.cfg.RequiresApproval {
+		return nil, fmt.Errorf("tool %s requires approval; set requires_approval on composite tool %s", s.Tool, t.Name())
+	}
+	var res ToolResult
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			utils.Logger.Info().Str("tool", t.Name()).Str("step", s.ID).Int("attempt", attempt+1).Msg("Retrying composite step")
+		}
+		res = t.registry.CallTool(ctx, ToolCall{Name: s.Tool, Args: args, Caller: t.Name(), Trace: append([]string{}, trace...)})
+		if res.Error == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return res.Output, res.Error
+}
+
+// progress summarizes the finished steps for error details.
+func (t *CompositeTool) progress(results map[string]*stepResult) string {
+	var lines []string
+	for _, s := range t.cfg.Steps {
+		r, ok := results[s.ID]
+		switch {
+		case !ok:
+			lines = append(lines, s.ID+": not run")
+		case r.Error != "":
+			lines = append(lines, s.ID+": failed: "+r.Error)
+		default:
+			lines = append(lines, s.ID+": ok")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderValue renders every string of v as a template over data.
+func renderValue(v interface{}, data map[string]interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return renderTemplate(x, data)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			r, err := renderValue(e, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			r, err := renderValue(e, data)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// renderTemplate returns the referenced value for single-field templates
+// and the rendered text otherwise. Missing values render as "".
+func renderTemplate(s string, data map[string]interface{}) (interface{}, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	if m := rawTemplate.FindStringSubmatch(s); m != nil {
+		return lookupField(data, strings.Split(m[1], ".")), nil
+	}
+	tmpl, err := template.New("").Funcs(config.CompositeTemplateFuncs).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(b.String(), "<no value>", ""), nil
+}
+
+func lookupField(v interface{}, path []string) interface{} {
+	for _, key := range path {
+		switch m := v.(type) {
+		case map[string]interface{}:
+			v = m[key]
+		case map[string]string:
+			v = m[key]
+		default:
+			return nil
+		}
+	}
+	return v
+}
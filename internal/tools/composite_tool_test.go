@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"aiupstart.com/go-gen/internal/config"
+)
+
+// funcTool calls fn and records the calls it gets.
+type funcTool struct {
+	name  string
+	fn    func(call ToolCall) ToolResult
+	mu    sync.Mutex
+	calls []ToolCall
+}
+
+func (t *funcTool) Name() string        { return t.name }
+func (t *funcTool) Description() string { return "stub" }
+func (t *funcTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *funcTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	t.mu.Lock()
+	t.calls = append(t.calls, call)
+	t.mu.Unlock()
+	return t.fn(call)
+}
+
+func (t *funcTool) called() []ToolCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]ToolCall(nil), t.calls...)
+}
+
+func compositeRegistry(tools ...Tool) *ToolRegistry {
+	registry := NewToolRegistry()
+	for _, tool := range tools {
+		registry.Register(tool)
+	}
+	return registry
+}
+
+func TestCompositeToolChain(t *testing.T) {
+	search := &funcTool{name: "search", fn: func(call ToolCall) ToolResult {
+		return ToolResult{Output: []interface{}{"p1", "p2"}}
+	}}
+	fetch := &funcTool{name: "fetch", fn: func(call ToolCall) ToolResult {
+		var titles []interface{}
+		for _, id := range call.Args["ids"].([]interface{}) {
+			titles = append(titles, "title of "+id.(string))
+		}
+		return ToolResult{Output: titles}
+	}}
+	registry := compositeRegistry(search, fetch)
+	tool := NewCompositeTool(config.CompositeToolConfig{
+		Name: "research",
+		Steps: []config.CompositeStepConfig{
+			{ID: "search", Tool: "search", Args: map[string]interface{}{"query": "{{ .args.topic }}"}},
+			{ID: "fetch", Tool: "fetch", Args: map[string]interface{}{"ids": "{{ .steps.search.output }}"}},
+		},
+		Output: `{{ .args.topic }}: {{ join "; " .steps.fetch.output }}`,
+	}, registry)
+	registry.Register(tool)
+
+	res := registry.CallTool(context.Background(), ToolCall{Name: "research", Args: map[string]interface{}{"topic": "llm"}, Caller: "Assistant"})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.Output != "llm: title of p1; title of p2" {
+		t.Errorf("output = %q", res.Output)
+	}
+	calls := search.called()
+	if len(calls) != 1 || calls[0].Args["query"] != "llm" {
+		t.Fatalf("search calls = %+v", calls)
+	}
+	// Steps run as the composite, with the chain in the trace.
+	if calls[0].Caller != "research" || !reflect.DeepEqual(calls[0].Trace, []string{"research", "search"}) {
+		t.Errorf("step caller %q, trace %v", calls[0].Caller, calls[0].Trace)
+	}
+}
+
+func TestCompositeToolFailingStep(t *testing.T) {
+	ok := &funcTool{name: "ok", fn: func(call ToolCall) ToolResult { return ToolResult{Output: "fine"} }}
+	boom := &funcTool{name: "boom", fn: func(call ToolCall) ToolResult { return ToolResult{Error: errors.New("exploded")} }}
+	last := &funcTool{name: "last", fn: func(call ToolCall) ToolResult { return ToolResult{Output: call.Args["prev"]} }}
+	registry := compositeRegistry(ok, boom, last)
+	steps := []config.CompositeStepConfig{
+		{ID: "first", Tool: "ok"},
+		{ID: "middle", Tool: "boom", Needs: []string{"first"}, Retries: 1},
+		{ID: "final", Tool: "last", Args: map[string]interface{}{"prev": "error was: {{ .steps.middle.error }}"}},
+	}
+
+	tool := NewCompositeTool(config.CompositeToolConfig{Name: "pipeline", Steps: steps}, registry)
+	res := tool.Call(context.Background(), ToolCall{Name: "pipeline"})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "step middle (boom): exploded") {
+		t.Fatalf("err = %v, want the middle step's failure", res.Error)
+	}
+	if d := res.ErrorDetail; d == nil || d.Output != "first: ok\nmiddle: failed: exploded\nfinal: not run" {
+		t.Errorf("error detail = %+v", res.ErrorDetail)
+	}
+	if n := len(boom.called()); n != 2 {
+		t.Errorf("middle step called %d times, want 1 + 1 retry", n)
+	}
+	if n := len(last.called()); n != 0 {
+		t.Errorf("step after the failure ran %d times", n)
+	}
+
+	// With on_error: continue the next step sees the error.
+	steps[1].OnError = config.OnErrorContinue
+	tool = NewCompositeTool(config.CompositeToolConfig{Name: "pipeline", Steps: steps}, registry)
+	res = tool.Call(context.Background(), ToolCall{Name: "pipeline"})
+	if res.Error != nil || res.Output != "error was: exploded" {
+		t.Errorf("continue: output = %v, err = %v", res.Output, res.Error)
+	}
+}
+
+func TestCompositeToolUnknownReferences(t *testing.T) {
+	registry := compositeRegistry()
+	tool := NewCompositeTool(config.CompositeToolConfig{
+		Name:  "broken",
+		Steps: []config.CompositeStepConfig{{ID: "a", Tool: "missing_tool"}},
+	}, registry)
+	res := tool.Call(context.Background(), ToolCall{Name: "broken"})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "step a (missing_tool)") || !strings.Contains(res.Error.Error(), "tool not found") {
+		t.Errorf("err = %v, want the unknown tool", res.Error)
+	}
+
+	// References to unknown steps are rejected when the config is loaded.
+	err := config.ValidateMcpConfig(&config.McpConfig{CompositeTools: []config.CompositeToolConfig{{
+		Name: "broken",
+		Steps: []config.CompositeStepConfig{
+			{ID: "a", Tool: "search", Args: map[string]interface{}{"q": "{{ .steps.nope.output }}"}},
+		},
+		Output: "{{ .steps.gone.output }}",
+	}}})
+	for _, want := range []string{`step "a" depends on unknown step "nope"`, `output references unknown step "gone"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestCompositeToolCallsItself(t *testing.T) {
+	registry := compositeRegistry()
+	registry.Register(NewCompositeTool(config.CompositeToolConfig{
+		Name:  "outer",
+		Steps: []config.CompositeStepConfig{{ID: "a", Tool: "inner"}},
+	}, registry))
+	registry.Register(NewCompositeTool(config.CompositeToolConfig{
+		Name:  "inner",
+		Steps: []config.CompositeStepConfig{{ID: "b", Tool: "outer"}},
+	}, registry))
+	res := registry.CallTool(context.Background(), ToolCall{Name: "outer"})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "calls itself (trace outer > inner > outer)") {
+		t.Errorf("err = %v, want the cycle", res.Error)
+	}
+}
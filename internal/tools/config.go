@@ -24,4 +24,19 @@ func LoadToolConfig(path string) (*ToolConfig, error) {
     dec := yaml.NewDecoder(f)
     err = dec.Decode(&cfg)
     return &cfg, err
-}
\ No newline at end of file
+}
+
+// Enabled reports whether a tool may be registered: tools listed in
+// tools.yaml follow their enabled flag, unlisted tools are enabled. A nil
+// config (no tools.yaml) enables every tool.
+func (c *ToolConfig) Enabled(name string) bool {
+    if c == nil {
+        return true
+    }
+    for _, t := range c.Tools {
+        if t.Name == name {
+            return t.Enabled
+        }
+    }
+    return true
+}
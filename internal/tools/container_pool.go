@@ -0,0 +1,371 @@
+// internal/tools/container_pool.go
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/metrics"
+	"aiupstart.com/go-gen/internal/utils"
+	"github.com/google/uuid"
+)
+
+var ErrPoolDraining = errors.New("container pool is draining")
+
+// ContainerPoolConfig controls the warm pool.
+type ContainerPoolConfig struct {
+	Prefix        string         // container name prefix
+	MaxContainers int            // global cap on idle + in-use containers
+	WarmPerImage  map[string]int // idle containers to keep ready per image
+	MaxUses       int            // sessions per container before it is destroyed (0 = 1)
+	Sandbox       *SandboxOptions
+}
+
+// containerRunner is the docker side of the pool, replaced in tests.
+type containerRunner interface {
+	Run(ctx context.Context, name, image, workspace string, args []string) error
+	Remove(ctx context.Context, name string)
+	Recycle(ctx context.Context, name string) error
+}
+
+type dockerRunner struct{}
+
+func (dockerRunner) Run(ctx context.Context, name, image, workspace string, args []string) error {
+	return runContainer(ctx, name, image, workspace, args)
+}
+
+func (dockerRunner) Remove(ctx context.Context, name string) {
+	exec.CommandContext(ctx, "docker", "rm", "-f", name).Run()
+}
+
+// Recycle wipes the workspace from inside the container (files may be
+// root-owned) and restarts it so no process from the last session survives.
+// Anything the session changed outside /workspace (installed packages, /tmp,
+// the home directory) is kept, which is why MaxUses defaults to 1.
+func (dockerRunner) Recycle(ctx context.Context, name string) error {
+	wipe := exec.CommandContext(ctx, "docker", "exec", name, "sh", "-c", "rm -rf /workspace/* /workspace/.[!.]* /workspace/..?* 2>/dev/null; true")
+	if out, err := wipe.CombinedOutput(); err != nil {
+		return fmt.Errorf("wipe workspace: %v - output: %s", err, out)
+	}
+	restart := exec.CommandContext(ctx, "docker", "restart", "-t", "0", name)
+	if out, err := restart.CombinedOutput(); err != nil {
+		return fmt.Errorf("restart: %v - output: %s", err, out)
+	}
+	return nil
+}
+
+// PooledContainer is a running container with its own host workspace.
+type PooledContainer struct {
+	Name      string
+	Image     string
+	Workspace string
+	uses      int
+}
+
+// ContainerPool keeps pre-started containers per image so sessions skip the
+// docker run (and first dependency install) cost.
+type ContainerPool struct {
+	cfg      ContainerPoolConfig
+	runner   containerRunner
+	ctx      context.Context // cancelled by Drain to stop warmers
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	idle     map[string][]*PooledContainer // image -> idle containers
+	inUse    map[string]*PooledContainer   // name -> container
+	warming  map[string]int                // image -> containers being started
+	slots    chan struct{}                 // one token per live container
+	released chan struct{}                 // signalled on every release, for Drain
+	draining bool
+	wg       sync.WaitGroup
+}
+
+func NewContainerPool(cfg ContainerPoolConfig) *ContainerPool {
+	if cfg.MaxContainers <= 0 {
+		cfg.MaxContainers = 4
+	}
+	if cfg.MaxUses <= 0 {
+		cfg.MaxUses = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ContainerPool{
+		cfg:      cfg,
+		runner:   dockerRunner{},
+		ctx:      ctx,
+		cancel:   cancel,
+		idle:     map[string][]*PooledContainer{},
+		inUse:    map[string]*PooledContainer{},
+		warming:  map[string]int{},
+		slots:    make(chan struct{}, cfg.MaxContainers),
+		released: make(chan struct{}, 1),
+	}
+}
+
+// Warm starts the configured number of idle containers per image in the
+// background. Warmers stop when Drain starts.
+func (p *ContainerPool) Warm() {
+	for image := range p.cfg.WarmPerImage {
+		p.replenish(image)
+	}
+}
+
+// Acquire hands out an idle container for image, starting one if none is ready.
+// It blocks while the global cap is reached and nothing can be evicted.
+func (p *ContainerPool) Acquire(ctx context.Context, image string) (*PooledContainer, error) {
+	start := time.Now()
+	defer func() { metrics.PoolAcquireSeconds.WithLabelValues(image).Observe(time.Since(start).Seconds()) }()
+
+	p.mu.Lock()
+	if p.draining {
+		p.mu.Unlock()
+		return nil, ErrPoolDraining
+	}
+	if idle := p.idle[image]; len(idle) > 0 {
+		c := idle[len(idle)-1]
+		p.idle[image] = idle[:len(idle)-1]
+		p.inUse[c.Name] = c
+		p.mu.Unlock()
+		p.updateGauges(image)
+		metrics.PoolAcquireTotal.WithLabelValues(image, "hit").Inc()
+		p.replenish(image)
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	if err := p.takeSlot(ctx, image); err != nil {
+		metrics.PoolAcquireTotal.WithLabelValues(image, "error").Inc()
+		return nil, err
+	}
+	c, err := p.start(ctx, image)
+	if err != nil {
+		<-p.slots
+		metrics.PoolAcquireTotal.WithLabelValues(image, "error").Inc()
+		return nil, err
+	}
+	p.mu.Lock()
+	p.inUse[c.Name] = c
+	p.mu.Unlock()
+	p.updateGauges(image)
+	metrics.PoolAcquireTotal.WithLabelValues(image, "miss").Inc()
+	p.replenish(image)
+	return c, nil
+}
+
+// Release returns a container after a session. By default it is destroyed and
+// a fresh one warmed in its place; with MaxUses > 1 it is wiped and restarted
+// for reuse while the image still wants warm capacity.
+func (p *ContainerPool) Release(ctx context.Context, c *PooledContainer) {
+	p.mu.Lock()
+	if _, ok := p.inUse[c.Name]; !ok {
+		p.mu.Unlock()
+		return // already force-removed by Drain
+	}
+	delete(p.inUse, c.Name)
+	c.uses++
+	keep := !p.draining &&
+		len(p.idle[c.Image])+p.warming[c.Image] < p.cfg.WarmPerImage[c.Image] &&
+		c.uses < p.cfg.MaxUses
+	p.mu.Unlock()
+	defer p.signalReleased()
+
+	if keep {
+		err := p.runner.Recycle(ctx, c.Name)
+		if err == nil {
+			p.mu.Lock()
+			p.idle[c.Image] = append(p.idle[c.Image], c)
+			p.mu.Unlock()
+			p.updateGauges(c.Image)
+			metrics.PoolReleaseTotal.WithLabelValues(c.Image, "recycled").Inc()
+			return
+		}
+		utils.Logger.Warn().Str("container", c.Name).Err(err).Msg("Failed to recycle pooled container, destroying it")
+	}
+	p.destroy(ctx, c)
+	metrics.PoolReleaseTotal.WithLabelValues(c.Image, "destroyed").Inc()
+	p.replenish(c.Image)
+}
+
+// Drain stops warming, destroys idle containers and waits for in-use
+// containers to be released until ctx is done, then force-removes the rest.
+func (p *ContainerPool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+	p.cancel()
+	warmed := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(warmed)
+	}()
+	select {
+	case <-warmed:
+	case <-ctx.Done():
+		// A warmer still stuck in docker destroys its container when it
+		// returns and sees draining.
+	}
+
+	p.mu.Lock()
+	var idle []*PooledContainer
+	for image, list := range p.idle {
+		idle = append(idle, list...)
+		delete(p.idle, image)
+	}
+	p.mu.Unlock()
+	for _, c := range idle {
+		p.destroy(context.Background(), c)
+	}
+
+	for {
+		p.mu.Lock()
+		remaining := len(p.inUse)
+		p.mu.Unlock()
+		if remaining == 0 {
+			utils.Logger.Info().Msg("Container pool drained")
+			return nil
+		}
+		select {
+		case <-p.released:
+		case <-ctx.Done():
+			p.mu.Lock()
+			var busy []*PooledContainer
+			for _, c := range p.inUse {
+				busy = append(busy, c)
+			}
+			p.inUse = map[string]*PooledContainer{}
+			p.mu.Unlock()
+			for _, c := range busy {
+				p.destroy(context.Background(), c)
+			}
+			return fmt.Errorf("pool drain timed out, force-removed %d in-use container(s)", len(busy))
+		}
+	}
+}
+
+// takeSlot reserves capacity for a new container, evicting an idle container
+// of another image if the cap is reached.
+func (p *ContainerPool) takeSlot(ctx context.Context, image string) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if victim := p.evictIdle(image); victim != nil {
+		p.destroy(ctx, victim)
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for pool capacity (%d containers): %w", p.cfg.MaxContainers, ctx.Err())
+	}
+}
+
+func (p *ContainerPool) evictIdle(except string) *PooledContainer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for image, list := range p.idle {
+		if image == except || len(list) == 0 {
+			continue
+		}
+		c := list[0]
+		p.idle[image] = list[1:]
+		return c
+	}
+	return nil
+}
+
+// replenish tops up idle containers for image without blocking on the cap.
+func (p *ContainerPool) replenish(image string) {
+	p.mu.Lock()
+	missing := p.cfg.WarmPerImage[image] - len(p.idle[image]) - p.warming[image]
+	if p.draining || missing <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.warming[image] += missing
+	p.wg.Add(missing) // under mu so Drain cannot start waiting in between
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		go func() {
+			defer p.wg.Done()
+			defer func() {
+				p.mu.Lock()
+				p.warming[image]--
+				p.mu.Unlock()
+			}()
+			select {
+			case p.slots <- struct{}{}:
+			default:
+				return // at capacity; sessions take priority over warm spares
+			}
+			c, err := p.start(p.ctx, image)
+			if err != nil {
+				<-p.slots
+				if p.ctx.Err() == nil {
+					utils.Logger.Warn().Str("image", image).Err(err).Msg("Failed to warm pooled container")
+				}
+				return
+			}
+			p.mu.Lock()
+			if p.draining {
+				p.mu.Unlock()
+				p.destroy(context.Background(), c)
+				return
+			}
+			p.idle[image] = append(p.idle[image], c)
+			p.mu.Unlock()
+			p.updateGauges(image)
+		}()
+	}
+}
+
+func (p *ContainerPool) start(ctx context.Context, image string) (*PooledContainer, error) {
+	id := uuid.NewString()
+	c := &PooledContainer{
+		Name:      fmt.Sprintf("%s-pool-%s", p.cfg.Prefix, id),
+		Image:     image,
+		Workspace: filepath.Join(os.TempDir(), "dockerexec-"+id),
+	}
+	if err := p.runner.Run(ctx, c.Name, image, c.Workspace, p.cfg.Sandbox.runArgs("", image)); err != nil {
+		os.RemoveAll(c.Workspace)
+		return nil, err
+	}
+	utils.Logger.Debug().Str("container", c.Name).Str("image", image).Msg("Started pooled Docker container")
+	return c, nil
+}
+
+// destroy removes the container and its workspace and frees its slot.
+func (p *ContainerPool) destroy(ctx context.Context, c *PooledContainer) {
+	p.runner.Remove(ctx, c.Name)
+	os.RemoveAll(c.Workspace)
+	<-p.slots
+	p.updateGauges(c.Image)
+	utils.Logger.Debug().Str("container", c.Name).Msg("Destroyed pooled Docker container")
+}
+
+func (p *ContainerPool) signalReleased() {
+	select {
+	case p.released <- struct{}{}:
+	default:
+	}
+}
+
+func (p *ContainerPool) updateGauges(image string) {
+	p.mu.Lock()
+	idle := len(p.idle[image])
+	busy := 0
+	for _, c := range p.inUse {
+		if c.Image == image {
+			busy++
+		}
+	}
+	p.mu.Unlock()
+	metrics.PoolContainers.WithLabelValues(image, "idle").Set(float64(idle))
+	metrics.PoolContainers.WithLabelValues(image, "in_use").Set(float64(busy))
+}
@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRunner records pool docker calls. Run blocks while block is set,
+// until ctx is done unless ignoreCtx is set.
+type fakeRunner struct {
+	mu        sync.Mutex
+	started   []string
+	removed   []string
+	recycled  []string
+	block     chan struct{}
+	ignoreCtx bool
+	running   chan string
+}
+
+func (f *fakeRunner) Run(ctx context.Context, name, image, workspace string, args []string) error {
+	if f.running != nil {
+		f.running <- name
+	}
+	if f.block != nil {
+		if f.ignoreCtx {
+			<-f.block
+		} else {
+			select {
+			case <-f.block:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = append(f.started, name)
+	return nil
+}
+
+func (f *fakeRunner) Remove(ctx context.Context, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = append(f.removed, name)
+}
+
+func (f *fakeRunner) Recycle(ctx context.Context, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recycled = append(f.recycled, name)
+	return nil
+}
+
+func (f *fakeRunner) calls() (started, removed, recycled []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.started...), append([]string(nil), f.removed...), append([]string(nil), f.recycled...)
+}
+
+func fakePool(cfg ContainerPoolConfig, runner *fakeRunner) *ContainerPool {
+	cfg.Prefix = "test"
+	p := NewContainerPool(cfg)
+	p.runner = runner
+	return p
+}
+
+func TestContainerPoolDestroysUsedContainers(t *testing.T) {
+	runner := &fakeRunner{}
+	p := fakePool(ContainerPoolConfig{WarmPerImage: map[string]int{"python": 1}}, runner)
+	p.Warm()
+	p.wg.Wait()
+
+	first, err := p.Acquire(context.Background(), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.wg.Wait() // the replacement spare
+	p.Release(context.Background(), first)
+	p.wg.Wait()
+
+	_, removed, recycled := runner.calls()
+	if len(recycled) != 0 || len(removed) != 1 || removed[0] != first.Name {
+		t.Fatalf("removed %v, recycled %v; want the used container destroyed", removed, recycled)
+	}
+	second, err := p.Acquire(context.Background(), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name == first.Name {
+		t.Error("a used container was handed to the next session")
+	}
+	p.Release(context.Background(), second)
+	if err := p.Drain(context.Background()); err != nil {
+		t.Errorf("Drain: %v", err)
+	}
+}
+
+func TestContainerPoolRecyclesUpToMaxUses(t *testing.T) {
+	runner := &fakeRunner{}
+	// One slot: the spare warmer after Acquire finds no capacity, so the
+	// released container is the one that refills the warm pool.
+	p := fakePool(ContainerPoolConfig{MaxContainers: 1, MaxUses: 2, WarmPerImage: map[string]int{"python": 1}}, runner)
+	c, err := p.Acquire(context.Background(), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.wg.Wait()
+	p.Release(context.Background(), c)
+	if _, _, recycled := runner.calls(); len(recycled) != 1 {
+		t.Fatalf("recycled %v after the first use, want the container kept", recycled)
+	}
+
+	again, err := p.Acquire(context.Background(), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != c {
+		t.Fatalf("Acquire = %s, want the recycled %s", again.Name, c.Name)
+	}
+	p.Release(context.Background(), again)
+	p.wg.Wait()
+	if _, removed, _ := runner.calls(); len(removed) != 1 || removed[0] != c.Name {
+		t.Errorf("removed %v after MaxUses sessions, want %s", removed, c.Name)
+	}
+}
+
+func TestContainerPoolDrainCancelsWarmers(t *testing.T) {
+	runner := &fakeRunner{block: make(chan struct{}), running: make(chan string, 1)}
+	p := fakePool(ContainerPoolConfig{WarmPerImage: map[string]int{"python": 1}}, runner)
+	p.Warm()
+	<-runner.running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Drain waited for its deadline instead of cancelling the warmer")
+	}
+	if started, _, _ := runner.calls(); len(started) != 0 {
+		t.Errorf("started %v after Drain", started)
+	}
+}
+
+func TestContainerPoolDrainHonoursCtx(t *testing.T) {
+	// A warmer stuck in docker must not hold Drain past its deadline.
+	runner := &fakeRunner{block: make(chan struct{}), ignoreCtx: true, running: make(chan string, 1)}
+	p := fakePool(ContainerPoolConfig{WarmPerImage: map[string]int{"python": 1}}, runner)
+	p.Warm()
+	name := <-runner.running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		p.Drain(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain ignored its ctx while a warmer was starting")
+	}
+
+	// The late container is destroyed, not parked in the drained pool.
+	close(runner.block)
+	p.wg.Wait()
+	if _, removed, _ := runner.calls(); len(removed) != 1 || removed[0] != name {
+		t.Errorf("removed %v, want the late warm container %s", removed, name)
+	}
+}
+
+func TestContainerPoolDrainForceRemovesInUse(t *testing.T) {
+	runner := &fakeRunner{}
+	p := fakePool(ContainerPoolConfig{}, runner)
+	c, err := p.Acquire(context.Background(), "python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = p.Drain(ctx)
+	if err == nil || !strings.Contains(err.Error(), "force-removed 1") {
+		t.Fatalf("Drain = %v, want one force-removed container", err)
+	}
+	if _, removed, _ := runner.calls(); len(removed) != 1 || removed[0] != c.Name {
+		t.Errorf("removed %v, want %s", removed, c.Name)
+	}
+	if _, err := p.Acquire(context.Background(), "python"); err != ErrPoolDraining {
+		t.Errorf("Acquire after Drain = %v, want ErrPoolDraining", err)
+	}
+	p.Release(context.Background(), c) // a late release is a no-op
+}
@@ -3,10 +3,13 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -18,14 +21,36 @@ import (
 )
 
 type DockerExecTool struct{
-    containerID   string
     image         string
-    containerName string
     prefix        string
-    workspace     string
+    sessionID     string
+    workspace     string // shared by every runtime of the session
+    network       string // per-session docker network linking the runtimes
+    runtimes      map[string]*sandboxRuntime // runtime name -> container
+    starting      map[string]chan struct{}   // runtime name -> closed when its start ends
+    runner        containerRunner
+    maxFileSize   int // per code block, 0 = DefaultMaxFileSize
+    maxFileCount  int // per call, 0 = DefaultMaxFileCount
+    pool          *ContainerPool   // optional warm pool
+    sandbox       *SandboxOptions  // extra docker run settings (cache volumes, mirrors, ...)
+    injected      []string         // workspace files written by the tool itself (mirror configs)
+    env           map[string]string // secrets/config injected into every exec
+    inflight      sync.WaitGroup   // running docker exec commands
+    stop          chan struct{}    // closed by Close to abort in-flight execs
+    closed        bool
 	mu            sync.Mutex // for concurrency safety
 }
 
+// sandboxRuntime is one container of the session. All runtimes mount the same
+// workspace and join the session network under their name, so a generated
+// frontend runtime can reach a backend runtime at http://backend:<port>.
+type sandboxRuntime struct {
+    name          string
+    image         string
+    containerName string
+    pooled        *PooledContainer // set when the container came from pool
+}
+
 type CodeBlock struct {
 	Language string `json:"language"`
 	FileName string `json:"filename"`
@@ -39,15 +64,49 @@ func NewDockerExecTool(prefix string, image string) *DockerExecTool {
         image = DefaultDockerImage
     }
     return &DockerExecTool{
-        image:  image,
-        prefix: prefix,
+        image:    image,
+        prefix:   prefix,
+        runtimes: map[string]*sandboxRuntime{},
+        starting: map[string]chan struct{}{},
+        runner:   dockerRunner{},
+        stop:     make(chan struct{}),
     }
 }
 
 
+// SetPool makes the tool take its container from a warm pool instead of
+// running a fresh one, and hand it back on CleanupContainer.
+func (t *DockerExecTool) SetPool(pool *ContainerPool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pool = pool
+}
+
+// SetSandboxOptions sets the docker run options for containers started by this tool.
+func (t *DockerExecTool) SetSandboxOptions(opts *SandboxOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sandbox = opts
+}
+
+// SetEnv sets environment variables (e.g. allowlisted secrets) for every
+// command run in the session containers.
+func (t *DockerExecTool) SetEnv(env map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.env = env
+}
+
+// SetWriteLimits overrides the per-file size and per-call file count limits for code blocks.
+func (t *DockerExecTool) SetWriteLimits(maxFileSize, maxFileCount int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxFileSize = maxFileSize
+	t.maxFileCount = maxFileCount
+}
 
 func (t *DockerExecTool) Name() string        { return "docker_exec" }
-func (t *DockerExecTool) Description() string { return "Execute code/scripts in a persistent Docker container. Supports python, bash, sh, dotnet, angular cli, npm." }
+func (t *DockerExecTool) Description() string { return "Execute code/scripts in persistent Docker containers. Supports python, bash, sh, dotnet, angular cli, npm. Each language (or named runtime) gets its own container sharing /workspace; runtimes reach each other by runtime name as hostname." }
 
 // Should return the function schema for OpenAI, or a description of accepted parameters
 func (t *DockerExecTool) Parameters() map[string]interface{} {
@@ -60,8 +119,9 @@ func (t *DockerExecTool) Parameters() map[string]interface{} {
 				"code":     "string",
 			},
 		},
-		"init":   "string",
-		"launch": "string",
+		"init":    "string",
+		"launch":  "string",
+		"runtime": "string",
 	}
 }
 
@@ -78,64 +138,291 @@ var langImageMap = map[string]string{
 
 
 
-// Ensure persistent container for the session
-func (t *DockerExecTool) ensureContainer(ctx context.Context, lang string) error {
+// ensureRuntime returns the persistent container for the named runtime,
+// starting it (and the session workspace and network) on first use. The image
+// is picked from the language of the call that first creates the runtime.
+//
+// The docker work runs without t.mu, so Close, Workspace and calls to other
+// runtimes are not held up by an image pull. A start in progress is recorded
+// in t.starting; calls for the same runtime wait for it, as do all calls
+// while the session's first runtime sets up the workspace and network.
+func (t *DockerExecTool) ensureRuntime(ctx context.Context, name, lang string) (*sandboxRuntime, error) {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-	if t.containerName != "" {
-		// Optionally check "docker inspect" if you want to verify running
-		return nil
+	for {
+		if t.closed {
+			t.mu.Unlock()
+			return nil, errDockerExecClosed
+		}
+		if rt, ok := t.runtimes[name]; ok {
+			t.mu.Unlock()
+			// Optionally check "docker inspect" if you want to verify running
+			return rt, nil
+		}
+		wait := t.starting[name]
+		if wait == nil && t.workspace == "" {
+			for _, c := range t.starting {
+				wait = c
+				break
+			}
+		}
+		if wait == nil {
+			break
+		}
+		t.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		t.mu.Lock() // the start may have failed; look again
 	}
-	id := uuid.NewString()
+
 	img := t.image
 	if limg, ok := langImageMap[lang]; ok {
 		img = limg
 	}
-	containerName := fmt.Sprintf("%s-%s", t.prefix, id)
-	workspace := filepath.Join(os.TempDir(), "dockerexec-"+id)
-	os.MkdirAll(workspace, 0o755)
-    utils.Logger.Debug().Str("containerName", containerName).Str("image", img).Msg("About to start the container for the session")
+	if t.sessionID == "" {
+		t.sessionID = uuid.NewString()
+	}
+	st := runtimeStart{
+		rt:        &sandboxRuntime{name: name, image: img},
+		session:   t.sessionID,
+		first:     t.workspace == "",
+		workspace: t.workspace,
+		network:   t.network,
+		linked:    t.sandbox == nil || t.sandbox.NetworkMode != "none",
+		runArgs:   t.sandbox.runArgs(lang, img),
+	}
+	if st.first {
+		st.workspace = filepath.Join(os.TempDir(), "dockerexec-"+st.session)
+		st.pool = t.pool
+		if t.sandbox != nil {
+			st.mirror = t.sandbox.Mirror
+		}
+	}
+	done := make(chan struct{})
+	t.starting[name] = done
+	t.mu.Unlock()
+
+	// Close aborts a start like it aborts execs.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-t.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := t.startRuntime(ctx, &st)
+
+	t.mu.Lock()
+	delete(t.starting, name)
+	close(done)
+	if err == nil && (t.closed || t.sessionID != st.session) {
+		err = fmt.Errorf("runtime %q: the session ended while its container was starting", name)
+	}
+	if err != nil {
+		t.mu.Unlock()
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		t.discardRuntime(cleanupCtx, &st)
+		return nil, err
+	}
+	if st.first {
+		t.workspace, t.network, t.injected = st.workspace, st.network, st.injected
+	}
+	t.runtimes[name] = st.rt
+	t.mu.Unlock()
+	return st.rt, nil
+}
+
+var errDockerExecClosed = errors.New("docker_exec is shutting down")
+
+// runtimeStart is what ensureRuntime hands to startRuntime: a snapshot of
+// the session taken under t.mu, and what the start created.
+type runtimeStart struct {
+	rt        *sandboxRuntime
+	session   string
+	first     bool // sets up the workspace and network
+	workspace string
+	network   string
+	linked    bool // join the session network
+	runArgs   []string
+	pool      *ContainerPool // first runtime only
+	mirror    *MirrorConfig  // first runtime only
+	injected  []string
+	started   bool
+}
+
+// startRuntime starts the container of st.rt, or takes it from the pool, and
+// links it to the session network. It runs without t.mu.
+func (t *DockerExecTool) startRuntime(ctx context.Context, st *runtimeStart) error {
+	rt := st.rt
+	// Pooled containers come with their own workspace, so only the first
+	// runtime of a session can be taken from the pool.
+	if st.pool != nil {
+		c, err := st.pool.Acquire(ctx, rt.image)
+		if err != nil {
+			return fmt.Errorf("failed to acquire pooled container: %w", err)
+		}
+		rt.pooled = c
+		rt.containerName = c.Name
+		st.workspace = c.Workspace
+		utils.Logger.Debug().Str("container", c.Name).Str("image", rt.image).Msg("Using pooled Docker container for the session")
+	} else {
+		rt.containerName = fmt.Sprintf("%s-%s-%s", t.prefix, st.session, rt.name)
+		utils.Logger.Debug().Str("containerName", rt.containerName).Str("image", rt.image).Msg("About to start the container for the session")
+		st.started = true // a failed docker run may still leave a container
+		if err := t.runner.Run(ctx, rt.containerName, rt.image, st.workspace, st.runArgs); err != nil {
+			return err
+		}
+		utils.Logger.Debug().Str("container", rt.containerName).Msg("Started persistent Docker container")
+	}
+
+	if st.first {
+		st.injected = injectMirrorConfig(st.workspace, st.mirror)
+	}
+	if !st.linked {
+		utils.Logger.Debug().Str("runtime", rt.name).Msg("Sandbox network is disabled; runtimes are not linked")
+		return nil
+	}
+	if st.first {
+		network := fmt.Sprintf("%s-net-%s", t.prefix, st.session)
+		args := append([]string{"network", "create"}, managedLabelArgs("")...)
+		if out, err := exec.CommandContext(ctx, "docker", append(args, network)...).CombinedOutput(); err != nil {
+			utils.Logger.Warn().Err(err).Bytes("output", out).Msg("Failed to create the session network; runtimes are not linked")
+			return nil
+		}
+		st.network = network
+	}
+	if st.network == "" {
+		return nil
+	}
+	if out, err := exec.CommandContext(ctx, "docker", "network", "connect", "--alias", rt.name, st.network, rt.containerName).CombinedOutput(); err != nil {
+		utils.Logger.Warn().Str("runtime", rt.name).Err(err).Bytes("output", out).Msg("Runtime is not linked to the session network")
+	}
+	return nil
+}
+
+// discardRuntime undoes a start that failed or finished after the session
+// ended.
+func (t *DockerExecTool) discardRuntime(ctx context.Context, st *runtimeStart) {
+	if st.rt.pooled != nil {
+		if st.network != "" {
+			exec.CommandContext(ctx, "docker", "network", "disconnect", "-f", st.network, st.rt.containerName).Run()
+		}
+		st.pool.Release(ctx, st.rt.pooled)
+	} else if st.started {
+		t.runner.Remove(ctx, st.rt.containerName)
+	}
+	if st.first {
+		if st.network != "" {
+			exec.CommandContext(ctx, "docker", "network", "rm", st.network).Run()
+		}
+		if st.rt.pooled == nil {
+			os.RemoveAll(st.workspace)
+		}
+	}
+}
+
+// injectMirrorConfig writes .npmrc, pip.conf and NuGet.config for the
+// configured package mirrors into a fresh workspace and returns their names.
+func injectMirrorConfig(workspace string, mirror *MirrorConfig) []string {
+	if mirror == nil {
+		return nil
+	}
+	files := mirror.configFiles()
+	if err := NewWorkspaceWriter(workspace).WriteBlocks(files); err != nil {
+		utils.Logger.Error().Err(err).Msg("Failed to write package mirror configuration")
+		return nil
+	}
+	var injected []string
+	for _, f := range files {
+		injected = append(injected, f.FileName)
+	}
+	return injected
+}
+
+// runtimeName picks the runtime for a call: the explicit "runtime" argument,
+// else the language, so python and dotnet calls land in different containers.
+func runtimeName(args map[string]interface{}, lang string) string {
+	name, _ := args["runtime"].(string)
+	if strings.TrimSpace(name) == "" {
+		name = lang
+	}
+	// Must be valid both in a container name and as a DNS alias.
+	name = runtimeNameSanitizer.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	name = strings.Trim(name, "-.")
+	if name == "" || name == "nil" {
+		return "default"
+	}
+	return name
+}
+
+var runtimeNameSanitizer = regexp.MustCompile(`[^a-z0-9.-]+`)
 
-	cmd := exec.CommandContext(ctx, "docker", "run", "-d", "--name", containerName, "-w", "/workspace", "-v", workspace+":/workspace", img, "tail", "-f", "/dev/null")
+// runContainer starts an idle container with workspace bind-mounted at /workspace.
+func runContainer(ctx context.Context, name, image, workspace string, extraArgs []string) error {
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		return fmt.Errorf("error creating workspace %s: %w", workspace, err)
+	}
+	args := []string{"run", "-d", "--name", name, "-w", "/workspace", "-v", workspace + ":/workspace"}
+	args = append(args, managedLabelArgs(workspace)...)
+	args = append(args, extraArgs...)
+	args = append(args, image, "tail", "-f", "/dev/null")
+	cmd := exec.CommandContext(ctx, "docker", args...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to start container: %v - output: %s", err, string(out))
 	}
-	t.containerName = containerName
-	t.workspace = workspace
-	utils.Logger.Debug().Str("container", containerName).Msg("Started persistent Docker container")
 	return nil
 }
 
 func (t *DockerExecTool) copyFilesToContainer(ctx context.Context, files []CodeBlock) error {
-	utils.Logger.Debug().Str("tool", t.Name()).Msg("About to loop over files and write them out")
-	for _, f := range files {
-		dest := filepath.Join(t.workspace, f.FileName)
-		utils.Logger.Debug().Str("tool", t.Name()).Msgf("Processing file %s", dest)
-
-		// Ensure the parent directories exist
-		parentDir := filepath.Dir(dest)
-        utils.Logger.Debug().Str("tool", t.Name()).Msgf("Checking parent path exists and creating if not")
-		if err := os.MkdirAll(parentDir, 0o755); err != nil {
-			utils.Logger.Error().Str("tool", t.Name()).Msgf("Failed to create directories in path %s: %v", parentDir, err)
-			return fmt.Errorf("error creating directories in path %s: %w", parentDir, err)
-		}
+	utils.Logger.Debug().Str("tool", t.Name()).Msg("About to validate and write out files")
+	t.mu.Lock()
+	writer := NewWorkspaceWriter(t.workspace)
+	if t.maxFileSize > 0 {
+		writer.MaxFileSize = t.maxFileSize
+	}
+	if t.maxFileCount > 0 {
+		writer.MaxFileCount = t.maxFileCount
+	}
+	t.mu.Unlock()
 
-		if err := os.WriteFile(dest, []byte(f.Code), 0644); err != nil {
-			utils.Logger.Error().Str("tool", t.Name()).Msgf("Failed to write file: %s: %v", dest, err)
-			return fmt.Errorf("failed to write temp file: %w", err)
-		}
-		// No need to docker cp since we mounted the workspace on start
+	// No need to docker cp since we mounted the workspace on start
+	if err := writer.WriteBlocks(files); err != nil {
+		utils.Logger.Error().Str("tool", t.Name()).Msgf("Rejected code blocks: %v", err)
+		return err
 	}
 	return nil
 }
 
-func (t *DockerExecTool) execInContainer(ctx context.Context, command string, timeout time.Duration) (string, error) {
-    args := []string{"exec", t.containerName, "sh", "-c", command}
+func (t *DockerExecTool) execInContainer(ctx context.Context, rt *sandboxRuntime, command string, timeout time.Duration) (string, error) {
+    args := []string{"exec"}
+    t.mu.Lock()
+    env := os.Environ()
+    for k, v := range t.env {
+        // "-e NAME" without a value makes docker copy it from our environment,
+        // so secret values never appear in the docker command line.
+        args = append(args, "-e", k)
+        env = append(env, k+"="+v)
+    }
+    t.mu.Unlock()
+    args = append(args, rt.containerName, "sh", "-c", command)
     ctx, cancel := context.WithTimeout(ctx, timeout)
     defer cancel()
+    go func() {
+        select {
+        case <-t.stop:
+            cancel()
+        case <-ctx.Done():
+        }
+    }()
 
     cmd := exec.CommandContext(ctx, "docker", args...)
+    cmd.Env = env
     output, err := cmd.CombinedOutput()
 
     if ctx.Err() == context.DeadlineExceeded {
@@ -151,14 +438,22 @@ func (t *DockerExecTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	defer timer.ObserveDuration()
     timeoutSec := 90 // default
     if to, ok := call.Args["timeout"].(float64); ok {
-        timeoutSec = int(to)
+        if to <= 0 {
+            return ToolResult{Error: fmt.Errorf("timeout must be a positive number of seconds, got %v", to)}
+        }
+        timeoutSec = int(math.Ceil(to))
     }
     timeoutDuration := time.Duration(timeoutSec)*time.Second
 
 	langRaw, _ := call.Args["language"]
 	lang := strings.ToLower(fmt.Sprintf("%v", langRaw))
 
-	if err := t.ensureContainer(ctx, lang); err != nil {
+	if !t.begin() {
+		return ToolResult{Error: errDockerExecClosed}
+	}
+	defer t.inflight.Done()
+	rt, err := t.ensureRuntime(ctx, runtimeName(call.Args, lang), lang)
+	if err != nil {
 		utils.Logger.Error().Msgf("Failed to ensure container: %v", err)
 		return ToolResult{Error: err}
 	}
@@ -184,7 +479,16 @@ func (t *DockerExecTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	}
 
 	if err := t.copyFilesToContainer(ctx, blocks); err != nil {
-		return ToolResult{Error: err}
+		return ToolResult{
+			Output: formatExecError("write", "write code_blocks to /workspace", err.Error(), "one or more files were rejected; fix the file names or sizes and retry"),
+			Error:  err,
+			ErrorDetail: &ExecErrorDetail{
+				Phase:   "write",
+				Command: "write code_blocks to /workspace",
+				Output:  err.Error(),
+				ErrMsg:  "one or more files were rejected",
+			},
+		}
 	}
 
     utils.Logger.Debug().Str("tool", t.Name()).Msg("Finished copying files to container and about to run init and launch commands")
@@ -193,7 +497,7 @@ func (t *DockerExecTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	initCmd, _ := call.Args["init"].(string)
 	if strings.TrimSpace(initCmd) != "" {
         utils.Logger.Debug().Str("tool", t.Name()).Msgf("About to execute the init command %s", initCmd)
-		initOut, err := t.execInContainer(ctx, initCmd, timeoutDuration)
+		initOut, err := t.execInContainer(ctx, rt, initCmd, timeoutDuration)
 		if err != nil {
             errDetail := formatExecError("init", initCmd, initOut, err.Error())
             return ToolResult{
@@ -213,12 +517,11 @@ func (t *DockerExecTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	// 3. Run launch or constructed main command
 	launchCmd, _ := call.Args["launch"].(string)
 	var output string
-	var err error
 	if strings.TrimSpace(launchCmd) != "" {
         // todo if angular, path the command to ensure no TTY expected
         launchCmd = patchAngularCmd(launchCmd)
         utils.Logger.Debug().Str("tool", t.Name()).Msgf("About to execute launch command %s", launchCmd)
-		output, err = t.execInContainer(ctx, launchCmd, timeoutDuration)
+		output, err = t.execInContainer(ctx, rt, launchCmd, timeoutDuration)
 	} else if len(blocks) > 0 {
 		mainfile := blocks[0].FileName
 		run := ""
@@ -235,7 +538,7 @@ func (t *DockerExecTool) Call(ctx context.Context, call ToolCall) ToolResult {
 			run = fmt.Sprintf("sh %s", mainfile)
 		}
         utils.Logger.Debug().Str("tool", t.Name()).Msgf("About to execute launch command %s", run)
-		output, err = t.execInContainer(ctx, run, timeoutDuration)
+		output, err = t.execInContainer(ctx, rt, run, timeoutDuration)
 	}
 
     if err != nil {
@@ -257,21 +560,86 @@ func (t *DockerExecTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	}
 }
 
+// Workspace returns the host directory mounted at /workspace, or "" before the container starts.
+func (t *DockerExecTool) Workspace() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.workspace
+}
+
 // Clean up (call at session end or from manager)
 func (t *DockerExecTool) CleanupContainer(ctx context.Context) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if t.containerName != "" {
-		exec.CommandContext(ctx, "docker", "rm", "-f", t.containerName).Run()
-		utils.Logger.Debug().Str("container", t.containerName).Msg("Cleaned up Docker container")
-		t.containerName = ""
+	// Remove plain runtimes first; a pooled runtime owns the shared workspace.
+	var pooled *sandboxRuntime
+	for name, rt := range t.runtimes {
+		if rt.pooled != nil {
+			pooled = rt
+			continue
+		}
+		t.runner.Remove(ctx, rt.containerName)
+		utils.Logger.Debug().Str("container", rt.containerName).Msg("Cleaned up Docker container")
+		delete(t.runtimes, name)
 	}
-	if t.workspace != "" {
+	if pooled != nil {
+		if t.network != "" {
+			exec.CommandContext(ctx, "docker", "network", "disconnect", "-f", t.network, pooled.containerName).Run()
+		}
+		t.pool.Release(ctx, pooled.pooled)
+		delete(t.runtimes, pooled.name)
+	} else if t.workspace != "" {
 		os.RemoveAll(t.workspace)
 	}
+	if t.network != "" {
+		exec.CommandContext(ctx, "docker", "network", "rm", t.network).Run()
+		t.network = ""
+	}
+	t.workspace = ""
+	t.sessionID = ""
 	return nil
 }
 
+// Close aborts in-flight commands, waits for them to return (until ctx is
+// done) and removes the session containers, network and workspace.
+func (t *DockerExecTool) Close(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.stop)
+	}
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		utils.Logger.Warn().Msg("Timed out waiting for in-flight docker exec commands")
+	}
+	// Containers must go even if the shutdown deadline already passed.
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	return t.CleanupContainer(cleanupCtx)
+}
+
+// begin registers an in-flight call unless the tool is closed. The check and
+// the Add happen under t.mu so Close cannot slip in between and remove the
+// containers while the call starts using them. Callers must call
+// t.inflight.Done when begin returns true.
+func (t *DockerExecTool) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return false
+	}
+	t.inflight.Add(1)
+	return true
+}
+
 // Static: Clean up all by prefix (e.g. crash recovery)
 func CleanupAllByPrefix(ctx context.Context, prefix string) {
 	cmd := exec.CommandContext(ctx, "sh", "-c", fmt.Sprintf(`docker ps -a --filter "name=%s" -q | xargs docker rm -f`, prefix))
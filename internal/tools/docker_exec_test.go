@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDockerExecRejectsNonPositiveTimeout(t *testing.T) {
+	tool := NewDockerExecTool("test", "")
+	for _, timeout := range []float64{0, -5} {
+		res := tool.Call(context.Background(), ToolCall{Name: tool.Name(), Args: map[string]interface{}{"timeout": timeout}})
+		if res.Error == nil || !strings.Contains(res.Error.Error(), "timeout must be a positive") {
+			t.Errorf("timeout %v: err = %v, want a rejection", timeout, res.Error)
+		}
+	}
+}
+
+func TestDockerExecRefusesCallsAfterClose(t *testing.T) {
+	tool := NewDockerExecTool("test", "")
+	if err := tool.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	res := tool.Call(context.Background(), ToolCall{Name: tool.Name(), Args: map[string]interface{}{}})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "shutting down") {
+		t.Fatalf("err = %v, want a closed tool", res.Error)
+	}
+	// The refused call must not leave a registered in-flight call behind.
+	tool.inflight.Wait()
+}
+
+// fakeExec returns a DockerExecTool whose containers are started by runner
+// and whose runtimes are not linked, so no docker command runs.
+func fakeExec(runner *fakeRunner) *DockerExecTool {
+	tool := NewDockerExecTool("test", "")
+	tool.runner = runner
+	tool.SetSandboxOptions(&SandboxOptions{NetworkMode: "none"})
+	return tool
+}
+
+func TestDockerExecCloseDuringStart(t *testing.T) {
+	runner := &fakeRunner{block: make(chan struct{}), running: make(chan string, 1)}
+	tool := fakeExec(runner)
+	res := make(chan ToolResult, 1)
+	go func() {
+		res <- tool.Call(context.Background(), ToolCall{Name: tool.Name(), Args: map[string]interface{}{"language": "python"}})
+	}()
+	<-runner.running
+
+	// The pending docker run must not hold the tool's lock.
+	tool.Workspace()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tool.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Close waited for the pending start instead of aborting it")
+	}
+	if r := <-res; r.Error == nil {
+		t.Error("the call whose start was aborted succeeded")
+	}
+	if _, removed, _ := runner.calls(); len(removed) != 1 {
+		t.Errorf("removed %v, want the half-started container", removed)
+	}
+}
+
+func TestDockerExecStartsRuntimeOnce(t *testing.T) {
+	runner := &fakeRunner{block: make(chan struct{}), running: make(chan string, 2)}
+	tool := fakeExec(runner)
+	defer tool.Close(context.Background()) // removes the workspace
+
+	var wg sync.WaitGroup
+	rts := make([]*sandboxRuntime, 3)
+	for i := range rts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rt, err := tool.ensureRuntime(context.Background(), "python", "python")
+			if err != nil {
+				t.Error(err)
+			}
+			rts[i] = rt
+		}()
+	}
+	<-runner.running
+	close(runner.block)
+	wg.Wait()
+	if started, _, _ := runner.calls(); len(started) != 1 {
+		t.Errorf("started %v, want one container for the runtime", started)
+	}
+	if rts[0] != rts[1] || rts[1] != rts[2] {
+		t.Error("concurrent calls got different runtimes")
+	}
+	if tool.Workspace() == "" {
+		t.Error("workspace not set after the first runtime started")
+	}
+}
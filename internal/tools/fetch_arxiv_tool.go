@@ -7,43 +7,114 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"aiupstart.com/go-gen/internal/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const (
+	defaultArxivResults = 5
+	maxArxivResults     = 50
+)
+
+// arxivAPIURL and arxivBaseURL are variables so they can point at a local
+// server.
+var (
+	arxivAPIURL  = "https://export.arxiv.org/api/query"
+	arxivBaseURL = "https://arxiv.org"
+)
+
+// arXiv asks API clients to wait 3 seconds between requests; every arXiv
+// tool goes through one throttle.
+var arxivThrottle = &throttle{interval: 3 * time.Second}
+
+// throttle spaces calls at least interval apart.
+type throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller may send its request, or ctx is done.
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	at := t.next
+	if at.Before(now) {
+		at = now
+	}
+	t.next = at.Add(t.interval)
+	t.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type FetchArxivTool struct{}
 
 type arxivFeed struct {
-	Entries []struct {
+	TotalResults int `xml:"totalResults"`
+	Entries      []struct {
 		Title   string `xml:"title"`
 		Authors []struct {
 			Name string `xml:"name"`
 		} `xml:"author"`
-		Summary string `xml:"summary"`
-		ID      string `xml:"id"`
+		Summary    string `xml:"summary"`
+		ID         string `xml:"id"`
+		Published  string `xml:"published"`
+		Updated    string `xml:"updated"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+		Links []struct {
+			Href  string `xml:"href,attr"`
+			Title string `xml:"title,attr"`
+		} `xml:"link"`
 	} `xml:"entry"`
 }
 
+var (
+	arxivCategoryPattern = regexp.MustCompile(`^[a-zA-Z-]+(\.[a-zA-Z-]+)?$`)
+	arxivSorts           = map[string]string{"relevance": "relevance", "submitted": "submittedDate", "updated": "lastUpdatedDate"}
+)
+
 func (t *FetchArxivTool) Name() string { return "fetch_arxiv" }
 func (t *FetchArxivTool) Description() string {
-    return "Fetch recent arXiv papers on a given topic."
+	return "Search arXiv papers by free query, title, abstract, author, category (e.g. cs.AI) and submission date, with paging and sorting. Returns id, title, authors, summary, dates, categories and links."
 }
-// func (t *FetchArxivTool) Parameters() map[string]string {
-//     return map[string]string{"query": "string"}
-// }
 func (t *FetchArxivTool) Parameters() map[string]interface{} {
+	str := func(desc string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": desc}
+	}
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"query": map[string]interface{}{
-				"type":        "string",
-				"description": "Search query for arXiv",
-			},
+			"query":       str("Search query for arXiv (all fields; arXiv syntax such as ti:, au:, AND, OR is accepted)"),
+			"title":       str("Words or phrase that must appear in the title"),
+			"abstract":    str("Words or phrase that must appear in the abstract"),
+			"author":      str("Author name, e.g. \"Hinton\""),
+			"category":    str("arXiv category, e.g. cs.AI, cs.CL or math"),
+			"date_from":   str("Earliest submission date, YYYY-MM-DD"),
+			"date_to":     str("Latest submission date, YYYY-MM-DD"),
+			"max_results": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of papers to return (default %d, at most %d)", defaultArxivResults, maxArxivResults)},
+			"start":       map[string]interface{}{"type": "integer", "description": "Offset of the first paper, for paging (default 0)"},
+			"sort_by":     map[string]interface{}{"type": "string", "enum": []string{"relevance", "submitted", "updated"}, "description": "Sort order (default submitted)"},
+			"sort_order":  map[string]interface{}{"type": "string", "enum": []string{"descending", "ascending"}, "description": "Default descending"},
 		},
-		"required": []string{"query"},
 	}
 }
 
@@ -51,27 +122,14 @@ func (t *FetchArxivTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
 	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
 	defer timer.ObserveDuration()
-	
-    query, ok := call.Args["query"].(string)
-    if !ok {
-        return ToolResult{Error: fmt.Errorf("missing argument: query")}
-    }
-	
-
-	apiURL := "http://export.arxiv.org/api/query?search_query=" + url.QueryEscape(query) + "&start=0&max_results=5&sortBy=submittedDate&sortOrder=descending"
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return ToolResult{Error: err}
-	}
-	req.Header.Set("User-Agent", "aiup-go-gen/1.0")
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+
+	apiURL, err := arxivSearchURL(call.Args)
 	if err != nil {
 		return ToolResult{Error: err}
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	body, err := arxivGet(ctx, apiURL, 15*time.Second, 10<<20)
 	if err != nil {
+		metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
 		return ToolResult{Error: err}
 	}
 	var feed arxivFeed
@@ -80,19 +138,162 @@ func (t *FetchArxivTool) Call(ctx context.Context, call ToolCall) ToolResult {
 	}
 	papers := []map[string]string{}
 	for _, entry := range feed.Entries {
+		// Invalid queries come back as a single entry describing the error.
+		if strings.Contains(entry.ID, "/api/errors") {
+			return ToolResult{Error: fmt.Errorf("arXiv rejected the query: %s", strings.TrimSpace(entry.Summary))}
+		}
 		authors := []string{}
 		for _, a := range entry.Authors {
 			authors = append(authors, a.Name)
 		}
-		papers = append(papers, map[string]string{
-			"Title":   strings.TrimSpace(entry.Title),
-			"Authors": strings.Join(authors, ", "),
-			"Summary": strings.TrimSpace(entry.Summary),
-			"URL":     entry.ID,
-		})
-	}
-    // papers := []map[string]string{
-    //     {"Title": fmt.Sprintf("LLM Research about %s", query), "Authors": "Jane Doe", "Summary": "Summary...", "URL": "http://arxiv.org/abs/1234"},
-    // }
-    return ToolResult{Output: papers}
-}
\ No newline at end of file
+		categories := []string{}
+		for _, c := range entry.Categories {
+			categories = append(categories, c.Term)
+		}
+		paper := map[string]string{
+			"ID":         arxivIDFromURL(entry.ID),
+			"Title":      strings.Join(strings.Fields(entry.Title), " "),
+			"Authors":    strings.Join(authors, ", "),
+			"Summary":    strings.TrimSpace(entry.Summary),
+			"URL":        entry.ID,
+			"Published":  entry.Published,
+			"Updated":    entry.Updated,
+			"Categories": strings.Join(categories, ", "),
+		}
+		for _, l := range entry.Links {
+			if l.Title == "pdf" {
+				paper["PDF"] = l.Href
+			}
+		}
+		papers = append(papers, paper)
+	}
+	return ToolResult{Output: papers}
+}
+
+// arxivSearchURL builds the API query of the tool's arguments.
+func arxivSearchURL(args map[string]interface{}) (string, error) {
+	arg := func(name string) string {
+		s, _ := args[name].(string)
+		return strings.TrimSpace(s)
+	}
+	var terms []string
+	if q := arg("query"); q != "" {
+		if !strings.Contains(q, ":") {
+			q = "all:" + arxivPhrase(q)
+		}
+		terms = append(terms, "("+q+")")
+	}
+	for _, f := range []struct{ arg, prefix string }{{"title", "ti"}, {"abstract", "abs"}, {"author", "au"}} {
+		if v := arg(f.arg); v != "" {
+			terms = append(terms, f.prefix+":"+arxivPhrase(v))
+		}
+	}
+	if c := arg("category"); c != "" {
+		if !arxivCategoryPattern.MatchString(c) {
+			return "", fmt.Errorf("invalid category %q: use an arXiv category such as cs.AI", c)
+		}
+		terms = append(terms, "cat:"+c)
+	}
+	from, to := arg("date_from"), arg("date_to")
+	if from != "" || to != "" {
+		lo, err := arxivDate(from, "199101010000", "0000")
+		if err != nil {
+			return "", fmt.Errorf("date_from: %w", err)
+		}
+		hi, err := arxivDate(to, time.Now().UTC().Format("200601021504"), "2359")
+		if err != nil {
+			return "", fmt.Errorf("date_to: %w", err)
+		}
+		terms = append(terms, fmt.Sprintf("submittedDate:[%s TO %s]", lo, hi))
+	}
+	if len(terms) == 0 {
+		return "", fmt.Errorf("missing argument: give a query, title, abstract, author or category")
+	}
+
+	max := intArg(args, "max_results", defaultArxivResults)
+	if max < 1 {
+		max = defaultArxivResults
+	}
+	if max > maxArxivResults {
+		max = maxArxivResults
+	}
+	start := intArg(args, "start", 0)
+	if start < 0 {
+		start = 0
+	}
+	sortBy := "submitted"
+	if s := arg("sort_by"); s != "" {
+		sortBy = s
+	}
+	sortField, ok := arxivSorts[sortBy]
+	if !ok {
+		return "", fmt.Errorf("invalid sort_by %q (want relevance, submitted or updated)", sortBy)
+	}
+	order := "descending"
+	if o := arg("sort_order"); o != "" {
+		order = o
+	}
+	if order != "descending" && order != "ascending" {
+		return "", fmt.Errorf("invalid sort_order %q (want descending or ascending)", order)
+	}
+
+	q := url.Values{}
+	q.Set("search_query", strings.Join(terms, " AND "))
+	q.Set("start", fmt.Sprint(start))
+	q.Set("max_results", fmt.Sprint(max))
+	q.Set("sortBy", sortField)
+	q.Set("sortOrder", order)
+	return arxivAPIURL + "?" + q.Encode(), nil
+}
+
+// arxivPhrase quotes multi-word values so they match as a phrase.
+func arxivPhrase(s string) string {
+	s = strings.ReplaceAll(s, `"`, "")
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// arxivDate converts YYYY-MM-DD (or YYYYMMDD) to the API's YYYYMMDDHHMM.
+func arxivDate(s, def, hhmm string) (string, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		if d, err = time.Parse("20060102", s); err != nil {
+			return "", fmt.Errorf("invalid date %q, want YYYY-MM-DD", s)
+		}
+	}
+	return d.Format("20060102") + hhmm, nil
+}
+
+// arxivGet fetches url after the throttle, reading at most limit bytes.
+func arxivGet(ctx context.Context, url string, timeout time.Duration, limit int64) ([]byte, error) {
+	if err := arxivThrottle.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "aiup-go-gen/1.0")
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("arXiv returned %s for %s", resp.Status, url)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("arXiv response larger than %d bytes", limit)
+	}
+	return body, nil
+}
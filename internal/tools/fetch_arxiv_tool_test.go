@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// arxivFixture reads a file of testdata/arxiv.
+func arxivFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "arxiv", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// arxivServer points the arXiv tools at a local server answering each path
+// of routes with its body, and everything else with 404. It returns the
+// requests the server got.
+func arxivServer(t *testing.T, routes map[string][]byte) func() []*url.URL {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []*url.URL
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reqs = append(reqs, r.URL)
+		mu.Unlock()
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	apiURL, baseURL, th := arxivAPIURL, arxivBaseURL, arxivThrottle
+	arxivAPIURL, arxivBaseURL, arxivThrottle = srv.URL+"/api/query", srv.URL, &throttle{}
+	t.Cleanup(func() {
+		srv.Close()
+		arxivAPIURL, arxivBaseURL, arxivThrottle = apiURL, baseURL, th
+	})
+	return func() []*url.URL {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*url.URL(nil), reqs...)
+	}
+}
+
+func TestFetchArxivSearch(t *testing.T) {
+	requests := arxivServer(t, map[string][]byte{"/api/query": arxivFixture(t, "feed.xml")})
+	tool := &FetchArxivTool{}
+	res := tool.Call(context.Background(), ToolCall{Args: map[string]interface{}{
+		"query":       "transformer",
+		"author":      "Ashish Vaswani",
+		"category":    "cs.CL",
+		"date_from":   "2017-01-01",
+		"date_to":     "2017-12-31",
+		"max_results": float64(2),
+		"sort_by":     "relevance",
+	}})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	q := reqs[0].Query()
+	wantQuery := `(all:transformer) AND au:"Ashish Vaswani" AND cat:cs.CL AND submittedDate:[201701010000 TO 201712312359]`
+	if got := q.Get("search_query"); got != wantQuery {
+		t.Errorf("search_query = %s\nwant %s", got, wantQuery)
+	}
+	for param, want := range map[string]string{"start": "0", "max_results": "2", "sortBy": "relevance", "sortOrder": "descending"} {
+		if got := q.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+
+	want := []map[string]string{
+		{
+			"ID":         "1706.03762v7",
+			"Title":      "Attention Is All You Need",
+			"Authors":    "Ashish Vaswani, Noam Shazeer",
+			"Summary":    "The dominant sequence transduction models are based on complex recurrent\nor convolutional neural networks.",
+			"URL":        "http://arxiv.org/abs/1706.03762v7",
+			"Published":  "2017-06-12T17:57:34Z",
+			"Updated":    "2023-08-02T00:41:18Z",
+			"Categories": "cs.CL, cs.LG",
+			"PDF":        "http://arxiv.org/pdf/1706.03762v7",
+		},
+		{
+			"ID":         "hep-th/9901001v1",
+			"Title":      "An Old-Style Identifier",
+			"Authors":    "A. Physicist",
+			"Summary":    "A paper with a pre-2007 identifier.",
+			"URL":        "http://arxiv.org/abs/hep-th/9901001v1",
+			"Published":  "1999-01-01T00:00:00Z",
+			"Updated":    "1999-01-01T00:00:00Z",
+			"Categories": "hep-th",
+		},
+	}
+	if !reflect.DeepEqual(res.Output, want) {
+		t.Errorf("papers:\n%v\nwant:\n%v", res.Output, want)
+	}
+}
+
+func TestFetchArxivErrors(t *testing.T) {
+	rejected := `<feed xmlns="http://www.w3.org/2005/Atom"><entry>
+<id>http://arxiv.org/api/errors#incorrect_id_format_for_1234</id>
+<summary>incorrect id format for 1234</summary>
+</entry></feed>`
+	arxivServer(t, map[string][]byte{"/api/query": []byte(rejected)})
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"no terms", map[string]interface{}{"sort_by": "relevance"}, "missing argument"},
+		{"bad category", map[string]interface{}{"category": "cs.AI OR x"}, `invalid category "cs.AI OR x"`},
+		{"bad date", map[string]interface{}{"query": "x", "date_from": "last week"}, `date_from: invalid date "last week"`},
+		{"bad sort", map[string]interface{}{"query": "x", "sort_by": "newest"}, `invalid sort_by "newest"`},
+		{"bad order", map[string]interface{}{"query": "x", "sort_order": "up"}, `invalid sort_order "up"`},
+		{"rejected by arXiv", map[string]interface{}{"query": "id:1234"}, "arXiv rejected the query: incorrect id format for 1234"},
+	}
+	tool := &FetchArxivTool{}
+	for _, tt := range tests {
+		res := tool.Call(context.Background(), ToolCall{Args: tt.args})
+		if res.Error == nil || !strings.Contains(res.Error.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, res.Error, tt.want)
+		}
+	}
+
+	arxivAPIURL = arxivBaseURL + "/missing"
+	res := tool.Call(context.Background(), ToolCall{Args: map[string]interface{}{"query": "x"}})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "arXiv returned 404 Not Found") {
+		t.Errorf("404: err = %v", res.Error)
+	}
+}
+
+func TestArxivThrottle(t *testing.T) {
+	th := &throttle{interval: 30 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := th.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 60*time.Millisecond {
+		t.Errorf("3 requests took %v, want them spaced 30ms apart", d)
+	}
+
+	th = &throttle{interval: time.Hour}
+	th.wait(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := th.wait(ctx); err != context.Canceled {
+		t.Errorf("wait = %v, want context.Canceled", err)
+	}
+}
@@ -2,18 +2,17 @@
 package tools
 
 import (
-    "bytes"
     "context"
     "encoding/json"
-    "fmt"
-    "io/ioutil"
-    "net/http"
+
+    "aiupstart.com/go-gen/internal/config"
 )
 
 type GenericMcpTool struct {
     NameStr        string // e.g. "stripe_mcp", "aws_mcp"
     Endpoint       string // e.g. "http://localhost:8080"
     DescriptionStr string // e.g. "Call Stripe MCP API..."
+    HTTP           *HTTPToolClient // auth, retry and TLS settings; nil = defaults
 }
 
 func (t *GenericMcpTool) Name() string        { return t.NameStr }
@@ -61,20 +60,17 @@ func (t *GenericMcpTool) Call(ctx context.Context, call ToolCall) ToolResult {
         }
     }
 
-    url := t.Endpoint + path
-    req, err := http.NewRequest(method, url, bytes.NewReader(bodyBytes))
-    if err != nil {
-        return ToolResult{Error: err}
+    client := t.HTTP
+    if client == nil {
+        var err error
+        if client, err = NewHTTPToolClient(config.McpToolConfig{Name: t.NameStr}, nil); err != nil {
+            return ToolResult{Error: err}
+        }
     }
-    req.Header.Set("Content-Type", "application/json")
-    resp, err := http.DefaultClient.Do(req)
+    url := t.Endpoint + path
+    resp, err := client.Do(ctx, method, url, bodyBytes, "application/json")
     if err != nil {
         return ToolResult{Error: err}
     }
-    defer resp.Body.Close()
-    data, _ := ioutil.ReadAll(resp.Body)
-    if resp.StatusCode < 200 || resp.StatusCode > 299 {
-        return ToolResult{Output: string(data), Error: fmt.Errorf("MCP call failed: %s", resp.Status)}
-    }
-    return ToolResult{Output: string(data)}
+    return httpToolResult(t.NameStr, method+" "+url, resp)
 }
\ No newline at end of file
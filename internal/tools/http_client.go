@@ -0,0 +1,355 @@
+// internal/tools/http_client.go
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+)
+
+// DefaultMaxResponseBytes caps HTTP tool responses so a large payload cannot
+// flood the LLM context.
+const DefaultMaxResponseBytes = 1 << 20
+
+var defaultRetryOn = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// SecretLookup resolves ${NAME} references in credentials and headers
+// (e.g. secrets.Store.Get). Nil falls back to the process environment.
+type SecretLookup func(name string) (string, bool)
+
+// HTTPToolClient sends requests for HTTP-backed tools with the auth, headers,
+// timeout, retry, TLS and response cap of one mcp_tools.yaml entry.
+type HTTPToolClient struct {
+	client   *http.Client
+	auth     *config.McpAuthConfig
+	headers  map[string]string
+	retry    config.McpRetryConfig
+	maxBytes int64
+	lookup   SecretLookup
+
+	mu          sync.Mutex
+	oauthToken  string
+	oauthExpiry time.Time
+}
+
+// HTTPToolResponse is a response whose body was read (up to the cap).
+type HTTPToolResponse struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	Truncated  bool
+}
+
+func NewHTTPToolClient(cfg config.McpToolConfig, lookup SecretLookup) (*HTTPToolClient, error) {
+	timeout := defaultHTTPToolTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLS != nil {
+		tlsCfg, err := buildTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Name, err)
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	c := &HTTPToolClient{
+		client:   &http.Client{Timeout: timeout, Transport: transport},
+		auth:     cfg.Auth,
+		headers:  cfg.Headers,
+		maxBytes: cfg.MaxResponseBytes,
+		lookup:   lookup,
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = DefaultMaxResponseBytes
+	}
+	if cfg.Retry != nil {
+		c.retry = *cfg.Retry
+	}
+	if c.retry.MaxAttempts <= 0 {
+		c.retry.MaxAttempts = 1
+	}
+	if c.retry.InitialBackoffMs <= 0 {
+		c.retry.InitialBackoffMs = 200
+	}
+	if c.retry.MaxBackoffMs <= 0 {
+		c.retry.MaxBackoffMs = 5000
+	}
+	if len(c.retry.RetryOn) == 0 {
+		c.retry.RetryOn = defaultRetryOn
+	}
+	if c.auth != nil {
+		switch c.auth.Type {
+		case "bearer", "basic", "api_key", "oauth2_client_credentials":
+		default:
+			return nil, fmt.Errorf("%s: unknown auth type %q", cfg.Name, c.auth.Type)
+		}
+	}
+	return c, nil
+}
+
+func buildTLSConfig(t *config.McpTLSConfig) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// expand resolves ${NAME} references through the secret lookup.
+func (c *HTTPToolClient) expand(s string) string {
+	return expandSecrets(s, c.lookup)
+}
+
+// Do sends a request, retrying per the retry policy. rawURL and body are
+// reused for every attempt. A 401 with OAuth2 refreshes the token once.
+func (c *HTTPToolClient) Do(ctx context.Context, method, rawURL string, body []byte, contentType string) (*HTTPToolResponse, error) {
+	retryable := c.retry.RetryNonIdempotent || isIdempotent(method)
+	refreshed := false
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		resp, err := c.once(ctx, method, rawURL, body, contentType)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && c.auth != nil &&
+			c.auth.Type == "oauth2_client_credentials" && !refreshed {
+			c.invalidateToken()
+			refreshed = true
+			attempt--
+			continue
+		}
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+		case c.shouldRetry(resp.StatusCode):
+			wait = retryAfter(resp.Header)
+			lastErr = fmt.Errorf("server returned %s", resp.Status)
+		default:
+			return resp, nil
+		}
+		if !retryable || attempt >= c.retry.MaxAttempts {
+			if err == nil {
+				return resp, nil
+			}
+			return nil, lastErr
+		}
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *HTTPToolClient) once(ctx context.Context, method, rawURL string, body []byte, contentType string) (*HTTPToolResponse, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, c.expand(v))
+	}
+	if err := c.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	out := &HTTPToolResponse{StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header, Body: data}
+	if int64(len(data)) > c.maxBytes {
+		out.Body, out.Truncated = data[:c.maxBytes], true
+	}
+	return out, nil
+}
+
+func (c *HTTPToolClient) authorize(ctx context.Context, req *http.Request) error {
+	a := c.auth
+	if a == nil {
+		return nil
+	}
+	switch a.Type {
+	case "bearer":
+		token := c.expand(a.Token)
+		if token == "" {
+			return errors.New("bearer token is empty (is the secret set?)")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "basic":
+		req.SetBasicAuth(c.expand(a.Username), c.expand(a.Password))
+	case "api_key":
+		name, value := a.Name, c.expand(a.Value)
+		if value == "" {
+			return errors.New("API key is empty (is the secret set?)")
+		}
+		if a.In == "query" {
+			if name == "" {
+				name = "api_key"
+			}
+			q := req.URL.Query()
+			q.Set(name, value)
+			req.URL.RawQuery = q.Encode()
+		} else {
+			if name == "" {
+				name = "X-API-Key"
+			}
+			req.Header.Set(name, value)
+		}
+	case "oauth2_client_credentials":
+		token, err := c.oauth2Token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// oauth2Token returns a cached client-credentials token, fetching a new one
+// shortly before it expires.
+func (c *HTTPToolClient) oauth2Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.oauthToken != "" && time.Now().Before(c.oauthExpiry) {
+		return c.oauthToken, nil
+	}
+	a := c.auth
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	if a.Audience != "" {
+		form.Set("audience", a.Audience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.expand(a.TokenURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.expand(a.ClientID)), url.QueryEscape(c.expand(a.ClientSecret)))
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth2 token request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oauth2 token request: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &tok); err != nil || tok.AccessToken == "" {
+		return "", fmt.Errorf("oauth2 token response has no access_token")
+	}
+	ttl := time.Duration(tok.ExpiresIn) * time.Second
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	c.oauthToken = tok.AccessToken
+	c.oauthExpiry = time.Now().Add(ttl - ttl/10)
+	return c.oauthToken, nil
+}
+
+func (c *HTTPToolClient) invalidateToken() {
+	c.mu.Lock()
+	c.oauthToken = ""
+	c.mu.Unlock()
+}
+
+func (c *HTTPToolClient) shouldRetry(status int) bool {
+	for _, s := range c.retry.RetryOn {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff is exponential with full jitter, capped at MaxBackoffMs.
+func (c *HTTPToolClient) backoff(attempt int) time.Duration {
+	d := time.Duration(c.retry.InitialBackoffMs) * time.Millisecond << (attempt - 1)
+	if max := time.Duration(c.retry.MaxBackoffMs) * time.Millisecond; d > max || d <= 0 {
+		d = max
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// retryAfter honours a Retry-After header given in seconds (capped at a minute).
+func retryAfter(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	if secs > 60 {
+		secs = 60
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
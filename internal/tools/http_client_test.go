@@ -0,0 +1,345 @@
+package tools
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+)
+
+func secrets(kv ...string) SecretLookup {
+	m := map[string]string{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		m[kv[i]] = kv[i+1]
+	}
+	return func(name string) (string, bool) {
+		v, ok := m[name]
+		return v, ok
+	}
+}
+
+func newTestClient(t *testing.T, cfg config.McpToolConfig, lookup SecretLookup) *HTTPToolClient {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = "test"
+	}
+	c, err := NewHTTPToolClient(cfg, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestHTTPClientAuth(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+	lookup := secrets("TOKEN", "s3cret", "USER", "alice", "PASS", "pw", "KEY", "k-1")
+
+	tests := []struct {
+		name  string
+		auth  *config.McpAuthConfig
+		check func(t *testing.T, r *http.Request)
+	}{
+		{"bearer", &config.McpAuthConfig{Type: "bearer", Token: "${TOKEN}"}, func(t *testing.T, r *http.Request) {
+			if h := r.Header.Get("Authorization"); h != "Bearer s3cret" {
+				t.Errorf("Authorization = %q", h)
+			}
+		}},
+		{"basic", &config.McpAuthConfig{Type: "basic", Username: "${USER}", Password: "${PASS}"}, func(t *testing.T, r *http.Request) {
+			if u, p, ok := r.BasicAuth(); !ok || u != "alice" || p != "pw" {
+				t.Errorf("basic auth = %q %q %v", u, p, ok)
+			}
+		}},
+		{"api_key header", &config.McpAuthConfig{Type: "api_key", Value: "${KEY}"}, func(t *testing.T, r *http.Request) {
+			if h := r.Header.Get("X-API-Key"); h != "k-1" {
+				t.Errorf("X-API-Key = %q", h)
+			}
+		}},
+		{"api_key named header", &config.McpAuthConfig{Type: "api_key", Name: "X-Token", Value: "${KEY}"}, func(t *testing.T, r *http.Request) {
+			if h := r.Header.Get("X-Token"); h != "k-1" {
+				t.Errorf("X-Token = %q", h)
+			}
+		}},
+		{"api_key query", &config.McpAuthConfig{Type: "api_key", In: "query", Name: "key", Value: "${KEY}"}, func(t *testing.T, r *http.Request) {
+			if q := r.URL.Query(); q.Get("key") != "k-1" || q.Get("page") != "2" {
+				t.Errorf("query = %q", r.URL.RawQuery)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			c := newTestClient(t, config.McpToolConfig{Auth: tt.auth, Headers: map[string]string{"X-Trace": "${USER}-1"}}, lookup)
+			resp, err := c.Do(context.Background(), http.MethodGet, srv.URL+"/items?page=2", nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %s", resp.Status)
+			}
+			if h := got.Header.Get("X-Trace"); h != "alice-1" {
+				t.Errorf("X-Trace = %q, want the expanded header", h)
+			}
+			tt.check(t, got)
+		})
+	}
+
+	t.Run("missing secret", func(t *testing.T) {
+		c := newTestClient(t, config.McpToolConfig{Auth: &config.McpAuthConfig{Type: "bearer", Token: "${UNSET}"}}, lookup)
+		if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, ""); err == nil || !strings.Contains(err.Error(), "bearer token is empty") {
+			t.Fatalf("err = %v, want an empty token error", err)
+		}
+	})
+	t.Run("unknown type", func(t *testing.T) {
+		if _, err := NewHTTPToolClient(config.McpToolConfig{Name: "x", Auth: &config.McpAuthConfig{Type: "digest"}}, lookup); err == nil {
+			t.Fatal("unknown auth type accepted")
+		}
+	})
+}
+
+func TestHTTPClientRetry(t *testing.T) {
+	t.Run("backoff until success", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		}))
+		defer srv.Close()
+		c := newTestClient(t, config.McpToolConfig{Retry: &config.McpRetryConfig{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 5}}, nil)
+		resp, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(resp.Body) != "ok" || calls != 3 {
+			t.Fatalf("status %d body %q after %d calls, want 200 ok after 3", resp.StatusCode, resp.Body, calls)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		c := newTestClient(t, config.McpToolConfig{Retry: &config.McpRetryConfig{MaxAttempts: 2, InitialBackoffMs: 1}}, nil)
+		resp, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable || calls != 2 {
+			t.Fatalf("status %d after %d calls, want the last 503 after 2", resp.StatusCode, calls)
+		}
+	})
+
+	t.Run("honours Retry-After", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		}))
+		defer srv.Close()
+		c := newTestClient(t, config.McpToolConfig{Retry: &config.McpRetryConfig{MaxAttempts: 2, InitialBackoffMs: 1, MaxBackoffMs: 1}}, nil)
+		start := time.Now()
+		resp, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+		if d := time.Since(start); d < time.Second {
+			t.Errorf("retried after %v, want the 1s of Retry-After", d)
+		}
+	})
+
+	t.Run("POST is not retried by default", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+		c := newTestClient(t, config.McpToolConfig{Retry: &config.McpRetryConfig{MaxAttempts: 3, InitialBackoffMs: 1}}, nil)
+		if _, err := c.Do(context.Background(), http.MethodPost, srv.URL, []byte(`{}`), "application/json"); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 1 {
+			t.Fatalf("%d calls, want 1", calls)
+		}
+	})
+
+	t.Run("context cancels the wait", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		c := newTestClient(t, config.McpToolConfig{Retry: &config.McpRetryConfig{MaxAttempts: 3}}, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, err := c.Do(ctx, http.MethodGet, srv.URL, nil, ""); err != context.DeadlineExceeded {
+			t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestHTTPClientResponseCap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("x", 100))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, config.McpToolConfig{MaxResponseBytes: 10}, nil)
+	resp, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Truncated || len(resp.Body) != 10 {
+		t.Fatalf("truncated %v, %d bytes; want 10 truncated bytes", resp.Truncated, len(resp.Body))
+	}
+
+	c = newTestClient(t, config.McpToolConfig{MaxResponseBytes: 100}, nil)
+	if resp, err = c.Do(context.Background(), http.MethodGet, srv.URL, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Truncated || len(resp.Body) != 100 {
+		t.Fatalf("truncated %v, %d bytes; want the whole 100 bytes", resp.Truncated, len(resp.Body))
+	}
+}
+
+func TestHTTPClientOAuth2(t *testing.T) {
+	var issued, current int32 // token numbers: issued last, accepted by the API
+	token := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if err := r.ParseForm(); err != nil || id != "cid" || secret != "csecret" ||
+			r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "read write" {
+			http.Error(w, "bad token request", http.StatusBadRequest)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		fmt.Fprintf(w, `{"access_token":"t%d","expires_in":3600}`, n)
+	}))
+	defer token.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer t%d", atomic.LoadInt32(&current)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer api.Close()
+
+	c := newTestClient(t, config.McpToolConfig{Auth: &config.McpAuthConfig{
+		Type:         "oauth2_client_credentials",
+		TokenURL:     token.URL,
+		ClientID:     "${CLIENT_ID}",
+		ClientSecret: "csecret",
+		Scopes:       []string{"read", "write"},
+	}}, secrets("CLIENT_ID", "cid"))
+	get := func() *HTTPToolResponse {
+		t.Helper()
+		resp, err := c.Do(context.Background(), http.MethodGet, api.URL, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	atomic.StoreInt32(&current, 1)
+	if resp := get(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("first call: %s", resp.Status)
+	}
+	if resp := get(); resp.StatusCode != http.StatusOK || issued != 1 {
+		t.Fatalf("second call: %s with %d tokens issued, want the cached token", resp.Status, issued)
+	}
+
+	// The server revokes t1: the 401 refreshes the token once.
+	atomic.StoreInt32(&current, 2)
+	if resp := get(); resp.StatusCode != http.StatusOK || issued != 2 {
+		t.Fatalf("after revocation: %s with %d tokens issued, want a refreshed token", resp.Status, issued)
+	}
+
+	// A token the server never accepts is refreshed once, then the 401 is returned.
+	atomic.StoreInt32(&current, 99)
+	if resp := get(); resp.StatusCode != http.StatusUnauthorized || issued != 3 {
+		t.Fatalf("rejected token: %s with %d tokens issued, want 401 after one refresh", resp.Status, issued)
+	}
+}
+
+func TestHTTPClientCustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("system roots reject the test CA", func(t *testing.T) {
+		c := newTestClient(t, config.McpToolConfig{}, nil)
+		if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, ""); err == nil {
+			t.Fatal("request to a server with an unknown CA succeeded")
+		}
+	})
+	t.Run("ca_file", func(t *testing.T) {
+		c := newTestClient(t, config.McpToolConfig{TLS: &config.McpTLSConfig{CAFile: caFile}}, nil)
+		resp, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.Body) != "secure" {
+			t.Fatalf("body = %q", resp.Body)
+		}
+	})
+	t.Run("server_name must match the certificate", func(t *testing.T) {
+		c := newTestClient(t, config.McpToolConfig{TLS: &config.McpTLSConfig{CAFile: caFile, ServerName: "other.test"}}, nil)
+		if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, ""); err == nil {
+			t.Fatal("request with a mismatched server_name succeeded")
+		}
+	})
+	t.Run("insecure_skip_verify", func(t *testing.T) {
+		c := newTestClient(t, config.McpToolConfig{TLS: &config.McpTLSConfig{InsecureSkipVerify: true}}, nil)
+		if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("unreadable ca_file", func(t *testing.T) {
+		_, err := NewHTTPToolClient(config.McpToolConfig{Name: "x", TLS: &config.McpTLSConfig{CAFile: filepath.Join(dir, "missing.pem")}}, nil)
+		if err == nil || !strings.Contains(err.Error(), "reading CA file") {
+			t.Fatalf("err = %v, want a CA file error", err)
+		}
+	})
+	t.Run("ca_file without certificates", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty.pem")
+		if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewHTTPToolClient(config.McpToolConfig{Name: "x", TLS: &config.McpTLSConfig{CAFile: empty}}, nil); err == nil {
+			t.Fatal("CA file without certificates accepted")
+		}
+	})
+}
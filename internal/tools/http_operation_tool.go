@@ -0,0 +1,269 @@
+// internal/tools/http_operation_tool.go
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+const defaultHTTPToolTimeout = 30 * time.Second
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// HttpOperationTool calls one REST operation declared in mcp_tools.yaml.
+// Arguments named in the path template ({id}) are substituted into the path;
+// the rest go to the query string for GET/DELETE/HEAD and to a JSON body
+// otherwise. Operations may name query arguments explicitly (query_params)
+// and an argument holding the whole body (body_param).
+type HttpOperationTool struct {
+	name        string
+	description string
+	endpoint    string
+	path        string
+	method      string
+	params      map[string]interface{}
+	queryParams []string
+	bodyParam   string
+	client      *HTTPToolClient
+	metadata    ToolMetadata
+}
+
+func NewHttpOperationTool(client *HTTPToolClient, endpoint string, op config.McpToolOperation) *HttpOperationTool {
+	method := strings.ToUpper(op.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	return &HttpOperationTool{
+		name:        op.Name,
+		description: op.Description,
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		path:        op.Path,
+		method:      method,
+		params:      op.Parameters,
+		queryParams: op.QueryParams,
+		bodyParam:   op.BodyParam,
+		client:      client,
+	}
+}
+
+// RegisterHttpOperations registers a tool for every operation of every
+// HTTP mcp_tools entry and returns their names. Builtin entries (e.g.
+// docker_exec) only describe tools implemented in code. Entries with an
+// unusable HTTP configuration (e.g. a missing CA file) are skipped and
+// reported in the returned error.
+func RegisterHttpOperations(cfg *config.McpConfig, registry *ToolRegistry, lookup SecretLookup) ([]string, error) {
+	var names []string
+	var errs []error
+	for _, t := range cfg.McpTools {
+		if t.Builtin || t.Endpoint == "" {
+			continue
+		}
+		client, err := NewHTTPToolClient(t, lookup)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, op := range t.Operations {
+			if op.Name == "" || op.Path == "" {
+				utils.Logger.Warn().Str("tool", t.Name).Str("operation", op.Name).Msg("Skipping operation without name or path")
+				continue
+			}
+			if registry.HasTool(op.Name) {
+				utils.Logger.Warn().Str("tool", t.Name).Str("operation", op.Name).Msg("Operation name already registered; replacing it")
+			}
+			tool := NewHttpOperationTool(client, t.Endpoint, op)
+			tool.metadata = ToolMetadata{Executor: t.Executor, RequiresApproval: t.RequiresApproval}
+			registry.Register(tool)
+			names = append(names, op.Name)
+		}
+	}
+	return names, errors.Join(errs...)
+}
+
+func (t *HttpOperationTool) Name() string        { return t.name }
+func (t *HttpOperationTool) Description() string { return t.description }
+
+// Metadata carries the routing settings of the tool's mcp_tools entry.
+func (t *HttpOperationTool) Metadata() ToolMetadata { return t.metadata }
+
+func (t *HttpOperationTool) Parameters() map[string]interface{} {
+	if t.params != nil {
+		return t.params
+	}
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (t *HttpOperationTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	u, body, err := t.buildRequest(call.Args)
+	if err != nil {
+		return ToolResult{
+			Error:       err,
+			ErrorDetail: &ExecErrorDetail{Phase: "args", Command: t.method + " " + t.path, ErrMsg: err.Error()},
+		}
+	}
+	contentType := ""
+	if body != nil {
+		contentType = "application/json"
+	}
+	target := t.method + " " + u
+	resp, err := t.client.Do(ctx, t.method, u, body, contentType)
+	if err != nil {
+		return ToolResult{
+			Error:       err,
+			ErrorDetail: &ExecErrorDetail{Phase: "http", Command: target, ErrMsg: err.Error()},
+		}
+	}
+	return httpToolResult(t.name, target, resp)
+}
+
+// httpToolResult turns a response into a ToolResult; non-2xx is an error.
+func httpToolResult(name, target string, resp *HTTPToolResponse) ToolResult {
+	out := string(resp.Body)
+	if resp.Truncated {
+		out += fmt.Sprintf("\n[truncated: response exceeded %d bytes]", len(resp.Body))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ToolResult{
+			Output:      out,
+			Error:       fmt.Errorf("%s failed: %s", name, resp.Status),
+			ErrorDetail: &ExecErrorDetail{Phase: "http", Command: target, Output: out, ErrMsg: resp.Status},
+		}
+	}
+	return ToolResult{Output: out}
+}
+
+// buildRequest validates required arguments, fills the path template and
+// encodes the remaining arguments into the URL or a JSON body.
+func (t *HttpOperationTool) buildRequest(args map[string]interface{}) (string, []byte, error) {
+	rest := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		rest[k] = v
+	}
+	if missing := missingRequired(t.params, rest); len(missing) > 0 {
+		return "", nil, fmt.Errorf("%s: missing required argument(s): %s", t.name, strings.Join(missing, ", "))
+	}
+
+	var pathErr error
+	path := pathParamPattern.ReplaceAllStringFunc(t.path, func(m string) string {
+		key := m[1 : len(m)-1]
+		v, ok := rest[key]
+		if !ok || v == nil {
+			pathErr = fmt.Errorf("%s: missing path argument %q", t.name, key)
+			return m
+		}
+		delete(rest, key)
+		return url.PathEscape(argString(v))
+	})
+	if pathErr != nil {
+		return "", nil, pathErr
+	}
+
+	queryArgs := map[string]interface{}{}
+	switch t.method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		queryArgs, rest = rest, nil
+	default:
+		for _, k := range t.queryParams {
+			if v, ok := rest[k]; ok {
+				queryArgs[k] = v
+				delete(rest, k)
+			}
+		}
+	}
+	query := url.Values{}
+	keys := make([]string, 0, len(queryArgs))
+	for k := range queryArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if list, ok := queryArgs[k].([]interface{}); ok {
+			for _, item := range list {
+				query.Add(k, argString(item))
+			}
+			continue
+		}
+		query.Set(k, argString(queryArgs[k]))
+	}
+
+	var body []byte
+	if rest != nil {
+		var payload interface{} = rest
+		if t.bodyParam != "" {
+			payload = rest[t.bodyParam]
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return "", nil, err
+		}
+		body = data
+	}
+
+	u := t.endpoint + path
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + query.Encode()
+	}
+	return u, body, nil
+}
+
+// missingRequired lists schema "required" properties absent from args.
+func missingRequired(schema map[string]interface{}, args map[string]interface{}) []string {
+	var missing []string
+	switch req := schema["required"].(type) {
+	case []interface{}:
+		for _, r := range req {
+			if name, ok := r.(string); ok {
+				if _, present := args[name]; !present {
+					missing = append(missing, name)
+				}
+			}
+		}
+	case []string:
+		for _, name := range req {
+			if _, present := args[name]; !present {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing
+}
+
+// argString renders a scalar argument for a path or query string; JSON
+// numbers that are whole print without an exponent, objects as JSON.
+func argString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+			return strconv.FormatInt(int64(x), 10)
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool, int, int64:
+		return fmt.Sprint(x)
+	case nil:
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
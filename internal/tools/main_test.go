@@ -0,0 +1,21 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	// Keep test runs from writing run.log next to the package.
+	if err := utils.ConfigureLogger("error", "", false); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	if echoPluginPath != "" {
+		os.RemoveAll(filepath.Dir(echoPluginPath))
+	}
+	os.Exit(code)
+}
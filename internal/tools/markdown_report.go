@@ -0,0 +1,461 @@
+// internal/tools/markdown_report.go
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"aiupstart.com/go-gen/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reportWorkspaceDir is where reports go inside a session workspace.
+const reportWorkspaceDir = "reports"
+
+// MarkdownReportTool renders a structured report (sections, tables and
+// citations) to a Markdown file, and optionally an HTML copy. Reports go to
+// reports/ in the docker_exec session workspace when there is one, so they
+// are exported with it, and to the output directory otherwise.
+type MarkdownReportTool struct {
+	exec      *DockerExecTool // may be nil
+	outputDir string
+	now       func() time.Time
+}
+
+func NewMarkdownReportTool(exec *DockerExecTool, outputDir string) *MarkdownReportTool {
+	if outputDir == "" {
+		outputDir = "reports"
+	}
+	return &MarkdownReportTool{exec: exec, outputDir: outputDir, now: time.Now}
+}
+
+// report is the tool's argument object.
+type report struct {
+	Title     string           `json:"title"`
+	Summary   string           `json:"summary"`
+	Sections  []reportSection  `json:"sections"`
+	Citations []reportCitation `json:"citations"`
+	Filename  string           `json:"filename"`
+	HTML      bool             `json:"html"`
+}
+
+type reportSection struct {
+	Heading string        `json:"heading"`
+	Level   int           `json:"level"`
+	Content string        `json:"content"`
+	Tables  []reportTable `json:"tables"`
+}
+
+type reportTable struct {
+	Caption string         `json:"caption"`
+	Columns []flexString   `json:"columns"`
+	Rows    [][]flexString `json:"rows"`
+}
+
+type reportCitation struct {
+	Title   string     `json:"title"`
+	Authors []string   `json:"authors"`
+	Year    flexString `json:"year"`
+	Venue   string     `json:"venue"`
+	URL     string     `json:"url"`
+	Note    string     `json:"note"`
+}
+
+// flexString accepts JSON strings, numbers and booleans (models send table
+// cells and years either way).
+type flexString string
+
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = flexString(str)
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v == nil {
+		*s = ""
+		return nil
+	}
+	*s = flexString(fmt.Sprint(v))
+	return nil
+}
+
+func (t *MarkdownReportTool) Name() string { return "markdown_report" }
+func (t *MarkdownReportTool) Description() string {
+	return "Write a report (sections, tables and numbered citations) to a Markdown file in the session workspace (reports/) or the output directory, optionally with an HTML copy. Cite sources in the text as [1], [2] ... in the order of citations."
+}
+func (t *MarkdownReportTool) Parameters() map[string]interface{} {
+	str := func(desc string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": desc}
+	}
+	list := func(desc string) map[string]interface{} {
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": desc}
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title":   str("Report title"),
+			"summary": str("Short summary shown under the title (Markdown)"),
+			"sections": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"heading": str("Section heading"),
+						"level":   map[string]interface{}{"type": "integer", "description": "Heading level 2-4 (default 2)"},
+						"content": str("Section body (Markdown)"),
+						"tables": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"caption": str("Table caption"),
+									"columns": list("Column headers"),
+									"rows": map[string]interface{}{
+										"type":        "array",
+										"items":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+										"description": "Rows of cells, in column order",
+									},
+								},
+								"required": []string{"columns", "rows"},
+							},
+						},
+					},
+					"required": []string{"heading"},
+				},
+			},
+			"citations": map[string]interface{}{
+				"type":        "array",
+				"description": "Sources, numbered from 1 in this order",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":   str("Title of the source"),
+						"authors": list("Authors"),
+						"year":    str("Publication year"),
+						"venue":   str("Journal, conference or archive (e.g. arXiv:2401.01234)"),
+						"url":     str("Link to the source"),
+						"note":    str("Why the source matters"),
+					},
+					"required": []string{"title"},
+				},
+			},
+			"filename": str("File name relative to the report directory (default: from the title); .md is added if missing"),
+			"html":     map[string]interface{}{"type": "boolean", "description": "Also write an HTML copy next to the Markdown file"},
+		},
+		"required": []string{"title", "sections"},
+	}
+}
+
+func (t *MarkdownReportTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	r, err := parseReport(call.Args)
+	if err != nil {
+		metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+		return ToolResult{Error: err}
+	}
+	now := t.now()
+	files := []struct{ name, content string }{{r.Filename, r.markdown(now)}}
+	if r.HTML {
+		files = append(files, struct{ name, content string }{r.Filename[:len(r.Filename)-len(".md")] + ".html", r.html(now)})
+	}
+
+	var written []string
+	for _, f := range files {
+		shown, err := t.write(f.name, f.content)
+		if err != nil {
+			metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+			return ToolResult{Error: fmt.Errorf("writing %s: %w", f.name, err)}
+		}
+		written = append(written, fmt.Sprintf("%s (%d bytes)", shown, len(f.content)))
+	}
+	return ToolResult{Output: fmt.Sprintf("Report %q written to %s", r.Title, strings.Join(written, " and "))}
+}
+
+// write stores one file and returns the path to show the model.
+func (t *MarkdownReportTool) write(name, content string) (string, error) {
+	var w *WorkspaceWriter
+	shown := ""
+	if t.exec != nil {
+		if ws := t.exec.Workspace(); ws != "" {
+			w = NewWorkspaceWriter(ws)
+			name = path.Join(reportWorkspaceDir, name)
+			shown = "/workspace/" + name
+		}
+	}
+	if w == nil {
+		if err := os.MkdirAll(t.outputDir, 0o755); err != nil {
+			return "", err
+		}
+		w = NewWorkspaceWriter(t.outputDir)
+		shown = filepath.Join(t.outputDir, filepath.FromSlash(name))
+	}
+	return shown, w.WriteFile(name, []byte(content))
+}
+
+func parseReport(args map[string]interface{}) (*report, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	var r report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid report arguments: %w", err)
+	}
+	r.Title = strings.TrimSpace(r.Title)
+	if r.Title == "" {
+		return nil, fmt.Errorf("missing argument: title")
+	}
+	if len(r.Sections) == 0 && strings.TrimSpace(r.Summary) == "" {
+		return nil, fmt.Errorf("the report is empty: add sections")
+	}
+	for i, s := range r.Sections {
+		if strings.TrimSpace(s.Heading) == "" {
+			return nil, fmt.Errorf("sections[%d]: missing heading", i)
+		}
+		if s.Level < 2 || s.Level > 4 {
+			r.Sections[i].Level = 2
+		}
+		for j, tb := range s.Tables {
+			if len(tb.Columns) == 0 {
+				return nil, fmt.Errorf("sections[%d].tables[%d]: missing columns", i, j)
+			}
+			for k, row := range tb.Rows {
+				if len(row) > len(tb.Columns) {
+					return nil, fmt.Errorf("sections[%d].tables[%d].rows[%d]: %d cells for %d columns", i, j, k, len(row), len(tb.Columns))
+				}
+			}
+		}
+	}
+	for i, c := range r.Citations {
+		if strings.TrimSpace(c.Title) == "" {
+			return nil, fmt.Errorf("citations[%d]: missing title", i)
+		}
+	}
+	if r.Filename == "" {
+		r.Filename = slugify(r.Title)
+	}
+	if !strings.HasSuffix(strings.ToLower(r.Filename), ".md") {
+		r.Filename += ".md"
+	}
+	if _, err := NormalizeWorkspacePath(r.Filename); err != nil {
+		return nil, fmt.Errorf("filename %q: %w", r.Filename, err)
+	}
+	return &r, nil
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(title string) string {
+	slug := strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "report"
+	}
+	return slug
+}
+
+// ---- Markdown ----
+
+func (r *report) markdown(now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_Generated %s_\n\n", r.Title, now.Format("2006-01-02"))
+	if s := strings.TrimSpace(r.Summary); s != "" {
+		b.WriteString(s + "\n\n")
+	}
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "%s %s\n\n", strings.Repeat("#", s.Level), s.Heading)
+		if c := strings.TrimSpace(s.Content); c != "" {
+			b.WriteString(c + "\n\n")
+		}
+		for _, tb := range s.Tables {
+			if tb.Caption != "" {
+				fmt.Fprintf(&b, "**%s**\n\n", tb.Caption)
+			}
+			b.WriteString(markdownRow(tb.Columns))
+			b.WriteString("|" + strings.Repeat(" --- |", len(tb.Columns)) + "\n")
+			for _, row := range tb.Rows {
+				b.WriteString(markdownRow(padRow(row, len(tb.Columns))))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(r.Citations) > 0 {
+		b.WriteString("## References\n\n")
+		for i, c := range r.Citations {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, c.markdown())
+		}
+	}
+	return b.String()
+}
+
+func markdownRow(cells []flexString) string {
+	var b strings.Builder
+	b.WriteString("|")
+	for _, c := range cells {
+		cell := strings.ReplaceAll(string(c), "|", `\|`)
+		cell = strings.ReplaceAll(strings.TrimSpace(cell), "\n", "<br>")
+		b.WriteString(" " + cell + " |")
+	}
+	return b.String() + "\n"
+}
+
+func padRow(row []flexString, n int) []flexString {
+	for len(row) < n {
+		row = append(row, "")
+	}
+	return row
+}
+
+func (c reportCitation) markdown() string {
+	var parts []string
+	if len(c.Authors) > 0 {
+		parts = append(parts, strings.Join(c.Authors, ", "))
+	}
+	if c.Year != "" {
+		parts = append(parts, "("+string(c.Year)+")")
+	}
+	s := strings.Join(append(parts, "*"+c.Title+"*."), " ")
+	if c.Venue != "" {
+		s += " " + c.Venue + "."
+	}
+	if c.URL != "" {
+		s += " <" + c.URL + ">"
+	}
+	if c.Note != "" {
+		s += " — " + c.Note
+	}
+	return s
+}
+
+// ---- HTML ----
+
+const reportCSS = `body{font-family:system-ui,sans-serif;max-width:50rem;margin:2rem auto;padding:0 1rem;line-height:1.5}
+table{border-collapse:collapse;margin:1rem 0}th,td{border:1px solid #ccc;padding:.3rem .6rem;text-align:left}
+caption{font-weight:bold;text-align:left;padding-bottom:.3rem}code{background:#f4f4f4;padding:0 .2rem}.generated{color:#666}`
+
+// html renders the report directly from its structure. Section text is
+// Markdown; paragraphs, bullet lists, links, bold, italics, inline code and
+// [n] citation markers are converted, anything else is shown as text.
+func (r *report) html(now time.Time) string {
+	var b strings.Builder
+	title := html.EscapeString(r.Title)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", title, reportCSS)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p class=\"generated\">Generated %s</p>\n", title, now.Format("2006-01-02"))
+	b.WriteString(r.htmlBlocks(r.Summary))
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "<h%d>%s</h%d>\n", s.Level, r.htmlInline(s.Heading), s.Level)
+		b.WriteString(r.htmlBlocks(s.Content))
+		for _, tb := range s.Tables {
+			b.WriteString("<table>\n")
+			if tb.Caption != "" {
+				fmt.Fprintf(&b, "<caption>%s</caption>\n", r.htmlInline(tb.Caption))
+			}
+			b.WriteString("<tr>")
+			for _, c := range tb.Columns {
+				fmt.Fprintf(&b, "<th>%s</th>", r.htmlInline(string(c)))
+			}
+			b.WriteString("</tr>\n")
+			for _, row := range tb.Rows {
+				b.WriteString("<tr>")
+				for _, c := range padRow(row, len(tb.Columns)) {
+					fmt.Fprintf(&b, "<td>%s</td>", r.htmlInline(string(c)))
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</table>\n")
+		}
+	}
+	if len(r.Citations) > 0 {
+		b.WriteString("<h2>References</h2>\n<ol>\n")
+		for i, c := range r.Citations {
+			fmt.Fprintf(&b, "<li id=\"ref-%d\">%s</li>\n", i+1, r.htmlInline(c.markdown()))
+		}
+		b.WriteString("</ol>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// htmlBlocks converts paragraphs and "-"/"*" bullet lists.
+func (r *report) htmlBlocks(text string) string {
+	var b strings.Builder
+	for _, block := range mdParagraph.Split(strings.TrimSpace(text), -1) {
+		if block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		list := true
+		for _, l := range lines {
+			l = strings.TrimSpace(l)
+			if !strings.HasPrefix(l, "- ") && !strings.HasPrefix(l, "* ") {
+				list = false
+				break
+			}
+		}
+		if list {
+			b.WriteString("<ul>\n")
+			for _, l := range lines {
+				fmt.Fprintf(&b, "<li>%s</li>\n", r.htmlInline(strings.TrimSpace(l)[2:]))
+			}
+			b.WriteString("</ul>\n")
+			continue
+		}
+		fmt.Fprintf(&b, "<p>%s</p>\n", r.htmlInline(block))
+	}
+	return b.String()
+}
+
+var (
+	mdParagraph = regexp.MustCompile(`\n\s*\n`)
+	mdCode      = regexp.MustCompile("`([^`]+)`")
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	mdAutolink  = regexp.MustCompile(`&lt;(https?://[^\s&]+)&gt;`)
+	mdBold      = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItalic    = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdCite      = regexp.MustCompile(`\[(\d+)\]`)
+)
+
+// htmlInline escapes text and converts inline Markdown. Code spans are
+// swapped out first so their content is left as is.
+func (r *report) htmlInline(text string) string {
+	s := html.EscapeString(text)
+	var codes []string
+	s = mdCode.ReplaceAllStringFunc(s, func(m string) string {
+		codes = append(codes, "<code>"+mdCode.FindStringSubmatch(m)[1]+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+	s = mdLink.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = mdAutolink.ReplaceAllString(s, `<a href="$1">$1</a>`)
+	s = mdBold.ReplaceAllString(s, "<strong>$1</strong>")
+	s = mdItalic.ReplaceAllString(s, "<em>$1</em>")
+	s = mdCite.ReplaceAllStringFunc(s, func(m string) string {
+		var n int
+		fmt.Sscanf(m, "[%d]", &n)
+		if n < 1 || n > len(r.Citations) {
+			return m
+		}
+		return fmt.Sprintf(`<a href="#ref-%d">[%d]</a>`, n, n)
+	})
+	s = strings.ReplaceAll(s, "\n", "<br>\n")
+	for i, c := range codes {
+		s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), c, 1)
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// sampleReport exercises every part of the rendering: summary, section
+// levels, tables with numeric and short rows, citations and inline Markdown.
+func sampleReport() map[string]interface{} {
+	return map[string]interface{}{
+		"title":   "Sparse Attention <Survey>",
+		"summary": "Sparse attention cuts the **quadratic** cost of transformers [1].",
+		"sections": []interface{}{
+			map[string]interface{}{
+				"heading": "Methods",
+				"content": "Two families:\n\n- fixed patterns [1]\n- learned routing [2]\n\nSee `attn_mask` and [the code](https://example.com/code).",
+				"tables": []interface{}{
+					map[string]interface{}{
+						"caption": "Results",
+						"columns": []interface{}{"Model", "Params", "Note"},
+						"rows": []interface{}{
+							[]interface{}{"Longformer", 149, "a|b"},
+							[]interface{}{"BigBird", "128M"},
+						},
+					},
+				},
+			},
+			map[string]interface{}{"heading": "Limits", "level": 3, "content": "Only *some* tasks [3] benefit."},
+			map[string]interface{}{"heading": "Bad level", "level": 9},
+		},
+		"citations": []interface{}{
+			map[string]interface{}{"title": "Longformer", "authors": []interface{}{"I. Beltagy", "M. Peters"}, "year": 2020, "venue": "arXiv:2004.05150", "url": "https://arxiv.org/abs/2004.05150"},
+			map[string]interface{}{"title": "Routing Transformers", "note": "learned clusters"},
+		},
+		"html": true,
+	}
+}
+
+// checkGolden compares got with testdata/report/name, rewriting it with
+// -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	file := filepath.Join("testdata", "report", name)
+	if *updateGolden {
+		if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file (go test -run %s -update to accept):\n%s", name, t.Name(), got)
+	}
+}
+
+func TestMarkdownReportGolden(t *testing.T) {
+	exec := workspaceExec(t, nil)
+	tool := NewMarkdownReportTool(exec, t.TempDir())
+	tool.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	res := tool.Call(context.Background(), ToolCall{Args: sampleReport()})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	for _, name := range []string{"sparse-attention-survey.md", "sparse-attention-survey.html"} {
+		data, err := os.ReadFile(filepath.Join(exec.Workspace(), reportWorkspaceDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, name, string(data))
+	}
+	want := `Report "Sparse Attention <Survey>" written to /workspace/reports/sparse-attention-survey.md (`
+	if out, _ := res.Output.(string); !strings.HasPrefix(out, want) || !strings.Contains(out, " and /workspace/reports/sparse-attention-survey.html (") {
+		t.Errorf("output = %q", res.Output)
+	}
+}
+
+func TestMarkdownReportWrite(t *testing.T) {
+	// Without a session workspace reports go to the output directory.
+	dir := filepath.Join(t.TempDir(), "out")
+	tool := NewMarkdownReportTool(nil, dir)
+	res := tool.Call(context.Background(), ToolCall{Args: map[string]interface{}{
+		"title":    "Notes",
+		"filename": "2024/notes",
+		"sections": []interface{}{map[string]interface{}{"heading": "One"}},
+	}})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024", "notes.md")); err != nil {
+		t.Errorf("report not in the output directory: %v", err)
+	}
+
+	// Writes go through WorkspaceWriter, which refuses symlinks.
+	root, outside := symlinkedWorkspace(t)
+	if err := os.Symlink(outside, filepath.Join(root, reportWorkspaceDir)); err != nil {
+		t.Fatal(err)
+	}
+	exec := NewDockerExecTool("test", "")
+	exec.workspace = root
+	res = NewMarkdownReportTool(exec, "").Call(context.Background(), ToolCall{Args: map[string]interface{}{
+		"title":    "Leak",
+		"sections": []interface{}{map[string]interface{}{"heading": "One"}},
+	}})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "symlink") {
+		t.Errorf("err = %v, want a symlink rejection", res.Error)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "leak.md")); !os.IsNotExist(err) {
+		t.Errorf("report written through the symlink: %v", err)
+	}
+}
+
+func TestMarkdownReportInvalid(t *testing.T) {
+	section := map[string]interface{}{"heading": "One"}
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"sections": []interface{}{section}}, "missing argument: title"},
+		{map[string]interface{}{"title": "Empty"}, "the report is empty"},
+		{map[string]interface{}{"title": "T", "sections": []interface{}{map[string]interface{}{"heading": " "}}}, "sections[0]: missing heading"},
+		{map[string]interface{}{"title": "T", "sections": []interface{}{map[string]interface{}{"heading": "H", "tables": []interface{}{
+			map[string]interface{}{"rows": []interface{}{}},
+		}}}}, "sections[0].tables[0]: missing columns"},
+		{map[string]interface{}{"title": "T", "sections": []interface{}{map[string]interface{}{"heading": "H", "tables": []interface{}{
+			map[string]interface{}{"columns": []interface{}{"a"}, "rows": []interface{}{[]interface{}{"1", "2"}}},
+		}}}}, "sections[0].tables[0].rows[0]: 2 cells for 1 columns"},
+		{map[string]interface{}{"title": "T", "sections": []interface{}{section}, "citations": []interface{}{map[string]interface{}{"url": "x"}}}, "citations[0]: missing title"},
+		{map[string]interface{}{"title": "T", "sections": []interface{}{section}, "filename": "../escape"}, "escapes the workspace"},
+	}
+	tool := NewMarkdownReportTool(nil, t.TempDir())
+	for _, tt := range tests {
+		res := tool.Call(context.Background(), ToolCall{Args: tt.args})
+		if res.Error == nil || !strings.Contains(res.Error.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.args, res.Error, tt.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// internal/tools/mcp_client.go
+package tools
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// McpToolNamePrefix starts the registry name of every tool discovered on an
+// MCP server: "mcp__<server>__<tool>".
+const McpToolNamePrefix = "mcp__"
+
+const defaultMcpTimeout = 60 * time.Second
+
+// maxToolNameLen is OpenAI's limit for function names.
+const maxToolNameLen = 64
+
+var toolNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// McpClient is a connection to one MCP server.
+type McpClient struct {
+	cfg       config.McpServerConfig
+	transport mcpTransport
+	timeout   time.Duration
+	nextID    atomic.Int64
+
+	ServerInfo      McpImplementation
+	ProtocolVersion string
+	Instructions    string
+}
+
+// ConnectMcpServer starts or dials the server described by cfg and performs
+// the initialize handshake. ${NAME} references in the command, url, env and
+// headers are resolved with lookup (nil: environment). A stdio server gets
+// only PATH, HOME and TMPDIR from our environment besides its env.
+func ConnectMcpServer(ctx context.Context, cfg config.McpServerConfig, lookup SecretLookup) (*McpClient, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("MCP server without a name")
+	}
+	env := expandMap(cfg.Env, lookup)
+	headers := expandMap(cfg.Headers, lookup)
+
+	transport := cfg.Transport
+	if transport == "" {
+		transport = "stdio"
+		if cfg.URL != "" {
+			transport = "http"
+		}
+	}
+	var (
+		tr  mcpTransport
+		err error
+	)
+	switch transport {
+	case "stdio":
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("MCP server %q: stdio transport needs a command", cfg.Name)
+		}
+		tr, err = newMcpStdioTransport(cfg.Name, expandSecrets(cfg.Command, lookup), cfg.Args, env, cfg.Dir)
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("MCP server %q: http transport needs a url", cfg.Name)
+		}
+		tr = newMcpHTTPTransport(cfg.Name, expandSecrets(cfg.URL, lookup), headers)
+	case "sse":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("MCP server %q: sse transport needs a url", cfg.Name)
+		}
+		tr, err = newMcpSSETransport(ctx, cfg.Name, expandSecrets(cfg.URL, lookup), headers)
+	default:
+		return nil, fmt.Errorf("MCP server %q: unknown transport %q (want stdio, http or sse)", cfg.Name, transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &McpClient{cfg: cfg, transport: tr, timeout: defaultMcpTimeout}
+	if cfg.TimeoutSeconds > 0 {
+		c.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	if err := c.initialize(ctx); err != nil {
+		tr.close()
+		return nil, fmt.Errorf("MCP server %q: initialize failed: %w", cfg.Name, err)
+	}
+	return c, nil
+}
+
+func expandMap(m map[string]string, lookup SecretLookup) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = expandSecrets(v, lookup)
+	}
+	return out
+}
+
+func (c *McpClient) Name() string { return c.cfg.Name }
+
+func (c *McpClient) initialize(ctx context.Context) error {
+	var res McpInitializeResult
+	err := c.request(ctx, "initialize", McpInitializeParams{
+		ProtocolVersion: McpProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      McpImplementation{Name: "go-gen", Version: "0.1.0"},
+	}, &res)
+	if err != nil {
+		return err
+	}
+	if !mcpVersionSupported(res.ProtocolVersion) {
+		return fmt.Errorf("unsupported protocol version %q", res.ProtocolVersion)
+	}
+	c.ServerInfo, c.ProtocolVersion, c.Instructions = res.ServerInfo, res.ProtocolVersion, res.Instructions
+	c.transport.setProtocolVersion(res.ProtocolVersion)
+	utils.Logger.Info().
+		Str("mcp_server", c.cfg.Name).
+		Str("server", res.ServerInfo.Name+" "+res.ServerInfo.Version).
+		Str("protocol", res.ProtocolVersion).
+		Msg("Connected to MCP server")
+	return c.transport.notify(ctx, &JSONRPCMessage{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// request performs one JSON-RPC call, bounded by the server timeout. A
+// cancelled call is reported to the server with notifications/cancelled.
+func (c *McpClient) request(ctx context.Context, method string, params, result interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.transport.call(ctx, &JSONRPCMessage{JSONRPC: "2.0", ID: id, Method: method, Params: raw})
+	if err != nil {
+		if ctx.Err() != nil && method != "initialize" {
+			note, _ := json.Marshal(map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+			nctx, ncancel := context.WithTimeout(context.Background(), 2*time.Second)
+			c.transport.notify(nctx, &JSONRPCMessage{JSONRPC: "2.0", Method: "notifications/cancelled", Params: note})
+			ncancel()
+		}
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *McpClient) ListTools(ctx context.Context) ([]McpToolInfo, error) {
+	var all []McpToolInfo
+	cursor := ""
+	for {
+		var res McpListToolsResult
+		if err := c.request(ctx, "tools/list", McpListToolsParams{Cursor: cursor}, &res); err != nil {
+			return nil, err
+		}
+		all = append(all, res.Tools...)
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			return all, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// CallTool invokes a remote tool. A tool-level failure is a result with
+// IsError set, not an error.
+func (c *McpClient) CallTool(ctx context.Context, name string, args map[string]interface{}) (*McpCallToolResult, error) {
+	var res McpCallToolResult
+	if err := c.request(ctx, "tools/call", McpCallToolParams{Name: name, Arguments: args}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *McpClient) Close() error {
+	return c.transport.close()
+}
+
+// RegisterTools lists the server's tools and registers the configured ones
+// (all by default) in registry. It returns the registered names.
+func (c *McpClient) RegisterTools(ctx context.Context, registry *ToolRegistry) ([]string, error) {
+	infos, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %q: tools/list failed: %w", c.cfg.Name, err)
+	}
+	wanted := map[string]bool{}
+	for _, n := range c.cfg.Tools {
+		wanted[n] = true
+	}
+	prefix := c.cfg.Prefix
+	if prefix == "" {
+		prefix = c.cfg.Name
+	}
+	var names []string
+	for _, info := range infos {
+		if len(wanted) > 0 && !wanted[info.Name] {
+			continue
+		}
+		tool := &McpRemoteTool{client: c, name: McpToolName(prefix, info.Name), info: info}
+		registry.Register(tool)
+		names = append(names, tool.name)
+	}
+	return names, nil
+}
+
+// McpToolName builds the registry/OpenAI function name for a remote tool,
+// shortening it with a hash suffix if it exceeds the 64 character limit.
+func McpToolName(server, tool string) string {
+	name := McpToolNamePrefix + toolNameSanitizer.ReplaceAllString(server, "_") + "__" + toolNameSanitizer.ReplaceAllString(tool, "_")
+	if len(name) <= maxToolNameLen {
+		return name
+	}
+	sum := sha1.Sum([]byte(name))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	return name[:maxToolNameLen-len(suffix)] + suffix
+}
+
+// ConnectMcpServers connects every enabled server and registers its tools.
+// A server that fails to start is logged and skipped so one broken server
+// does not stop the session. The returned clients must be closed.
+func ConnectMcpServers(ctx context.Context, servers []config.McpServerConfig, registry *ToolRegistry, lookup SecretLookup) []*McpClient {
+	var clients []*McpClient
+	for _, cfg := range servers {
+		if cfg.Disabled {
+			continue
+		}
+		c, err := ConnectMcpServer(ctx, cfg, lookup)
+		if err != nil {
+			utils.Logger.Error().Str("mcp_server", cfg.Name).Err(err).Msg("MCP server unavailable")
+			continue
+		}
+		names, err := c.RegisterTools(ctx, registry)
+		if err != nil {
+			utils.Logger.Error().Str("mcp_server", cfg.Name).Err(err).Msg("MCP server unavailable")
+			c.Close()
+			continue
+		}
+		utils.Logger.Info().Str("mcp_server", cfg.Name).Strs("tools", names).Msg("Registered MCP tools")
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// McpRemoteTool exposes one tool of an MCP server through the Tool interface.
+type McpRemoteTool struct {
+	client *McpClient
+	name   string
+	info   McpToolInfo
+}
+
+func (t *McpRemoteTool) Name() string { return t.name }
+
+// Metadata carries the routing settings of the tool's mcp_servers entry.
+func (t *McpRemoteTool) Metadata() ToolMetadata {
+	return ToolMetadata{Executor: t.client.cfg.Executor, RequiresApproval: t.client.cfg.RequiresApproval}
+}
+
+func (t *McpRemoteTool) Description() string {
+	desc := t.info.Description
+	if desc == "" {
+		desc = t.info.Title
+	}
+	return desc
+}
+
+// Parameters returns the server's input schema, which is already JSON Schema.
+func (t *McpRemoteTool) Parameters() map[string]interface{} {
+	if t.info.InputSchema == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.info.InputSchema
+}
+
+func (t *McpRemoteTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	target := t.client.cfg.Name + "/" + t.info.Name
+	res, err := t.client.CallTool(ctx, t.info.Name, call.Args)
+	if err != nil {
+		return ToolResult{
+			Error:       fmt.Errorf("MCP call %s failed: %w", target, err),
+			ErrorDetail: &ExecErrorDetail{Phase: "mcp", Command: target, ErrMsg: err.Error()},
+		}
+	}
+	text := res.Text()
+	if res.IsError {
+		return ToolResult{
+			Output:      text,
+			Error:       fmt.Errorf("MCP tool %s reported an error", target),
+			ErrorDetail: &ExecErrorDetail{Phase: "mcp", Command: target, Output: text, ErrMsg: "isError"},
+		}
+	}
+	return ToolResult{Output: text}
+}
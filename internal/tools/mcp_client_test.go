@@ -0,0 +1,251 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/tools"
+	"aiupstart.com/go-gen/internal/tools/mcptest"
+)
+
+// TestMcpStdioHelperProcess is not a test: it is the stdio MCP server started
+// by the tests below (the test binary re-run with GO_GEN_MCP_HELPER=1). With
+// GO_GEN_MCP_HELPER_ENV=1 it also serves getenv, reporting its environment.
+func TestMcpStdioHelperProcess(t *testing.T) {
+	if os.Getenv("GO_GEN_MCP_HELPER") != "1" {
+		return
+	}
+	srv := mcptest.NewServer()
+	srv.PageSize = 1
+	if os.Getenv("GO_GEN_MCP_HELPER_ENV") == "1" {
+		srv.AddTool(tools.McpToolInfo{Name: "getenv", InputSchema: map[string]interface{}{"type": "object"}},
+			func(ctx context.Context, args map[string]interface{}) tools.McpCallToolResult {
+				name, _ := args["name"].(string)
+				v, ok := os.LookupEnv(name)
+				if !ok {
+					return tools.McpTextResult("<unset>", false)
+				}
+				return tools.McpTextResult(v, false)
+			})
+	}
+	srv.ServeStdio(context.Background(), os.Stdin, os.Stdout)
+	os.Exit(0)
+}
+
+func connect(t *testing.T, cfg config.McpServerConfig) *tools.McpClient {
+	t.Helper()
+	c, err := tools.ConnectMcpServer(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestMcpClientTransports(t *testing.T) {
+	type setup func(t *testing.T, srv *mcptest.Server) config.McpServerConfig
+	transports := []struct {
+		name  string
+		setup setup
+	}{
+		{"stdio", func(t *testing.T, _ *mcptest.Server) config.McpServerConfig {
+			return config.McpServerConfig{
+				Name:    "fake",
+				Command: os.Args[0],
+				Args:    []string{"-test.run=^TestMcpStdioHelperProcess$"},
+				Env:     map[string]string{"GO_GEN_MCP_HELPER": "1"},
+			}
+		}},
+		{"http json", func(t *testing.T, srv *mcptest.Server) config.McpServerConfig {
+			ts := httptest.NewServer(srv)
+			t.Cleanup(ts.Close)
+			return config.McpServerConfig{Name: "fake", URL: ts.URL}
+		}},
+		{"http event-stream", func(t *testing.T, srv *mcptest.Server) config.McpServerConfig {
+			srv.SSE = true
+			ts := httptest.NewServer(srv)
+			t.Cleanup(ts.Close)
+			return config.McpServerConfig{Name: "fake", Transport: "http", URL: ts.URL}
+		}},
+		{"sse", func(t *testing.T, srv *mcptest.Server) config.McpServerConfig {
+			ts := httptest.NewServer(srv.SSEHandler())
+			t.Cleanup(ts.Close)
+			return config.McpServerConfig{Name: "fake", Transport: "sse", URL: ts.URL + "/sse"}
+		}},
+	}
+	for _, tr := range transports {
+		t.Run(tr.name, func(t *testing.T) {
+			srv := mcptest.NewServer()
+			srv.PageSize = 1
+			c := connect(t, tr.setup(t, srv))
+			if c.ServerInfo.Name != "mcptest" || c.ProtocolVersion != tools.McpProtocolVersion {
+				t.Errorf("server %q, protocol %q after initialize", c.ServerInfo.Name, c.ProtocolVersion)
+			}
+
+			registry := tools.NewToolRegistry()
+			names, err := c.RegisterTools(context.Background(), registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "mcp__fake__echo,mcp__fake__add,mcp__fake__fail,mcp__fake__sleep"
+			if got := strings.Join(names, ","); got != want {
+				t.Fatalf("registered %s, want %s", got, want)
+			}
+
+			res := registry.Call(context.Background(), tools.ToolCall{Name: "mcp__fake__echo", Args: map[string]interface{}{"text": "hi"}})
+			if res.Error != nil || res.Output != "hi" {
+				t.Errorf("echo = %v, %v", res.Output, res.Error)
+			}
+			res = registry.Call(context.Background(), tools.ToolCall{Name: "mcp__fake__add", Args: map[string]interface{}{"a": 2, "b": 3}})
+			if res.Error != nil || res.Output != "5" {
+				t.Errorf("add = %v, %v", res.Output, res.Error)
+			}
+		})
+	}
+}
+
+func TestMcpClientHandshake(t *testing.T) {
+	srv := mcptest.NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	connect(t, config.McpServerConfig{Name: "fake", URL: ts.URL})
+
+	if len(srv.Received) != 2 {
+		t.Fatalf("server received %d messages, want initialize and notifications/initialized", len(srv.Received))
+	}
+	first, note := srv.Received[0], srv.Received[1]
+	if first.Method != "initialize" || !first.IsRequest() {
+		t.Fatalf("first message = %s, want the initialize request", first.Method)
+	}
+	var params tools.McpInitializeParams
+	if err := json.Unmarshal(first.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.ProtocolVersion != tools.McpProtocolVersion || params.ClientInfo.Name == "" {
+		t.Errorf("initialize params = %+v", params)
+	}
+	if note.Method != "notifications/initialized" || note.IsRequest() {
+		t.Errorf("second message = %s (request %v), want the initialized notification", note.Method, note.IsRequest())
+	}
+}
+
+func TestMcpClientListToolsPagination(t *testing.T) {
+	srv := mcptest.NewServer()
+	srv.PageSize = 3
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	c := connect(t, config.McpServerConfig{Name: "fake", URL: ts.URL})
+
+	infos, err := c.ListTools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 4 {
+		t.Fatalf("listed %d tools, want all 4 across pages", len(infos))
+	}
+	var cursors []string
+	for _, m := range srv.Received {
+		if m.Method == "tools/list" {
+			var p tools.McpListToolsParams
+			json.Unmarshal(m.Params, &p)
+			cursors = append(cursors, p.Cursor)
+		}
+	}
+	if got := strings.Join(cursors, "|"); got != "|3" {
+		t.Errorf("tools/list cursors = %q, want the first page and cursor 3", got)
+	}
+}
+
+func TestMcpClientRegistersConfiguredTools(t *testing.T) {
+	srv := mcptest.NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	c := connect(t, config.McpServerConfig{Name: "fake", URL: ts.URL, Prefix: "calc", Tools: []string{"add"}})
+
+	registry := tools.NewToolRegistry()
+	names, err := c.RegisterTools(context.Background(), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "mcp__calc__add" {
+		t.Fatalf("registered %v, want only mcp__calc__add", names)
+	}
+}
+
+func TestMcpClientErrors(t *testing.T) {
+	srv := mcptest.NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	c := connect(t, config.McpServerConfig{Name: "fake", URL: ts.URL})
+	registry := tools.NewToolRegistry()
+	if _, err := c.RegisterTools(context.Background(), registry); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("isError result", func(t *testing.T) {
+		res := registry.Call(context.Background(), tools.ToolCall{Name: "mcp__fake__fail", Args: map[string]interface{}{"message": "disk full"}})
+		if res.Error == nil {
+			t.Fatal("isError result reported as success")
+		}
+		if res.Output != "disk full" {
+			t.Errorf("output = %v, want the tool's error text", res.Output)
+		}
+		if res.ErrorDetail == nil || res.ErrorDetail.Phase != "mcp" || res.ErrorDetail.Output != "disk full" {
+			t.Errorf("error detail = %+v", res.ErrorDetail)
+		}
+	})
+	t.Run("JSON-RPC error", func(t *testing.T) {
+		_, err := c.CallTool(context.Background(), "missing", nil)
+		var rpcErr *tools.JSONRPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != tools.JSONRPCInvalidParams || !strings.Contains(rpcErr.Message, "unknown tool") {
+			t.Fatalf("err = %v, want the server's invalid params error", err)
+		}
+	})
+}
+
+func TestMcpStdioServerEnvironment(t *testing.T) {
+	t.Setenv("GOGEN_SECRETS_KEY", "parent-passphrase")
+	t.Setenv("OPENAI_API_KEY", "sk-parent")
+	t.Setenv("API_TOKEN", "from-process-env")
+	lookup := func(name string) (string, bool) {
+		if name == "API_TOKEN" {
+			return "from-secret-store", true
+		}
+		return "", false
+	}
+	c, err := tools.ConnectMcpServer(context.Background(), config.McpServerConfig{
+		Name:    "fake",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestMcpStdioHelperProcess$"},
+		Env: map[string]string{
+			"GO_GEN_MCP_HELPER":     "1",
+			"GO_GEN_MCP_HELPER_ENV": "1",
+			"API_TOKEN":             "${API_TOKEN}",
+		},
+	}, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for name, want := range map[string]string{
+		"GOGEN_SECRETS_KEY": "<unset>",
+		"OPENAI_API_KEY":    "<unset>",
+		"API_TOKEN":         "from-secret-store",
+		"PATH":              os.Getenv("PATH"),
+	} {
+		res, err := c.CallTool(context.Background(), "getenv", map[string]interface{}{"name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Text(); got != want {
+			t.Errorf("server sees %s=%q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// internal/tools/mcp_protocol.go
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// McpProtocolVersion is the newest MCP revision spoken by this package.
+const McpProtocolVersion = "2025-06-18"
+
+// mcpSupportedVersions lists every revision we accept from a peer, newest first.
+var mcpSupportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+func mcpVersionSupported(v string) bool {
+	for _, s := range mcpSupportedVersions {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON-RPC 2.0 error codes used by MCP.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+// JSONRPCMessage is a request, notification or response. Requests carry ID
+// and Method, notifications only Method, responses ID and Result or Error.
+type JSONRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+func (m *JSONRPCMessage) IsRequest() bool      { return m.Method != "" && len(m.ID) > 0 }
+func (m *JSONRPCMessage) IsNotification() bool { return m.Method != "" && len(m.ID) == 0 }
+func (m *JSONRPCMessage) IsResponse() bool     { return m.Method == "" && len(m.ID) > 0 }
+
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// NewJSONRPCResult builds the response to request id.
+func NewJSONRPCResult(id json.RawMessage, result interface{}) *JSONRPCMessage {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return NewJSONRPCError(id, JSONRPCInternalError, err.Error())
+	}
+	return &JSONRPCMessage{JSONRPC: "2.0", ID: id, Result: data}
+}
+
+// NewJSONRPCError builds an error response to request id.
+func NewJSONRPCError(id json.RawMessage, code int, msg string) *JSONRPCMessage {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &JSONRPCMessage{JSONRPC: "2.0", ID: id, Error: &JSONRPCError{Code: code, Message: msg}}
+}
+
+type McpImplementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type McpInitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      McpImplementation      `json:"clientInfo"`
+}
+
+type McpInitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      McpImplementation      `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+}
+
+// McpToolInfo is one entry of a tools/list result.
+type McpToolInfo struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+type McpListToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type McpListToolsResult struct {
+	Tools      []McpToolInfo `json:"tools"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+type McpCallToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// McpContent is a content block of a tool result (text, image, audio,
+// resource_link or embedded resource).
+type McpContent struct {
+	Type     string                 `json:"type"`
+	Text     string                 `json:"text,omitempty"`
+	Data     string                 `json:"data,omitempty"`
+	MimeType string                 `json:"mimeType,omitempty"`
+	URI      string                 `json:"uri,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+}
+
+// McpCallToolResult is the result of tools/call. IsError marks a tool-level
+// failure whose details are in Content (as opposed to a JSON-RPC error).
+type McpCallToolResult struct {
+	Content           []McpContent `json:"content"`
+	StructuredContent interface{}  `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+// McpTextResult is a convenience for single text block results.
+func McpTextResult(text string, isError bool) McpCallToolResult {
+	return McpCallToolResult{Content: []McpContent{{Type: "text", Text: text}}, IsError: isError}
+}
+
+// Text flattens the result for agents and the LLM: text blocks verbatim,
+// other blocks as short placeholders, structured content as JSON if there is
+// no text.
+func (r *McpCallToolResult) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s: %s, %d base64 bytes]", c.Type, c.MimeType, len(c.Data)))
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s]", c.URI))
+		case "resource":
+			if text, ok := c.Resource["text"].(string); ok {
+				parts = append(parts, text)
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource: %v]", c.Resource["uri"]))
+			}
+		}
+	}
+	if len(parts) == 0 && r.StructuredContent != nil {
+		if data, err := json.Marshal(r.StructuredContent); err == nil {
+			return string(data)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,54 @@
+// internal/tools/mcp_schema.go
+package tools
+
+var jsonSchemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true, "null": true,
+}
+
+// ToolInputSchema returns a JSON Schema for a tool's Parameters(). Most tools
+// already return a schema; older ones return a shorthand map of
+// name -> type (or description), e.g. DockerExecTool, which is converted.
+func ToolInputSchema(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	if _, ok := params["type"]; ok {
+		return params
+	}
+	props := map[string]interface{}{}
+	for name, v := range params {
+		props[name] = shorthandSchema(v)
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+func shorthandSchema(v interface{}) map[string]interface{} {
+	switch x := v.(type) {
+	case string:
+		if jsonSchemaTypes[x] {
+			return map[string]interface{}{"type": x}
+		}
+		return map[string]interface{}{"type": "string", "description": x}
+	case map[string]interface{}:
+		return ToolInputSchema(x)
+	case map[string]string:
+		props := map[string]interface{}{}
+		for name, t := range x {
+			props[name] = shorthandSchema(t)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	case []map[string]string:
+		schema := map[string]interface{}{"type": "array"}
+		if len(x) > 0 {
+			schema["items"] = shorthandSchema(x[0])
+		}
+		return schema
+	case []interface{}:
+		schema := map[string]interface{}{"type": "array"}
+		if len(x) > 0 {
+			schema["items"] = shorthandSchema(x[0])
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,429 @@
+// internal/tools/mcp_server.go
+package tools
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/utils"
+	"github.com/google/uuid"
+)
+
+// McpServerOptions configures McpServer.
+type McpServerOptions struct {
+	Name         string                            // serverInfo.name, default "go-gen"
+	Version      string                            // serverInfo.version
+	Instructions string                            // returned from initialize
+	Schemas      map[string]map[string]interface{} // tool name -> input schema overriding Parameters()
+	Token        string                            // HTTP only: required bearer token
+	// AllowedOrigins lists browser origins accepted over HTTP. Empty allows
+	// only localhost origins (DNS rebinding protection); requests without an
+	// Origin header (non-browser clients) are always accepted.
+	AllowedOrigins []string
+	// ApprovalTools also serves tools whose metadata requires approval. MCP
+	// clients call tools without a human in the loop, so by default those
+	// tools are neither listed nor callable.
+	ApprovalTools bool
+	// SessionIdleTimeout ends HTTP sessions idle this long (default 30m);
+	// their clients get 404 and initialize again. MaxSessions caps open
+	// HTTP sessions (default 100): a new one ends the longest idle.
+	SessionIdleTimeout time.Duration
+	MaxSessions        int
+}
+
+// McpServer serves the tools of a ToolRegistry over MCP (stdio or
+// streamable HTTP). Tool calls go through the registry, so secret redaction
+// applies to everything returned to MCP clients.
+type McpServer struct {
+	registry *ToolRegistry
+	opts     McpServerOptions
+
+	mu       sync.Mutex
+	sessions map[string]*mcpServerSession // HTTP sessions by Mcp-Session-Id
+}
+
+// mcpServerSession is one connected client.
+type mcpServerSession struct {
+	id       string
+	client   McpImplementation
+	lastUsed time.Time // HTTP only, guarded by McpServer.mu
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc // request id -> cancel
+}
+
+func NewMcpServer(registry *ToolRegistry, opts McpServerOptions) *McpServer {
+	if opts.Name == "" {
+		opts.Name = "go-gen"
+	}
+	if opts.Version == "" {
+		opts.Version = "0.1.0"
+	}
+	if opts.SessionIdleTimeout <= 0 {
+		opts.SessionIdleTimeout = 30 * time.Minute
+	}
+	if opts.MaxSessions <= 0 {
+		opts.MaxSessions = 100
+	}
+	return &McpServer{registry: registry, opts: opts, sessions: map[string]*mcpServerSession{}}
+}
+
+func newMcpServerSession(id string) *mcpServerSession {
+	return &mcpServerSession{id: id, inflight: map[string]context.CancelFunc{}}
+}
+
+// handle processes one message and returns the response, or nil for
+// notifications and responses.
+func (s *McpServer) handle(ctx context.Context, sess *mcpServerSession, msg *JSONRPCMessage) *JSONRPCMessage {
+	if msg.IsNotification() {
+		if msg.Method == "notifications/cancelled" {
+			var p struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			json.Unmarshal(msg.Params, &p)
+			sess.mu.Lock()
+			if cancel, ok := sess.inflight[string(p.RequestID)]; ok {
+				cancel()
+			}
+			sess.mu.Unlock()
+		}
+		return nil
+	}
+	if !msg.IsRequest() {
+		return nil
+	}
+	switch msg.Method {
+	case "initialize":
+		var p McpInitializeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return NewJSONRPCError(msg.ID, JSONRPCInvalidParams, err.Error())
+		}
+		sess.client = p.ClientInfo
+		version := McpProtocolVersion
+		if mcpVersionSupported(p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		utils.Logger.Info().Str("client", p.ClientInfo.Name+" "+p.ClientInfo.Version).Str("protocol", version).Msg("MCP client connected")
+		return NewJSONRPCResult(msg.ID, McpInitializeResult{
+			ProtocolVersion: version,
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{"listChanged": false}},
+			ServerInfo:      McpImplementation{Name: s.opts.Name, Version: s.opts.Version},
+			Instructions:    s.opts.Instructions,
+		})
+	case "ping":
+		return NewJSONRPCResult(msg.ID, struct{}{})
+	case "tools/list":
+		return NewJSONRPCResult(msg.ID, McpListToolsResult{Tools: s.listTools()})
+	case "tools/call":
+		var p McpCallToolParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return NewJSONRPCError(msg.ID, JSONRPCInvalidParams, err.Error())
+		}
+		t, ok := s.registry.Get(p.Name)
+		if !ok {
+			return NewJSONRPCError(msg.ID, JSONRPCInvalidParams, "unknown tool: "+p.Name)
+		}
+		if !s.serves(t) {
+			return NewJSONRPCError(msg.ID, JSONRPCInvalidParams, "tool "+p.Name+" requires human approval and is not served over MCP")
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		sess.mu.Lock()
+		sess.inflight[string(msg.ID)] = cancel
+		sess.mu.Unlock()
+		defer func() {
+			sess.mu.Lock()
+			delete(sess.inflight, string(msg.ID))
+			sess.mu.Unlock()
+			cancel()
+		}()
+		caller := "mcp"
+		if sess.client.Name != "" {
+			caller = "mcp:" + sess.client.Name
+		}
+		res := s.registry.Call(ctx, ToolCall{Name: p.Name, Args: p.Arguments, Caller: caller, Trace: []string{caller}})
+		return NewJSONRPCResult(msg.ID, mcpResultFromToolResult(res))
+	}
+	return NewJSONRPCError(msg.ID, JSONRPCMethodNotFound, "method not found: "+msg.Method)
+}
+
+func (s *McpServer) listTools() []McpToolInfo {
+	list := s.registry.List()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	infos := make([]McpToolInfo, 0, len(list))
+	for _, t := range list {
+		if !s.serves(t) {
+			continue
+		}
+		schema, ok := s.opts.Schemas[t.Name()]
+		if !ok {
+			schema = ToolInputSchema(t.Parameters())
+		}
+		infos = append(infos, McpToolInfo{Name: t.Name(), Description: t.Description(), InputSchema: schema})
+	}
+	return infos
+}
+
+// serves reports whether t is offered to MCP clients.
+func (s *McpServer) serves(t Tool) bool {
+	return s.opts.ApprovalTools || !MetadataOf(t).RequiresApproval
+}
+
+// mcpResultFromToolResult maps a ToolResult to MCP content. String output
+// becomes a text block, other output JSON text plus structuredContent for
+// objects. An error sets isError and adds a text block with the error and
+// ExecErrorDetail, which is also given as structuredContent.
+func mcpResultFromToolResult(res ToolResult) McpCallToolResult {
+	out := McpCallToolResult{Content: []McpContent{}}
+	switch o := res.Output.(type) {
+	case nil:
+	case string:
+		if o != "" {
+			out.Content = append(out.Content, McpContent{Type: "text", Text: o})
+		}
+	default:
+		data, err := json.Marshal(o)
+		if err != nil {
+			data = []byte(fmt.Sprintf("%v", o))
+		}
+		out.Content = append(out.Content, McpContent{Type: "text", Text: string(data)})
+		if len(data) > 0 && data[0] == '{' {
+			out.StructuredContent = o
+		}
+	}
+	if res.Error == nil {
+		return out
+	}
+	out.IsError = true
+	var b strings.Builder
+	fmt.Fprintf(&b, "Error: %s", res.Error)
+	if d := res.ErrorDetail; d != nil {
+		fmt.Fprintf(&b, "\nPhase: %s", d.Phase)
+		if d.Command != "" {
+			fmt.Fprintf(&b, "\nCommand: %s", d.Command)
+		}
+		if d.ErrMsg != "" {
+			fmt.Fprintf(&b, "\nDetail: %s", d.ErrMsg)
+		}
+		if d.Output != "" && d.Output != res.Output {
+			fmt.Fprintf(&b, "\nOutput:\n%s", d.Output)
+		}
+		out.StructuredContent = map[string]interface{}{
+			"error": res.Error.Error(), "phase": d.Phase, "command": d.Command, "output": d.Output, "detail": d.ErrMsg,
+		}
+	}
+	out.Content = append(out.Content, McpContent{Type: "text", Text: b.String()})
+	return out
+}
+
+// ServeStdio serves one client on newline-delimited JSON-RPC until r reaches
+// EOF or ctx is cancelled, then waits for in-flight calls.
+func (s *McpServer) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sess := newMcpServerSession("stdio")
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	defer wg.Wait()
+	write := func(msg *JSONRPCMessage) {
+		data, _ := json.Marshal(msg)
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), mcpMaxMessage)
+		for sc.Scan() {
+			line := append([]byte(nil), sc.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- sc.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			if strings.TrimSpace(string(line)) == "" {
+				continue
+			}
+			var msg JSONRPCMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				write(NewJSONRPCError(nil, JSONRPCParseError, err.Error()))
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp := s.handle(ctx, sess, &msg); resp != nil {
+					write(resp)
+				}
+			}()
+		}
+	}
+}
+
+// ServeHTTP implements the streamable HTTP transport: clients POST one
+// JSON-RPC message per request and get a JSON reply. The server never pushes
+// messages, so GET (server stream) is not offered. Sessions end on DELETE,
+// after SessionIdleTimeout, or when MaxSessions newer ones push them out.
+func (s *McpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !s.originAllowed(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if s.opts.Token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		s.mu.Lock()
+		if sess := s.sessions[r.Header.Get("Mcp-Session-Id")]; sess != nil {
+			s.endSession(sess)
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg JSONRPCMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, mcpMaxMessage)).Decode(&msg); err != nil {
+		writeJSONRPC(w, http.StatusBadRequest, NewJSONRPCError(nil, JSONRPCParseError, err.Error()))
+		return
+	}
+	var sess *mcpServerSession
+	if msg.Method == "initialize" {
+		sess = newMcpServerSession(uuid.New().String())
+		s.addSession(sess)
+		w.Header().Set("Mcp-Session-Id", sess.id)
+	} else {
+		id := r.Header.Get("Mcp-Session-Id")
+		if id == "" {
+			writeJSONRPC(w, http.StatusBadRequest, NewJSONRPCError(msg.ID, JSONRPCInvalidRequest, "missing Mcp-Session-Id header"))
+			return
+		}
+		if sess = s.session(id); sess == nil {
+			writeJSONRPC(w, http.StatusNotFound, NewJSONRPCError(msg.ID, JSONRPCInvalidRequest, "unknown session"))
+			return
+		}
+	}
+	resp := s.handle(r.Context(), sess, &msg)
+	s.session(sess.id) // a long call counts as activity
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSONRPC(w, http.StatusOK, resp)
+}
+
+// session returns the open HTTP session id, marking it used, or nil if it
+// is unknown or has expired.
+func (s *McpServer) session(id string) *mcpServerSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireSessions()
+	sess := s.sessions[id]
+	if sess != nil {
+		sess.lastUsed = time.Now()
+	}
+	return sess
+}
+
+// addSession opens sess, ending the longest idle session if MaxSessions
+// are open.
+func (s *McpServer) addSession(sess *mcpServerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireSessions()
+	if len(s.sessions) >= s.opts.MaxSessions {
+		var idlest *mcpServerSession
+		for _, other := range s.sessions {
+			if idlest == nil || other.lastUsed.Before(idlest.lastUsed) {
+				idlest = other
+			}
+		}
+		utils.Logger.Info().Str("session", idlest.id).Int("max_sessions", s.opts.MaxSessions).Msg("Too many MCP sessions; ending the longest idle")
+		s.endSession(idlest)
+	}
+	sess.lastUsed = time.Now()
+	s.sessions[sess.id] = sess
+}
+
+// expireSessions ends sessions idle longer than SessionIdleTimeout. A
+// session with calls in flight is not idle. Called with s.mu held.
+func (s *McpServer) expireSessions() {
+	cutoff := time.Now().Add(-s.opts.SessionIdleTimeout)
+	for _, sess := range s.sessions {
+		sess.mu.Lock()
+		busy := len(sess.inflight) > 0
+		sess.mu.Unlock()
+		if !busy && sess.lastUsed.Before(cutoff) {
+			utils.Logger.Debug().Str("session", sess.id).Str("client", sess.client.Name).Msg("MCP session expired")
+			s.endSession(sess)
+		}
+	}
+}
+
+// endSession removes sess and cancels its calls. Called with s.mu held.
+func (s *McpServer) endSession(sess *mcpServerSession) {
+	delete(s.sessions, sess.id)
+	sess.mu.Lock()
+	for _, cancel := range sess.inflight {
+		cancel()
+	}
+	sess.mu.Unlock()
+}
+
+func writeJSONRPC(w http.ResponseWriter, status int, msg *JSONRPCMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
+
+func (s *McpServer) originAllowed(origin string) bool {
+	if len(s.opts.AllowedOrigins) > 0 {
+		for _, o := range s.opts.AllowedOrigins {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
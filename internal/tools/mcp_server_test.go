@@ -0,0 +1,233 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/tools"
+)
+
+// stubTool answers "ran <name>" and optionally requires approval.
+type stubTool struct {
+	name     string
+	approval bool
+}
+
+func (t *stubTool) Name() string        { return t.name }
+func (t *stubTool) Description() string { return "stub " + t.name }
+func (t *stubTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *stubTool) Metadata() tools.ToolMetadata {
+	return tools.ToolMetadata{RequiresApproval: t.approval}
+}
+func (t *stubTool) Call(ctx context.Context, call tools.ToolCall) tools.ToolResult {
+	return tools.ToolResult{Output: "ran " + t.name}
+}
+
+func serveRegistry(t *testing.T, opts tools.McpServerOptions) *tools.McpClient {
+	t.Helper()
+	registry := tools.NewToolRegistry()
+	registry.Register(&stubTool{name: "lookup"})
+	registry.Register(&stubTool{name: "refund", approval: true})
+	ts := httptest.NewServer(tools.NewMcpServer(registry, opts))
+	t.Cleanup(ts.Close)
+	return connect(t, config.McpServerConfig{Name: "playground", URL: ts.URL})
+}
+
+func toolNames(t *testing.T, c *tools.McpClient) string {
+	t.Helper()
+	infos, err := c.ListTools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestMcpServerHidesApprovalTools(t *testing.T) {
+	c := serveRegistry(t, tools.McpServerOptions{})
+	if got := toolNames(t, c); got != "lookup" {
+		t.Errorf("tools/list = %s, want only lookup", got)
+	}
+	if res, err := c.CallTool(context.Background(), "lookup", nil); err != nil || res.Text() != "ran lookup" {
+		t.Errorf("lookup = %v, %v", res, err)
+	}
+	_, err := c.CallTool(context.Background(), "refund", nil)
+	var rpcErr *tools.JSONRPCError
+	if !errors.As(err, &rpcErr) || !strings.Contains(rpcErr.Message, "requires human approval") {
+		t.Fatalf("refund err = %v, want a refusal", err)
+	}
+}
+
+func TestMcpServerServesApprovalToolsWhenAllowed(t *testing.T) {
+	c := serveRegistry(t, tools.McpServerOptions{ApprovalTools: true})
+	if got := toolNames(t, c); got != "lookup,refund" {
+		t.Errorf("tools/list = %s, want lookup,refund", got)
+	}
+	if res, err := c.CallTool(context.Background(), "refund", nil); err != nil || res.Text() != "ran refund" {
+		t.Errorf("refund = %v, %v", res, err)
+	}
+}
+
+// leakyTool fails with a secret in its structured output and error detail.
+type leakyTool struct{}
+
+func (leakyTool) Name() string        { return "leaky" }
+func (leakyTool) Description() string { return "leaks a token" }
+func (leakyTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (leakyTool) Call(ctx context.Context, call tools.ToolCall) tools.ToolResult {
+	return tools.ToolResult{
+		Output:      map[string]interface{}{"token": "s3cr3t-token"},
+		Error:       errors.New("rejected s3cr3t-token"),
+		ErrorDetail: &tools.ExecErrorDetail{Phase: "http", Command: "GET /?key=s3cr3t-token", Output: "s3cr3t-token", ErrMsg: "s3cr3t-token"},
+	}
+}
+
+func TestMcpServerRedactsResults(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	registry.SetRedactor(strings.NewReplacer("s3cr3t-token", "[REDACTED:TOKEN]").Replace)
+	registry.Register(leakyTool{})
+	ts := httptest.NewServer(tools.NewMcpServer(registry, tools.McpServerOptions{}))
+	defer ts.Close()
+	c := connect(t, config.McpServerConfig{Name: "playground", URL: ts.URL})
+
+	res, err := c.CallTool(context.Background(), "leaky", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(res)
+	if strings.Contains(string(data), "s3cr3t-token") || !strings.Contains(string(data), "[REDACTED:TOKEN]") {
+		t.Errorf("result = %s, want the token redacted everywhere", data)
+	}
+}
+
+const (
+	initializeBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"curl"}}}`
+	pingBody       = `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+)
+
+// mcpPost posts body to the server with the given headers.
+func mcpPost(t *testing.T, ts *httptest.Server, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// mcpSession initializes a session and returns its id.
+func mcpSession(t *testing.T, ts *httptest.Server, header map[string]string) string {
+	t.Helper()
+	resp := mcpPost(t, ts, initializeBody, header)
+	id := resp.Header.Get("Mcp-Session-Id")
+	if resp.StatusCode != http.StatusOK || id == "" {
+		t.Fatalf("initialize: %s, session %q", resp.Status, id)
+	}
+	return id
+}
+
+func TestMcpServerHTTPChecks(t *testing.T) {
+	ts := httptest.NewServer(tools.NewMcpServer(tools.NewToolRegistry(), tools.McpServerOptions{Token: "t0ken"}))
+	defer ts.Close()
+	auth := map[string]string{"Authorization": "Bearer t0ken"}
+	id := mcpSession(t, ts, auth)
+
+	tests := []struct {
+		name   string
+		body   string
+		header map[string]string
+		want   int
+	}{
+		{"no token", initializeBody, nil, http.StatusUnauthorized},
+		{"wrong token", initializeBody, map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"foreign origin", initializeBody, map[string]string{"Authorization": "Bearer t0ken", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"localhost origin", initializeBody, map[string]string{"Authorization": "Bearer t0ken", "Origin": "http://localhost:5173"}, http.StatusOK},
+		{"no session", pingBody, auth, http.StatusBadRequest},
+		{"unknown session", pingBody, map[string]string{"Authorization": "Bearer t0ken", "Mcp-Session-Id": "nope"}, http.StatusNotFound},
+		{"session", pingBody, map[string]string{"Authorization": "Bearer t0ken", "Mcp-Session-Id": id}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp := mcpPost(t, ts, tt.body, tt.header)
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: %s, want %d", tt.name, resp.Status, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s: no WWW-Authenticate challenge", tt.name)
+		}
+	}
+
+	// Configured origins replace the localhost default.
+	ts2 := httptest.NewServer(tools.NewMcpServer(tools.NewToolRegistry(), tools.McpServerOptions{AllowedOrigins: []string{"https://app.example"}}))
+	defer ts2.Close()
+	for origin, want := range map[string]int{"https://app.example": http.StatusOK, "http://localhost": http.StatusForbidden} {
+		if resp := mcpPost(t, ts2, initializeBody, map[string]string{"Origin": origin}); resp.StatusCode != want {
+			t.Errorf("origin %s: %s, want %d", origin, resp.Status, want)
+		}
+	}
+}
+
+func TestMcpServerSessionLimits(t *testing.T) {
+	ping := func(ts *httptest.Server, id string) int {
+		return mcpPost(t, ts, pingBody, map[string]string{"Mcp-Session-Id": id}).StatusCode
+	}
+
+	// The longest idle session makes room for a new one.
+	ts := httptest.NewServer(tools.NewMcpServer(tools.NewToolRegistry(), tools.McpServerOptions{MaxSessions: 2}))
+	defer ts.Close()
+	first, second := mcpSession(t, ts, nil), mcpSession(t, ts, nil)
+	ping(ts, first)
+	third := mcpSession(t, ts, nil)
+	for id, want := range map[string]int{first: http.StatusOK, second: http.StatusNotFound, third: http.StatusOK} {
+		if got := ping(ts, id); got != want {
+			t.Errorf("max sessions: ping %s = %d, want %d", id, got, want)
+		}
+	}
+
+	// Idle sessions expire; DELETE ends one at once.
+	ts = httptest.NewServer(tools.NewMcpServer(tools.NewToolRegistry(), tools.McpServerOptions{SessionIdleTimeout: 50 * time.Millisecond}))
+	defer ts.Close()
+	idle, deleted := mcpSession(t, ts, nil), mcpSession(t, ts, nil)
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set("Mcp-Session-Id", deleted)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: %s", resp.Status)
+	}
+	if got := ping(ts, deleted); got != http.StatusNotFound {
+		t.Errorf("deleted session: ping = %d, want 404", got)
+	}
+	if got := ping(ts, idle); got != http.StatusOK {
+		t.Errorf("fresh session: ping = %d, want 200", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := ping(ts, idle); got != http.StatusNotFound {
+		t.Errorf("idle session: ping = %d, want 404", got)
+	}
+}
@@ -0,0 +1,552 @@
+// internal/tools/mcp_transport.go
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// mcpMaxMessage bounds a single JSON-RPC line/event (large tool outputs).
+const mcpMaxMessage = 16 << 20
+
+// mcpTransport moves JSON-RPC messages to and from one server.
+type mcpTransport interface {
+	// call sends a request and waits for the matching response.
+	call(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error)
+	notify(ctx context.Context, msg *JSONRPCMessage) error
+	// setProtocolVersion is called after initialize (HTTP sends it as a header).
+	setProtocolVersion(v string)
+	close() error
+}
+
+// mcpDispatcher matches responses read from a stream to waiting callers and
+// answers requests the server sends us (only ping is supported).
+type mcpDispatcher struct {
+	server  string
+	mu      sync.Mutex
+	pending map[string]chan *JSONRPCMessage
+	err     error // set once the stream is gone
+	reply   func(*JSONRPCMessage) error
+}
+
+func newMcpDispatcher(server string) *mcpDispatcher {
+	return &mcpDispatcher{server: server, pending: map[string]chan *JSONRPCMessage{}}
+}
+
+func (d *mcpDispatcher) register(id json.RawMessage) (chan *JSONRPCMessage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return nil, d.err
+	}
+	ch := make(chan *JSONRPCMessage, 1)
+	d.pending[string(id)] = ch
+	return ch, nil
+}
+
+func (d *mcpDispatcher) forget(id json.RawMessage) {
+	d.mu.Lock()
+	delete(d.pending, string(id))
+	d.mu.Unlock()
+}
+
+func (d *mcpDispatcher) wait(ctx context.Context, id json.RawMessage, ch chan *JSONRPCMessage) (*JSONRPCMessage, error) {
+	defer d.forget(id)
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, d.failure()
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (d *mcpDispatcher) failure() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// handle routes one incoming message.
+func (d *mcpDispatcher) handle(msg *JSONRPCMessage) {
+	switch {
+	case msg.IsResponse():
+		d.mu.Lock()
+		ch, ok := d.pending[string(msg.ID)]
+		delete(d.pending, string(msg.ID))
+		d.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case msg.IsRequest():
+		resp := NewJSONRPCError(msg.ID, JSONRPCMethodNotFound, "method not supported by client: "+msg.Method)
+		if msg.Method == "ping" {
+			resp = NewJSONRPCResult(msg.ID, struct{}{})
+		}
+		if d.reply != nil {
+			if err := d.reply(resp); err != nil {
+				utils.Logger.Warn().Str("mcp_server", d.server).Err(err).Msg("Failed to answer server request")
+			}
+		}
+	default:
+		utils.Logger.Debug().Str("mcp_server", d.server).Str("method", msg.Method).Msg("MCP notification")
+	}
+}
+
+// fail wakes every waiting caller with err; later calls fail immediately.
+func (d *mcpDispatcher) fail(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return
+	}
+	d.err = err
+	for id, ch := range d.pending {
+		close(ch)
+		delete(d.pending, id)
+	}
+}
+
+// ---------------------------------------------------------------- stdio
+
+// mcpStdioTransport runs the server as a child process speaking
+// newline-delimited JSON-RPC on stdin/stdout; stderr goes to the log.
+type mcpStdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	disp    *mcpDispatcher
+	exited  chan struct{}
+}
+
+func newMcpStdioTransport(server, command string, args []string, env map[string]string, dir string) (*mcpStdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = childEnv(env)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting MCP server %q: %w", server, err)
+	}
+	t := &mcpStdioTransport{cmd: cmd, stdin: stdin, disp: newMcpDispatcher(server), exited: make(chan struct{})}
+	t.disp.reply = t.write
+
+	go func() {
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			utils.Logger.Debug().Str("mcp_server", server).Msg(sc.Text())
+		}
+	}()
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(make([]byte, 64*1024), mcpMaxMessage)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var msg JSONRPCMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				utils.Logger.Warn().Str("mcp_server", server).Msgf("Ignoring non JSON-RPC output: %.200s", line)
+				continue
+			}
+			t.disp.handle(&msg)
+		}
+		waitErr := cmd.Wait()
+		if err := sc.Err(); err != nil {
+			waitErr = err
+		}
+		t.disp.fail(fmt.Errorf("MCP server %q exited: %v", server, waitErr))
+		close(t.exited)
+	}()
+	return t, nil
+}
+
+func (t *mcpStdioTransport) write(msg *JSONRPCMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *mcpStdioTransport) call(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+	ch, err := t.disp.register(msg.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.write(msg); err != nil {
+		t.disp.forget(msg.ID)
+		return nil, err
+	}
+	return t.disp.wait(ctx, msg.ID, ch)
+}
+
+func (t *mcpStdioTransport) notify(_ context.Context, msg *JSONRPCMessage) error {
+	return t.write(msg)
+}
+
+func (t *mcpStdioTransport) setProtocolVersion(string) {}
+
+// close ends stdin (the spec's shutdown signal) and kills the server if it
+// does not exit promptly.
+func (t *mcpStdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.exited:
+	case <-time.After(3 * time.Second):
+		t.cmd.Process.Kill()
+		<-t.exited
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------- streamable HTTP
+
+// mcpHTTPTransport implements the streamable HTTP transport: every message is
+// POSTed to one endpoint and the reply comes back as JSON or as an SSE stream.
+type mcpHTTPTransport struct {
+	server   string
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	disp     *mcpDispatcher
+
+	mu        sync.Mutex
+	sessionID string
+	version   string
+}
+
+func newMcpHTTPTransport(server, endpoint string, headers map[string]string) *mcpHTTPTransport {
+	t := &mcpHTTPTransport{server: server, endpoint: endpoint, headers: headers, client: &http.Client{}, disp: newMcpDispatcher(server)}
+	t.disp.reply = func(msg *JSONRPCMessage) error {
+		resp, err := t.post(context.Background(), msg)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return t
+}
+
+func (t *mcpHTTPTransport) setProtocolVersion(v string) {
+	t.mu.Lock()
+	t.version = v
+	t.mu.Unlock()
+}
+
+func (t *mcpHTTPTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.version != "" {
+		req.Header.Set("MCP-Protocol-Version", t.version)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *mcpHTTPTransport) post(ctx context.Context, msg *JSONRPCMessage) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound && t.hasSession() {
+			return nil, fmt.Errorf("MCP session expired on %s (404)", t.endpoint)
+		}
+		return nil, fmt.Errorf("MCP server %q returned %s: %s", t.server, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (t *mcpHTTPTransport) hasSession() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID != ""
+}
+
+func (t *mcpHTTPTransport) call(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	ctype, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if ctype == "text/event-stream" {
+		// The stream may carry server requests and notifications before our response.
+		var result *JSONRPCMessage
+		err := readSSE(resp.Body, func(event, data string) bool {
+			if event != "" && event != "message" {
+				return true
+			}
+			var m JSONRPCMessage
+			if json.Unmarshal([]byte(data), &m) != nil {
+				return true
+			}
+			if m.IsResponse() && string(m.ID) == string(msg.ID) {
+				result = &m
+				return false
+			}
+			t.disp.handle(&m)
+			return true
+		})
+		if result != nil {
+			return result, nil
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("MCP server %q closed the stream without a response: %w", t.server, err)
+	}
+	var result JSONRPCMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, mcpMaxMessage)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding MCP response: %w", err)
+	}
+	return &result, nil
+}
+
+func (t *mcpHTTPTransport) notify(ctx context.Context, msg *JSONRPCMessage) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close ends the session; servers may not support DELETE, so errors are ignored.
+func (t *mcpHTTPTransport) close() error {
+	if !t.hasSession() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return nil
+	}
+	if resp, err := t.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------- legacy SSE
+
+// mcpSSETransport implements the 2024-11-05 HTTP+SSE transport: a long-lived
+// GET stream carries every server message, and the first "endpoint" event
+// names the URL to POST client messages to.
+type mcpSSETransport struct {
+	server  string
+	headers map[string]string
+	client  *http.Client
+	disp    *mcpDispatcher
+	post    string
+	cancel  context.CancelFunc
+}
+
+func newMcpSSETransport(ctx context.Context, server, streamURL string, headers map[string]string) (*mcpSSETransport, error) {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("MCP server %q returned %s for the SSE stream", server, resp.Status)
+	}
+
+	t := &mcpSSETransport{server: server, headers: headers, client: client, disp: newMcpDispatcher(server), cancel: cancel}
+	t.disp.reply = func(msg *JSONRPCMessage) error { return t.send(context.Background(), msg) }
+	endpoint := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		err := readSSE(resp.Body, func(event, data string) bool {
+			if event == "endpoint" {
+				select {
+				case endpoint <- data:
+				default:
+				}
+				return true
+			}
+			var m JSONRPCMessage
+			if json.Unmarshal([]byte(data), &m) == nil {
+				t.disp.handle(&m)
+			}
+			return true
+		})
+		if err == nil {
+			err = io.EOF
+		}
+		t.disp.fail(fmt.Errorf("MCP server %q closed the SSE stream: %v", server, err))
+		close(endpoint)
+	}()
+
+	select {
+	case ep, ok := <-endpoint:
+		if !ok {
+			cancel()
+			return nil, t.disp.failure()
+		}
+		base, _ := url.Parse(streamURL)
+		ref, err := url.Parse(ep)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("bad endpoint event %q: %w", ep, err)
+		}
+		t.post = base.ResolveReference(ref).String()
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	return t, nil
+}
+
+func (t *mcpSSETransport) send(ctx context.Context, msg *JSONRPCMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.post, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("MCP server %q returned %s: %s", t.server, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (t *mcpSSETransport) call(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+	ch, err := t.disp.register(msg.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.send(ctx, msg); err != nil {
+		t.disp.forget(msg.ID)
+		return nil, err
+	}
+	return t.disp.wait(ctx, msg.ID, ch)
+}
+
+func (t *mcpSSETransport) notify(ctx context.Context, msg *JSONRPCMessage) error {
+	return t.send(ctx, msg)
+}
+
+func (t *mcpSSETransport) setProtocolVersion(string) {}
+
+func (t *mcpSSETransport) close() error {
+	t.cancel()
+	return nil
+}
+
+// readSSE parses a text/event-stream, calling fn for every event until fn
+// returns false or the stream ends. A clean end of stream returns nil.
+func readSSE(r io.Reader, fn func(event, data string) bool) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), mcpMaxMessage)
+	var event string
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if !fn(event, strings.Join(data, "\n")) {
+					return nil
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if len(data) > 0 {
+		fn(event, strings.Join(data, "\n"))
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
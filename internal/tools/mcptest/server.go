@@ -0,0 +1,314 @@
+// Package mcptest provides a small in-process MCP server for exercising the
+// MCP client over stdio, streamable HTTP and legacy SSE without a real server.
+// Run it with "playground mcp-fake".
+package mcptest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/tools"
+	"github.com/google/uuid"
+)
+
+// Handler implements one fake tool.
+type Handler func(ctx context.Context, args map[string]interface{}) tools.McpCallToolResult
+
+type fakeTool struct {
+	info    tools.McpToolInfo
+	handler Handler
+}
+
+// Server answers initialize, ping, tools/list (paged by PageSize) and
+// tools/call. Every received message is kept in Received for inspection.
+type Server struct {
+	Name     string
+	PageSize int  // tools per tools/list page; 0 = all in one page
+	SSE      bool // streamable HTTP: reply as text/event-stream instead of JSON
+
+	mu       sync.Mutex
+	tools    []fakeTool
+	Received []tools.JSONRPCMessage
+
+	sseMu       sync.Mutex
+	sseSessions map[string]chan []byte
+}
+
+// NewServer returns a server with the default tools: echo, add, fail and sleep.
+func NewServer() *Server {
+	s := &Server{Name: "mcptest", sseSessions: map[string]chan []byte{}}
+	s.AddTool(tools.McpToolInfo{
+		Name:        "echo",
+		Description: "Echo the given text back.",
+		InputSchema: objectSchema(map[string]interface{}{"text": map[string]interface{}{"type": "string"}}, "text"),
+	}, func(_ context.Context, args map[string]interface{}) tools.McpCallToolResult {
+		return tools.McpTextResult(fmt.Sprint(args["text"]), false)
+	})
+	s.AddTool(tools.McpToolInfo{
+		Name:        "add",
+		Description: "Add two numbers.",
+		InputSchema: objectSchema(map[string]interface{}{
+			"a": map[string]interface{}{"type": "number"},
+			"b": map[string]interface{}{"type": "number"},
+		}, "a", "b"),
+	}, func(_ context.Context, args map[string]interface{}) tools.McpCallToolResult {
+		a, okA := args["a"].(float64)
+		b, okB := args["b"].(float64)
+		if !okA || !okB {
+			return tools.McpTextResult("a and b must be numbers", true)
+		}
+		res := tools.McpTextResult(fmt.Sprint(a+b), false)
+		res.StructuredContent = map[string]interface{}{"sum": a + b}
+		return res
+	})
+	s.AddTool(tools.McpToolInfo{
+		Name:        "fail",
+		Description: "Always fails with the given message.",
+		InputSchema: objectSchema(map[string]interface{}{"message": map[string]interface{}{"type": "string"}}),
+	}, func(_ context.Context, args map[string]interface{}) tools.McpCallToolResult {
+		msg, _ := args["message"].(string)
+		if msg == "" {
+			msg = "tool failed"
+		}
+		return tools.McpTextResult(msg, true)
+	})
+	s.AddTool(tools.McpToolInfo{
+		Name:        "sleep",
+		Description: "Sleep for the given number of milliseconds.",
+		InputSchema: objectSchema(map[string]interface{}{"ms": map[string]interface{}{"type": "integer"}}, "ms"),
+	}, func(ctx context.Context, args map[string]interface{}) tools.McpCallToolResult {
+		ms, _ := args["ms"].(float64)
+		select {
+		case <-time.After(time.Duration(ms) * time.Millisecond):
+			return tools.McpTextResult("done", false)
+		case <-ctx.Done():
+			return tools.McpTextResult("cancelled", true)
+		}
+	})
+	return s
+}
+
+func objectSchema(props map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// AddTool registers a fake tool (replacing one with the same name).
+func (s *Server) AddTool(info tools.McpToolInfo, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.tools {
+		if t.info.Name == info.Name {
+			s.tools[i] = fakeTool{info, h}
+			return
+		}
+	}
+	s.tools = append(s.tools, fakeTool{info, h})
+}
+
+// Handle processes one message and returns the response (nil for notifications).
+func (s *Server) Handle(ctx context.Context, msg *tools.JSONRPCMessage) *tools.JSONRPCMessage {
+	s.mu.Lock()
+	s.Received = append(s.Received, *msg)
+	s.mu.Unlock()
+	if !msg.IsRequest() {
+		return nil
+	}
+	switch msg.Method {
+	case "initialize":
+		var p tools.McpInitializeParams
+		json.Unmarshal(msg.Params, &p)
+		version := p.ProtocolVersion
+		if version == "" {
+			version = tools.McpProtocolVersion
+		}
+		return tools.NewJSONRPCResult(msg.ID, tools.McpInitializeResult{
+			ProtocolVersion: version,
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+			ServerInfo:      tools.McpImplementation{Name: s.Name, Version: "test"},
+		})
+	case "ping":
+		return tools.NewJSONRPCResult(msg.ID, struct{}{})
+	case "tools/list":
+		return tools.NewJSONRPCResult(msg.ID, s.listTools(msg.Params))
+	case "tools/call":
+		var p tools.McpCallToolParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return tools.NewJSONRPCError(msg.ID, tools.JSONRPCInvalidParams, err.Error())
+		}
+		s.mu.Lock()
+		var h Handler
+		for _, t := range s.tools {
+			if t.info.Name == p.Name {
+				h = t.handler
+			}
+		}
+		s.mu.Unlock()
+		if h == nil {
+			return tools.NewJSONRPCError(msg.ID, tools.JSONRPCInvalidParams, "unknown tool: "+p.Name)
+		}
+		return tools.NewJSONRPCResult(msg.ID, h(ctx, p.Arguments))
+	}
+	return tools.NewJSONRPCError(msg.ID, tools.JSONRPCMethodNotFound, "method not found: "+msg.Method)
+}
+
+func (s *Server) listTools(params json.RawMessage) tools.McpListToolsResult {
+	var p tools.McpListToolsParams
+	json.Unmarshal(params, &p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := 0
+	fmt.Sscan(p.Cursor, &start)
+	end := len(s.tools)
+	if s.PageSize > 0 && start+s.PageSize < end {
+		end = start + s.PageSize
+	}
+	res := tools.McpListToolsResult{Tools: []tools.McpToolInfo{}}
+	if start < len(s.tools) {
+		for _, t := range s.tools[start:end] {
+			res.Tools = append(res.Tools, t.info)
+		}
+	}
+	if end < len(s.tools) {
+		res.NextCursor = fmt.Sprint(end)
+	}
+	return res
+}
+
+// ServeStdio speaks newline-delimited JSON-RPC until r is closed.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var msg tools.JSONRPCMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			writeMu.Lock()
+			writeJSONLine(w, tools.NewJSONRPCError(nil, tools.JSONRPCParseError, err.Error()))
+			writeMu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := s.Handle(ctx, &msg); resp != nil {
+				writeMu.Lock()
+				writeJSONLine(w, resp)
+				writeMu.Unlock()
+			}
+		}()
+	}
+	return sc.Err()
+}
+
+func writeJSONLine(w io.Writer, msg *tools.JSONRPCMessage) {
+	data, _ := json.Marshal(msg)
+	w.Write(append(data, '\n'))
+}
+
+// ServeHTTP implements the streamable HTTP transport on a single endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var msg tools.JSONRPCMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := s.Handle(r.Context(), &msg)
+	if msg.Method == "initialize" {
+		w.Header().Set("Mcp-Session-Id", uuid.New().String())
+	}
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	data, _ := json.Marshal(resp)
+	if s.SSE {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": keep-alive\n\nevent: message\ndata: %s\n\n", data)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// SSEHandler implements the legacy HTTP+SSE transport: GET <base>/sse opens
+// the event stream, POST <base>/message?session=<id> delivers client messages.
+func (s *Server) SSEHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		id := uuid.New().String()
+		ch := make(chan []byte, 16)
+		s.sseMu.Lock()
+		s.sseSessions[id] = ch
+		s.sseMu.Unlock()
+		defer func() {
+			s.sseMu.Lock()
+			delete(s.sseSessions, id)
+			s.sseMu.Unlock()
+		}()
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: message?session=%s\n\n", id)
+		flusher.Flush()
+		for {
+			select {
+			case data := <-ch:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		s.sseMu.Lock()
+		ch, ok := s.sseSessions[r.URL.Query().Get("session")]
+		s.sseMu.Unlock()
+		if !ok {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		var msg tools.JSONRPCMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			if resp := s.Handle(context.Background(), &msg); resp != nil {
+				data, _ := json.Marshal(resp)
+				ch <- data
+			}
+		}()
+	})
+	return mux
+}
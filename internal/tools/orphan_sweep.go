@@ -0,0 +1,101 @@
+// internal/tools/orphan_sweep.go
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// Labels put on every container and network this process creates, so a
+// later run can find leftovers without relying on name prefixes.
+const (
+	LabelManaged   = "gogen.managed"
+	LabelOwner     = "gogen.owner"     // "<hostname>:<pid>" of the creating process
+	LabelWorkspace = "gogen.workspace" // host workspace directory, removed with the container
+)
+
+// ownerID identifies this process in LabelOwner.
+func ownerID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// managedLabelArgs returns the --label flags for docker run / network create.
+func managedLabelArgs(workspace string) []string {
+	args := []string{"--label", LabelManaged + "=true", "--label", LabelOwner + "=" + ownerID()}
+	if workspace != "" {
+		args = append(args, "--label", LabelWorkspace+"="+workspace)
+	}
+	return args
+}
+
+// SweepOrphans removes managed containers and networks whose owning process
+// on this host is gone (e.g. after a crash or kill -9), together with their
+// workspaces. Resources of other live processes and other hosts are left alone.
+func SweepOrphans(ctx context.Context) (int, error) {
+	out, err := exec.CommandContext(ctx, "docker", "ps", "-a",
+		"--filter", "label="+LabelManaged+"=true",
+		"--format", `{{.ID}}|{{.Label "`+LabelOwner+`"}}|{{.Label "`+LabelWorkspace+`"}}`).Output()
+	if err != nil {
+		return 0, fmt.Errorf("listing managed containers: %w", err)
+	}
+	removed := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "|", 3)
+		if len(parts) != 3 || !ownerGone(parts[1]) {
+			continue
+		}
+		if err := exec.CommandContext(ctx, "docker", "rm", "-f", parts[0]).Run(); err != nil {
+			utils.Logger.Warn().Str("container", parts[0]).Err(err).Msg("Failed to remove orphaned container")
+			continue
+		}
+		removeWorkspaceDir(parts[2])
+		removed++
+		utils.Logger.Info().Str("container", parts[0]).Str("owner", parts[1]).Msg("Removed orphaned container")
+	}
+
+	out, err = exec.CommandContext(ctx, "docker", "network", "ls",
+		"--filter", "label="+LabelManaged+"=true",
+		"--format", `{{.ID}}|{{.Label "`+LabelOwner+`"}}`).Output()
+	if err != nil {
+		return removed, fmt.Errorf("listing managed networks: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "|", 2)
+		if len(parts) == 2 && ownerGone(parts[1]) {
+			exec.CommandContext(ctx, "docker", "network", "rm", parts[0]).Run()
+		}
+	}
+	return removed, nil
+}
+
+// ownerGone reports whether the owner label names a dead process on this host.
+func ownerGone(owner string) bool {
+	host, pidStr, found := strings.Cut(owner, ":")
+	if !found {
+		return false
+	}
+	if me, _ := os.Hostname(); host != me {
+		return false
+	}
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil || pid <= 0 {
+		return false
+	}
+	return pid != os.Getpid() && !processAlive(pid)
+}
+
+// removeWorkspaceDir only deletes directories that look like ours.
+func removeWorkspaceDir(dir string) {
+	if dir == "" || filepath.Dir(dir) != filepath.Clean(os.TempDir()) || !strings.HasPrefix(filepath.Base(dir), "dockerexec-") {
+		return
+	}
+	os.RemoveAll(dir)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package tools
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive uses signal 0, which checks existence without signalling.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return !errors.Is(err, syscall.ESRCH)
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package tools
+
+// processAlive cannot cheaply probe a pid on Windows; assume alive so the
+// sweep never removes containers of a running process.
+func processAlive(pid int) bool {
+	return true
+}
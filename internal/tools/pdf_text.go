@@ -0,0 +1,375 @@
+// internal/tools/pdf_text.go
+// Best-effort plain text of PDF and LaTeX files, without external tools.
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPDFStream bounds one inflated content stream.
+const maxPDFStream = 16 << 20
+
+// extractPDFText returns the text shown by the Tj, TJ, ' and " operators of
+// the PDF's content streams (FlateDecode or uncompressed). Fonts with
+// custom encodings (e.g. CID fonts without a usable byte mapping) come out
+// garbled or empty; pdftotext does better when installed.
+func extractPDFText(data []byte) string {
+	var out strings.Builder
+	for _, stream := range pdfStreams(data) {
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue // images, fonts, ...
+		}
+		if text := pdfContentText(stream); strings.TrimSpace(text) != "" {
+			out.WriteString(text)
+			out.WriteString("\n")
+		}
+	}
+	return cleanText(out.String())
+}
+
+// pdfStreams returns the (inflated when possible) bodies of all streams.
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+	for {
+		i := bytes.Index(data, []byte("stream"))
+		if i < 0 {
+			break
+		}
+		if i >= 3 && string(data[i-3:i]) == "end" { // "endstream" without a start
+			data = data[i+6:]
+			continue
+		}
+		body := data[i+6:]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := body[:end]
+		data = body[end+9:]
+		if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+			inflated, err := io.ReadAll(io.LimitReader(zr, maxPDFStream))
+			// Truncated or padded streams still yield their text.
+			if len(inflated) > 0 || err == nil {
+				streams = append(streams, inflated)
+				continue
+			}
+		}
+		streams = append(streams, raw)
+	}
+	return streams
+}
+
+// pdfContentText interprets the text operators of one content stream.
+func pdfContentText(content []byte) string {
+	var (
+		out      strings.Builder
+		operands []interface{} // float64, []byte (string) or []interface{} (array)
+		inText   bool
+	)
+	sc := &pdfScanner{data: content}
+	for {
+		tok, ok := sc.next()
+		if !ok {
+			break
+		}
+		switch v := tok.(type) {
+		case pdfOperator:
+			switch string(v) {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				out.WriteString("\n")
+			case "Tj":
+				writePDFStrings(&out, operands)
+			case "'", "\"":
+				out.WriteString("\n")
+				writePDFStrings(&out, operands)
+			case "TJ":
+				for _, op := range operands {
+					arr, _ := op.([]interface{})
+					for _, e := range arr {
+						switch x := e.(type) {
+						case []byte:
+							out.WriteString(pdfDecodeString(x))
+						case float64:
+							if x < -200 { // a kerning gap this wide is a word space
+								out.WriteString(" ")
+							}
+						}
+					}
+				}
+			case "T*":
+				out.WriteString("\n")
+			case "Td", "TD":
+				if len(operands) == 2 {
+					if ty, _ := operands[1].(float64); ty != 0 {
+						out.WriteString("\n")
+					} else if tx, _ := operands[0].(float64); tx > 0 {
+						out.WriteString(" ")
+					}
+				}
+			case "Tm":
+				if inText {
+					out.WriteString("\n")
+				}
+			}
+			operands = operands[:0]
+		default:
+			operands = append(operands, tok)
+		}
+	}
+	return out.String()
+}
+
+func writePDFStrings(out *strings.Builder, operands []interface{}) {
+	for _, op := range operands {
+		if s, ok := op.([]byte); ok {
+			out.WriteString(pdfDecodeString(s))
+		}
+	}
+}
+
+// pdfLigatures maps the TeX font positions of ligatures.
+var pdfLigatures = map[byte]string{0x0B: "ff", 0x0C: "fi", 0x0D: "fl", 0x0E: "ffi", 0x0F: "ffl"}
+
+// pdfDecodeString maps string bytes to text: UTF-16 with a BOM, else
+// Latin-1 with TeX ligatures; other control bytes are dropped.
+func pdfDecodeString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		var r []rune
+		for i := 2; i+1 < len(b); i += 2 {
+			r = append(r, rune(b[i])<<8|rune(b[i+1]))
+		}
+		return string(r)
+	}
+	var s strings.Builder
+	for _, c := range b {
+		switch {
+		case pdfLigatures[c] != "":
+			s.WriteString(pdfLigatures[c])
+		case c >= 0x20 && c != 0x7F:
+			s.WriteRune(rune(c))
+		}
+	}
+	return s.String()
+}
+
+type pdfOperator string
+
+// pdfScanner splits a content stream into numbers, strings, arrays and
+// operators. Names, dictionaries and inline images are skipped.
+type pdfScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *pdfScanner) next() (interface{}, bool) {
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case isPDFSpace(c):
+			s.pos++
+		case c == '%':
+			for s.pos < len(s.data) && s.data[s.pos] != '\n' && s.data[s.pos] != '\r' {
+				s.pos++
+			}
+		case c == '(':
+			return s.literal(), true
+		case c == '<' && s.peek(1) == '<':
+			s.pos += 2
+		case c == '>' && s.peek(1) == '>':
+			s.pos += 2
+		case c == '<':
+			return s.hex(), true
+		case c == '[':
+			s.pos++
+			var arr []interface{}
+			for {
+				tok, ok := s.next()
+				if !ok {
+					return arr, true
+				}
+				if op, isOp := tok.(pdfOperator); isOp && op == "]" {
+					return arr, true
+				}
+				arr = append(arr, tok)
+			}
+		case c == ']':
+			s.pos++
+			return pdfOperator("]"), true
+		case c == '/':
+			s.pos++
+			s.word() // names are operands we do not need
+		case c == '{' || c == '}':
+			s.pos++
+		default:
+			w := s.word()
+			if w == "" {
+				s.pos++
+				continue
+			}
+			if f, err := strconv.ParseFloat(w, 64); err == nil {
+				return f, true
+			}
+			if w == "BI" { // inline image: skip to EI
+				if i := bytes.Index(s.data[s.pos:], []byte("EI")); i >= 0 {
+					s.pos += i + 2
+				} else {
+					s.pos = len(s.data)
+				}
+				continue
+			}
+			return pdfOperator(w), true
+		}
+	}
+	return nil, false
+}
+
+func (s *pdfScanner) peek(n int) byte {
+	if s.pos+n < len(s.data) {
+		return s.data[s.pos+n]
+	}
+	return 0
+}
+
+func (s *pdfScanner) word() string {
+	start := s.pos
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if isPDFSpace(c) || strings.IndexByte("()<>[]{}/%", c) >= 0 {
+			break
+		}
+		s.pos++
+	}
+	return string(s.data[start:s.pos])
+}
+
+// literal reads a (string) with nested parentheses and escapes.
+func (s *pdfScanner) literal() []byte {
+	s.pos++ // (
+	var out []byte
+	depth := 1
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		s.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if s.pos >= len(s.data) {
+				return out
+			}
+			e := s.data[s.pos]
+			s.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n': // line continuation
+				if e == '\r' && s.pos < len(s.data) && s.data[s.pos] == '\n' {
+					s.pos++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for k := 0; k < 2 && s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '7'; k++ {
+						n = n*8 + int(s.data[s.pos]-'0')
+						s.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// hex reads a <hex string>.
+func (s *pdfScanner) hex() []byte {
+	s.pos++ // <
+	var digits []byte
+	for s.pos < len(s.data) && s.data[s.pos] != '>' {
+		if c := s.data[s.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		s.pos++
+	}
+	s.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			continue
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+var (
+	latexComment     = regexp.MustCompile(`(?m)(^|[^\\])%.*$`)
+	latexDropped     = regexp.MustCompile(`\\(?:cite[a-zA-Z]*|ref|eqref|autoref|cref|label|includegraphics|bibliography|bibliographystyle|input|include|usepackage|documentclass|url|vspace|hspace)\*?(?:\[[^\]]*\])*(?:\{[^{}]*\})?`)
+	latexHeading     = regexp.MustCompile(`\\(?:part|chapter|section|subsection|subsubsection|paragraph)\*?(?:\[[^\]]*\])?\{([^{}]*)\}`)
+	latexEnvironment = regexp.MustCompile(`\\(?:begin|end)\{[^{}]*\}(?:\[[^\]]*\])?`)
+	latexCommand     = regexp.MustCompile(`\\[a-zA-Z]+\*?(?:\[[^\]]*\])?`)
+	latexSpaces      = regexp.MustCompile(`[ \t]+`)
+	blankLines       = regexp.MustCompile(`\n\s*\n\s*(\n\s*)+`)
+)
+
+// latexText strips LaTeX markup from a source file, keeping the document
+// body, headings and prose; math is left as written.
+func latexText(src string) string {
+	src = latexComment.ReplaceAllString(src, "$1")
+	if i := strings.Index(src, `\begin{document}`); i >= 0 {
+		src = src[i+len(`\begin{document}`):]
+	}
+	if i := strings.Index(src, `\end{document}`); i >= 0 {
+		src = src[:i]
+	}
+	src = latexHeading.ReplaceAllString(src, "\n\n$1\n\n")
+	src = latexDropped.ReplaceAllString(src, "")
+	src = latexEnvironment.ReplaceAllString(src, "\n")
+	src = strings.NewReplacer(`\\`, "\n", `\%`, "%", `\&`, "&", `\_`, "_", `\#`, "#", `\$`, "$", "~", " ", "``", `"`, "''", `"`).Replace(src)
+	src = latexCommand.ReplaceAllString(src, "")
+	src = strings.NewReplacer("{", "", "}", "").Replace(src)
+	return cleanText(src)
+}
+
+// cleanText collapses runs of spaces and blank lines.
+func cleanText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(latexSpaces.ReplaceAllString(l, " "))
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,366 @@
+// internal/tools/plugin_tool.go
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/metrics"
+	"aiupstart.com/go-gen/internal/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultPluginTimeout     = 30 * time.Second
+	defaultPluginMaxRestarts = 3
+	pluginDescribeTimeout    = 10 * time.Second
+)
+
+// errPluginTimeout marks a call the plugin did not answer in time.
+var errPluginTimeout = errors.New("plugin did not answer in time")
+
+// PluginTool runs one tool in a child process. The plugin protocol is one
+// JSON object per line on stdin/stdout:
+//
+//	-> {"id":1,"method":"describe"}
+//	<- {"id":1,"result":{"name":"word_count","description":"...","schema":{...}}}
+//	-> {"id":2,"method":"call","params":{"args":{...}}}
+//	<- {"id":2,"result":<any JSON>}   or   {"id":2,"error":"message"}
+//
+// stderr goes to the log and closing stdin asks the plugin to exit. Calls
+// are sent one at a time. A call that times out kills the process; a process
+// that died is restarted on the next call, up to max_restarts times in a row
+// (default 3, 0 never restarts; a call the plugin answers resets the count).
+type PluginTool struct {
+	cfg     config.PluginConfig
+	env     []string
+	timeout time.Duration
+
+	mu       sync.Mutex // one call at a time
+	proc     *pluginProcess
+	nextID   int64
+	failures int // consecutive crashes/timeouts
+	closed   bool
+
+	infoMu sync.RWMutex // info is read while a call holds mu
+	info   pluginInfo
+}
+
+type pluginInfo struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+type pluginMessage struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method,omitempty"`
+	Params interface{}     `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// pluginProcess is one run of the plugin executable.
+type pluginProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan []byte
+	stop    chan struct{} // closed by kill; unblocks the stdout reader
+	stopped sync.Once
+	exited  chan struct{}
+	waitErr error // set before exited is closed
+}
+
+// StartPlugin starts the plugin of cfg and asks it to describe its tool.
+// ${NAME} references in env are expanded with lookup (nil: environment).
+// The plugin gets only PATH, HOME and TMPDIR from our environment besides
+// its env, so secrets reach it only when configured.
+func StartPlugin(ctx context.Context, cfg config.PluginConfig, lookup SecretLookup) (*PluginTool, error) {
+	t := &PluginTool{cfg: cfg, timeout: defaultPluginTimeout}
+	if cfg.TimeoutSeconds > 0 {
+		t.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	t.env = childEnv(expandMap(cfg.Env, lookup))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.start(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// StartPlugins starts every enabled plugin and registers its tool. A plugin
+// that fails to start is logged and skipped. The returned tools must be
+// closed.
+func StartPlugins(ctx context.Context, plugins []config.PluginConfig, registry *ToolRegistry, lookup SecretLookup) []*PluginTool {
+	var started []*PluginTool
+	for _, cfg := range plugins {
+		if cfg.Disabled {
+			continue
+		}
+		t, err := StartPlugin(ctx, cfg, lookup)
+		if err != nil {
+			utils.Logger.Error().Str("plugin", cfg.Name).Err(err).Msg("Plugin unavailable")
+			continue
+		}
+		registry.Register(t)
+		utils.Logger.Info().Str("plugin", cfg.Name).Str("command", cfg.Command).Msg("Registered plugin tool")
+		started = append(started, t)
+	}
+	return started
+}
+
+func (t *PluginTool) Name() string { return t.cfg.Name }
+
+func (t *PluginTool) Description() string {
+	t.infoMu.RLock()
+	defer t.infoMu.RUnlock()
+	return t.info.Description
+}
+
+// Parameters returns the schema from the plugin's describe answer.
+func (t *PluginTool) Parameters() map[string]interface{} {
+	t.infoMu.RLock()
+	defer t.infoMu.RUnlock()
+	if t.info.Schema == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.info.Schema
+}
+
+// Metadata carries the routing settings of the tool's plugins entry.
+func (t *PluginTool) Metadata() ToolMetadata {
+	return ToolMetadata{Executor: t.cfg.Executor, RequiresApproval: t.cfg.RequiresApproval}
+}
+
+func (t *PluginTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	args := call.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	res, err := t.call(ctx, args)
+	if err != nil {
+		metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+		return ToolResult{
+			Error:       fmt.Errorf("plugin %s: %w", t.Name(), err),
+			ErrorDetail: &ExecErrorDetail{Phase: "plugin", Command: t.cfg.Command, ErrMsg: err.Error()},
+		}
+	}
+	var out interface{}
+	if err := json.Unmarshal(res, &out); err != nil {
+		out = string(res)
+	}
+	return ToolResult{Output: out}
+}
+
+// call sends one call, (re)starting the process when needed.
+func (t *PluginTool) call(ctx context.Context, args map[string]interface{}) (json.RawMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, errors.New("plugin is closed")
+	}
+	if t.proc != nil && t.proc.done() { // crashed between calls
+		t.failures++
+		t.proc = nil
+	}
+	if t.proc == nil {
+		maxRestarts := defaultPluginMaxRestarts
+		if t.cfg.MaxRestarts != nil {
+			maxRestarts = *t.cfg.MaxRestarts
+		}
+		if t.failures > maxRestarts {
+			return nil, fmt.Errorf("plugin failed %d times in a row; not restarting it (restart the session after fixing it)", t.failures)
+		}
+		utils.Logger.Warn().Str("plugin", t.cfg.Name).Int("failures", t.failures).Msg("Restarting plugin")
+		if err := t.start(ctx); err != nil {
+			t.failures++
+			return nil, err
+		}
+	}
+	res, err := t.request(ctx, "call", map[string]interface{}{"args": args}, t.timeout)
+	var perr pluginError
+	switch {
+	case err == nil, errors.As(err, &perr):
+		t.failures = 0 // the plugin answered
+	default:
+		t.failures++
+		t.proc.kill() // restarted by the next call
+		t.proc = nil
+	}
+	return res, err
+}
+
+// start launches the process and reads its description. The caller holds mu.
+func (t *PluginTool) start(ctx context.Context) error {
+	if t.proc != nil {
+		t.proc.kill()
+		t.proc = nil
+	}
+	p, err := startPluginProcess(t.cfg, t.env)
+	if err != nil {
+		return err
+	}
+	t.proc = p
+	res, err := t.request(ctx, "describe", nil, pluginDescribeTimeout)
+	var info pluginInfo
+	if err == nil {
+		if jerr := json.Unmarshal(res, &info); jerr != nil {
+			err = fmt.Errorf("invalid answer: %w", jerr)
+		}
+	}
+	if err != nil {
+		p.kill()
+		t.proc = nil
+		return fmt.Errorf("describe: %w", err)
+	}
+	if info.Name != "" && info.Name != t.cfg.Name {
+		utils.Logger.Warn().Str("plugin", t.cfg.Name).Str("described", info.Name).Msg("Plugin describes a different tool name; registering it under the configured name")
+	}
+	t.infoMu.Lock()
+	t.info = info
+	t.infoMu.Unlock()
+	return nil
+}
+
+// pluginError is an error answered by the plugin itself; the process is fine.
+type pluginError string
+
+func (e pluginError) Error() string { return string(e) }
+
+// request writes one message and waits for the answer with the same id. On
+// timeout or cancellation the process is killed, since a late answer would
+// be read as the answer to the next request. The caller holds mu.
+func (t *PluginTool) request(ctx context.Context, method string, params interface{}, timeout time.Duration) (json.RawMessage, error) {
+	p := t.proc
+	t.nextID++
+	id := t.nextID
+	data, err := json.Marshal(pluginMessage{ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		p.kill()
+		return nil, fmt.Errorf("writing to plugin: %w", err)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line := <-p.lines:
+			var msg pluginMessage
+			if err := json.Unmarshal(line, &msg); err != nil || msg.ID != id {
+				utils.Logger.Warn().Str("plugin", t.cfg.Name).Msgf("Ignoring unexpected plugin output: %.200s", line)
+				continue
+			}
+			if msg.Error != "" {
+				return nil, pluginError(msg.Error)
+			}
+			return msg.Result, nil
+		case <-p.exited:
+			return nil, fmt.Errorf("plugin exited: %v", p.waitErr)
+		case <-timer.C:
+			p.kill()
+			return nil, fmt.Errorf("%w (%s); killed it", errPluginTimeout, timeout)
+		case <-ctx.Done():
+			p.kill()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close ends stdin and kills the plugin if it does not exit promptly.
+func (t *PluginTool) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	if t.proc == nil {
+		return nil
+	}
+	t.proc.stdin.Close()
+	t.proc.stopped.Do(func() { close(t.proc.stop) }) // drop unread output
+	select {
+	case <-t.proc.exited:
+	case <-time.After(3 * time.Second):
+		t.proc.kill()
+	}
+	return nil
+}
+
+func startPluginProcess(cfg config.PluginConfig, env []string) (*pluginProcess, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting plugin %q: %w", cfg.Name, err)
+	}
+	p := &pluginProcess{cmd: cmd, stdin: stdin, lines: make(chan []byte), stop: make(chan struct{}), exited: make(chan struct{})}
+
+	go func() {
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			utils.Logger.Debug().Str("plugin", cfg.Name).Msg(sc.Text())
+		}
+	}()
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(make([]byte, 64*1024), mcpMaxMessage)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			select {
+			case p.lines <- append([]byte(nil), line...):
+			case <-p.stop:
+			}
+		}
+		p.waitErr = cmd.Wait()
+		if err := sc.Err(); err != nil {
+			p.waitErr = err
+		}
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+func (p *pluginProcess) done() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *pluginProcess) kill() {
+	p.stopped.Do(func() { close(p.stop) })
+	if p.cmd.Process != nil && !p.done() {
+		p.cmd.Process.Kill()
+	}
+}
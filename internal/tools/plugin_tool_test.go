@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+)
+
+var (
+	echoPluginOnce sync.Once
+	echoPluginPath string
+	echoPluginErr  error
+)
+
+// echoPlugin builds testdata/echoplugin once per test run and returns the
+// path of the executable.
+func echoPlugin(t *testing.T) string {
+	t.Helper()
+	echoPluginOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "echoplugin")
+		if err != nil {
+			echoPluginErr = err
+			return
+		}
+		echoPluginPath = filepath.Join(dir, "echoplugin")
+		out, err := exec.Command("go", "build", "-o", echoPluginPath, "./testdata/echoplugin").CombinedOutput()
+		if err != nil {
+			echoPluginErr = fmt.Errorf("building echoplugin: %v\n%s", err, out)
+		}
+	})
+	if echoPluginErr != nil {
+		t.Fatal(echoPluginErr)
+	}
+	return echoPluginPath
+}
+
+func helperPlugin(t *testing.T, maxRestarts *int) *PluginTool {
+	t.Helper()
+	return startEchoPlugin(t, config.PluginConfig{MaxRestarts: maxRestarts}, nil)
+}
+
+func startEchoPlugin(t *testing.T, cfg config.PluginConfig, lookup SecretLookup) *PluginTool {
+	t.Helper()
+	cfg.Name = "echo"
+	cfg.Command = echoPlugin(t)
+	cfg.TimeoutSeconds = 1
+	p, err := StartPlugin(context.Background(), cfg, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func callPlugin(p *PluginTool, args map[string]interface{}) ToolResult {
+	return p.Call(context.Background(), ToolCall{Name: p.Name(), Args: args, Caller: "test"})
+}
+
+func pluginPID(t *testing.T, res ToolResult) float64 {
+	t.Helper()
+	if res.Error != nil {
+		t.Fatalf("call failed: %v", res.Error)
+	}
+	out, _ := res.Output.(map[string]interface{})
+	pid, _ := out["pid"].(float64)
+	if pid == 0 {
+		t.Fatalf("output = %v, want an echo with a pid", res.Output)
+	}
+	return pid
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestPluginHandshakeAndCall(t *testing.T) {
+	p := helperPlugin(t, nil)
+	if p.Name() != "echo" || p.Description() != "Echo the arguments." {
+		t.Errorf("name %q, description %q from the describe answer", p.Name(), p.Description())
+	}
+	if props, _ := p.Parameters()["properties"].(map[string]interface{}); props["text"] == nil {
+		t.Errorf("parameters = %v, want the described schema", p.Parameters())
+	}
+
+	res := callPlugin(p, map[string]interface{}{"text": "hi"})
+	pid := pluginPID(t, res)
+	if echo := res.Output.(map[string]interface{})["echo"].(map[string]interface{}); echo["text"] != "hi" {
+		t.Errorf("echo = %v", echo)
+	}
+
+	// An error answered by the plugin leaves the process running.
+	res = callPlugin(p, map[string]interface{}{"fail": true})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "asked to fail") {
+		t.Fatalf("err = %v, want the plugin's error", res.Error)
+	}
+	if got := pluginPID(t, callPlugin(p, nil)); got != pid {
+		t.Errorf("plugin restarted after an answered error (pid %v -> %v)", pid, got)
+	}
+}
+
+func TestPluginTimeoutKillsProcess(t *testing.T) {
+	p := helperPlugin(t, nil)
+	pid := pluginPID(t, callPlugin(p, nil))
+
+	start := time.Now()
+	res := callPlugin(p, map[string]interface{}{"sleep": true})
+	if !errors.Is(res.Error, errPluginTimeout) {
+		t.Fatalf("err = %v, want a timeout", res.Error)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("timed out after %v, want about 1s", d)
+	}
+
+	if got := pluginPID(t, callPlugin(p, nil)); got == pid {
+		t.Fatal("the hung process answered the next call; want a restarted plugin")
+	}
+}
+
+func TestPluginRestartsAfterCrash(t *testing.T) {
+	p := helperPlugin(t, intPtr(2))
+	pid := pluginPID(t, callPlugin(p, nil))
+
+	if res := callPlugin(p, map[string]interface{}{"crash": true}); res.Error == nil {
+		t.Fatal("crash reported no error")
+	}
+	if got := pluginPID(t, callPlugin(p, nil)); got == pid {
+		t.Fatal("want a restarted plugin after the crash")
+	}
+
+	// The successful call reset the count: the plugin is restarted after two
+	// crashes in a row, not after a third.
+	for i := 0; i < 3; i++ {
+		if res := callPlugin(p, map[string]interface{}{"crash": true}); res.Error == nil {
+			t.Fatalf("crash %d reported no error", i+1)
+		}
+	}
+	res := callPlugin(p, nil)
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "not restarting") {
+		t.Fatalf("err = %v, want the restart limit", res.Error)
+	}
+}
+
+func TestPluginMaxRestartsZeroNeverRestarts(t *testing.T) {
+	p := helperPlugin(t, intPtr(0))
+	pluginPID(t, callPlugin(p, nil))
+	if res := callPlugin(p, map[string]interface{}{"crash": true}); res.Error == nil {
+		t.Fatal("crash reported no error")
+	}
+	res := callPlugin(p, nil)
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "not restarting") {
+		t.Fatalf("err = %v, want no restart with max_restarts 0", res.Error)
+	}
+}
+
+func TestPluginClose(t *testing.T) {
+	p := helperPlugin(t, nil)
+	pluginPID(t, callPlugin(p, nil))
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if res := callPlugin(p, nil); res.Error == nil || !strings.Contains(res.Error.Error(), "closed") {
+		t.Fatalf("err = %v, want a closed plugin", res.Error)
+	}
+}
+
+func TestWordCountPlugin(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := StartPlugin(context.Background(), config.PluginConfig{
+		Name:    "word_count",
+		Command: "python3",
+		Args:    []string{"plugins/word_count.py"},
+		Dir:     root,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	res := callPlugin(p, map[string]interface{}{"text": "one two\nthree"})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	out, _ := res.Output.(map[string]interface{})
+	if out["lines"] != 2.0 || out["words"] != 3.0 || out["characters"] != 13.0 {
+		t.Errorf("output = %v, want 2 lines, 3 words, 13 characters", res.Output)
+	}
+	if res := callPlugin(p, map[string]interface{}{"text": 42}); res.Error == nil {
+		t.Error("non-string text accepted")
+	}
+}
+
+func TestPluginEnvironment(t *testing.T) {
+	t.Setenv("GOGEN_SECRETS_KEY", "parent-passphrase")
+	t.Setenv("OPENAI_API_KEY", "sk-parent")
+	lookup := secrets("API_TOKEN", "from-secret-store")
+	p := startEchoPlugin(t, config.PluginConfig{Env: map[string]string{"API_TOKEN": "${API_TOKEN}"}}, lookup)
+
+	for name, want := range map[string]interface{}{
+		"GOGEN_SECRETS_KEY": nil,
+		"OPENAI_API_KEY":    nil,
+		"API_TOKEN":         "from-secret-store",
+		"PATH":              os.Getenv("PATH"),
+	} {
+		res := callPlugin(p, map[string]interface{}{"getenv": name})
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		if res.Output != want {
+			t.Errorf("plugin sees %s=%v, want %v", name, res.Output, want)
+		}
+	}
+}
+
+func TestPluginIgnoresUnexpectedOutput(t *testing.T) {
+	p := helperPlugin(t, nil)
+	res := callPlugin(p, map[string]interface{}{"noise": true, "text": "hi"})
+	pluginPID(t, res)
+	if echo := res.Output.(map[string]interface{})["echo"].(map[string]interface{}); echo["text"] != "hi" {
+		t.Errorf("echo = %v, want the answer with the call's id", echo)
+	}
+}
+
+func TestPluginOutputIsRedacted(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.SetRedactor(strings.NewReplacer("s3cr3t-token", "[REDACTED:TOKEN]").Replace)
+	registry.Register(helperPlugin(t, nil))
+	res := registry.Call(context.Background(), ToolCall{Name: "echo", Args: map[string]interface{}{"text": "s3cr3t-token"}})
+	echo := res.Output.(map[string]interface{})["echo"].(map[string]interface{})
+	if echo["text"] != "[REDACTED:TOKEN]" {
+		t.Errorf("echo = %v, want the secret redacted", echo)
+	}
+}
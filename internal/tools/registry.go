@@ -2,43 +2,194 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"sync"
+
+	"aiupstart.com/go-gen/internal/metrics"
+	"aiupstart.com/go-gen/internal/utils"
 )
 
+// ErrToolDenied is wrapped by the error of a call outside the caller's scope.
+var ErrToolDenied = errors.New("tool not allowed")
+
+// ToolScope limits the tools an agent sees and may call: a tool is
+// permitted if it matches an Allow pattern (none allows every tool) and no
+// Deny pattern. Patterns are path.Match globs, e.g. "workspace_*".
+type ToolScope struct {
+    Allow []string
+    Deny  []string
+}
+
+// Permits reports whether the scope lets an agent use the named tool.
+func (s ToolScope) Permits(name string) bool {
+    if len(s.Allow) > 0 && !MatchToolName(s.Allow, name) {
+        return false
+    }
+    return !MatchToolName(s.Deny, name)
+}
+
+// MatchToolName reports whether name matches one of the path.Match glob
+// patterns. Tool scopes, routes and team configs all use this syntax.
+func MatchToolName(patterns []string, name string) bool {
+    for _, p := range patterns {
+        if ok, _ := path.Match(p, name); ok {
+            return true
+        }
+    }
+    return false
+}
+
+// ToolRegistry holds the tools of a session. Scoped returns views limited
+// to one agent's ToolScope; views share the tools of the registry they were
+// made from. Calls are checked against the scope of the calling agent
+// (ToolCall.Caller) and of the view, so an agent cannot run a tool outside
+// its scope through another agent. Agents without a scope may use any tool.
 type ToolRegistry struct {
-    tools map[string]Tool
-    mu    sync.RWMutex
+    tools  map[string]Tool
+    mu     sync.RWMutex
+    redact func(string) string // masks secrets in results before they reach agents/LLM
+    scopes map[string]ToolScope // agent name -> scope
+
+    root  *ToolRegistry // set on views: the registry holding the tools
+    agent string        // agent whose scope limits a view
 }
 
 func NewToolRegistry() *ToolRegistry {
     return &ToolRegistry{
-        tools: make(map[string]Tool),
+        tools:  make(map[string]Tool),
+        scopes: make(map[string]ToolScope),
+    }
+}
+
+// base returns the registry holding the tools and scopes.
+func (r *ToolRegistry) base() *ToolRegistry {
+    if r.root != nil {
+        return r.root
     }
+    return r
+}
+
+// SetScope limits the tools the named agent sees and may call.
+func (r *ToolRegistry) SetScope(agent string, scope ToolScope) {
+    b := r.base()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.scopes[agent] = scope
+}
+
+// Scoped returns a view listing only the tools the agent's scope permits.
+// Tools registered later through either registry appear in both.
+func (r *ToolRegistry) Scoped(agent string) *ToolRegistry {
+    return &ToolRegistry{root: r.base(), agent: agent}
+}
+
+// permits reports whether agent may use the named tool ("" and agents
+// without a scope may use any).
+func (r *ToolRegistry) permits(agent, name string) bool {
+    if agent == "" {
+        return true
+    }
+    b := r.base()
+    b.mu.RLock()
+    scope, ok := b.scopes[agent]
+    b.mu.RUnlock()
+    return !ok || scope.Permits(name)
 }
 
 func (r *ToolRegistry) Register(tool Tool) {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-    r.tools[tool.Name()] = tool
+    b := r.base()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.tools[tool.Name()] = tool
+}
+
+// Unregister removes a tool; views made with Scoped lose it too.
+func (r *ToolRegistry) Unregister(name string) {
+    b := r.base()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    delete(b.tools, name)
+}
+
+// Swap removes and adds tools in one step, so no call or listing sees a
+// half-applied change.
+func (r *ToolRegistry) Swap(remove []string, add []Tool) {
+    b := r.base()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for _, name := range remove {
+        delete(b.tools, name)
+    }
+    for _, t := range add {
+        b.tools[t.Name()] = t
+    }
 }
 
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    tool, ok := r.tools[name]
-    return tool, ok
+    b := r.base()
+    b.mu.RLock()
+    tool, ok := b.tools[name]
+    b.mu.RUnlock()
+    if !ok || !r.permits(r.agent, name) {
+        return nil, false
+    }
+    return tool, true
 }
 
 func (r *ToolRegistry) List() []Tool {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    out := make([]Tool, 0, len(r.tools))
-    for _, t := range r.tools {
+    b := r.base()
+    b.mu.RLock()
+    out := make([]Tool, 0, len(b.tools))
+    for _, t := range b.tools {
         out = append(out, t)
     }
-    return out
+    b.mu.RUnlock()
+    if r.agent == "" {
+        return out
+    }
+    visible := out[:0]
+    for _, t := range out {
+        if r.permits(r.agent, t.Name()) {
+            visible = append(visible, t)
+        }
+    }
+    return visible
+}
+
+// SetRedactor installs a function that masks secret values in every tool result.
+func (r *ToolRegistry) SetRedactor(redact func(string) string) {
+    b := r.base()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.redact = redact
+}
+
+// redactResult masks secrets in outputs (strings and structured values),
+// errors and exec error details.
+func (r *ToolRegistry) redactResult(res ToolResult) ToolResult {
+    b := r.base()
+    b.mu.RLock()
+    redact := b.redact
+    b.mu.RUnlock()
+    if redact == nil {
+        return res
+    }
+    res.Output = redactOutput(res.Output, redact)
+    if res.Error != nil {
+        if msg := redact(res.Error.Error()); msg != res.Error.Error() {
+            res.Error = errors.New(msg)
+        }
+    }
+    if res.ErrorDetail != nil {
+        d := *res.ErrorDetail
+        d.Command, d.Output, d.ErrMsg = redact(d.Command), redact(d.Output), redact(d.ErrMsg)
+        res.ErrorDetail = &d
+    }
+    return res
 }
 
 // Generates a string like: "Available tools: fetch_arxiv: search academic papers; milvus_vector: vector DB ops; ..."
@@ -52,27 +203,112 @@ func (tr *ToolRegistry) DescribeTools() string {
 
 // Dynamic tool invocation by name (with trace support)
 func (r *ToolRegistry) CallTool(ctx context.Context, call ToolCall) ToolResult {
-    tool, ok := r.Get(call.Name)
-    if !ok {
-        return ToolResult{Error: fmt.Errorf("tool not found: %s", call.Name)}
+    tool, err := r.lookup(call, "tool not found")
+    if err != nil {
+        return ToolResult{Error: err}
     }
     // Extend trace
     call.Trace = append(call.Trace, call.Name)
-    return tool.Call(ctx, call)
+    return r.redactResult(tool.Call(ctx, call))
 }
 
 // This is what you need to add:
 func (r *ToolRegistry) Call(ctx context.Context, call ToolCall) ToolResult {
-    tool, ok := r.tools[call.Name]
+    tool, err := r.lookup(call, "unknown tool")
+    if err != nil {
+        return ToolResult{Error: err}
+    }
+    return r.redactResult(tool.Call(ctx, call))
+}
+
+// redactOutput masks secrets in a tool output. Structured outputs (plugin,
+// HTTP JSON, arXiv and composite results) are converted to their JSON form
+// and every string in it, keys included, is redacted; the original value is
+// kept when it holds no secret, so callers still see its concrete type.
+func redactOutput(out interface{}, redact func(string) string) interface{} {
+    switch v := out.(type) {
+    case nil:
+        return nil
+    case string:
+        return redact(v)
+    case []byte:
+        return redact(string(v))
+    }
+    data, err := json.Marshal(out)
+    if err != nil {
+        return redact(fmt.Sprint(out))
+    }
+    var generic interface{}
+    if err := json.Unmarshal(data, &generic); err != nil {
+        return redact(string(data))
+    }
+    redacted, changed := redactValue(generic, redact)
+    if !changed {
+        return out
+    }
+    return redacted
+}
+
+// redactValue walks a decoded JSON value and reports whether it masked anything.
+func redactValue(v interface{}, redact func(string) string) (interface{}, bool) {
+    switch x := v.(type) {
+    case string:
+        r := redact(x)
+        return r, r != x
+    case []interface{}:
+        changed := false
+        for i, item := range x {
+            var c bool
+            x[i], c = redactValue(item, redact)
+            changed = changed || c
+        }
+        return x, changed
+    case map[string]interface{}:
+        out := make(map[string]interface{}, len(x))
+        changed := false
+        for k, item := range x {
+            rk := redact(k)
+            ri, c := redactValue(item, redact)
+            out[rk] = ri
+            changed = changed || c || rk != k
+        }
+        return out, changed
+    }
+    return v, false
+}
+
+// lookup finds the called tool and checks that both the caller and the
+// view's agent may use it; denials are logged and counted.
+func (r *ToolRegistry) lookup(call ToolCall, notFound string) (Tool, error) {
+    b := r.base()
+    b.mu.RLock()
+    tool, ok := b.tools[call.Name]
+    b.mu.RUnlock()
     if !ok {
-        return ToolResult{Error: fmt.Errorf("unknown tool: %s", call.Name)}
+        return nil, fmt.Errorf("%s: %s", notFound, call.Name)
+    }
+    for _, agent := range []string{call.Caller, r.agent} {
+        if r.permits(agent, call.Name) {
+            continue
+        }
+        metrics.ToolDeniedTotal.WithLabelValues(call.Name, agent).Inc()
+        utils.Logger.Warn().Str("tool", call.Name).Str("agent", agent).Msg("Tool call denied by agent scope")
+        return nil, fmt.Errorf("%w: agent %s may not call %s", ErrToolDenied, agent, call.Name)
     }
-    return tool.Call(ctx, call)
+    return tool, nil
+}
+
+// Registered reports whether the tool exists, even if the view's scope
+// hides it.
+func (r *ToolRegistry) Registered(name string) bool {
+    b := r.base()
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    _, ok := b.tools[name]
+    return ok
 }
 
 func (r *ToolRegistry) HasTool(name string) bool {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    _, ok := r.tools[name]
+    _, ok := r.Get(name)
     return ok
-}
\ No newline at end of file
+}
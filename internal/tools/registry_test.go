@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMatchToolName(t *testing.T) {
+	patterns := []string{"workspace_*", "mcp__github__?ist"}
+	for name, want := range map[string]bool{
+		"workspace_read":     true,
+		"workspace":          false,
+		"mcp__github__list":  true,
+		"mcp__github__lists": false,
+		"docker_exec":        false,
+	} {
+		if got := MatchToolName(patterns, name); got != want {
+			t.Errorf("MatchToolName(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if MatchToolName([]string{"[bad"}, "[bad") {
+		t.Error("malformed pattern matched")
+	}
+
+	scope := ToolScope{Allow: []string{"workspace_*"}, Deny: []string{"workspace_grep"}}
+	if !scope.Permits("workspace_read") || scope.Permits("workspace_grep") || scope.Permits("docker_exec") {
+		t.Errorf("scope %+v permits the wrong tools", scope)
+	}
+}
+
+// resultTool returns a fixed result.
+type resultTool struct {
+	name string
+	res  ToolResult
+}
+
+func (t *resultTool) Name() string        { return t.name }
+func (t *resultTool) Description() string { return "returns a fixed result" }
+func (t *resultTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *resultTool) Call(ctx context.Context, call ToolCall) ToolResult { return t.res }
+
+func TestRegistryRedactsStructuredOutputs(t *testing.T) {
+	redact := strings.NewReplacer("s3cr3t-token", "[REDACTED:TOKEN]").Replace
+	type paper struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	}
+	tests := []struct {
+		name   string
+		output interface{}
+		want   string // JSON of the redacted output
+	}{
+		{"string", "token=s3cr3t-token", `"token=[REDACTED:TOKEN]"`},
+		{"plugin map", map[string]interface{}{"echo": map[string]interface{}{"auth": "s3cr3t-token"}, "n": 1.0}, `{"echo":{"auth":"[REDACTED:TOKEN]"},"n":1}`},
+		{"list", []interface{}{"a", "Bearer s3cr3t-token"}, `["a","Bearer [REDACTED:TOKEN]"]`},
+		{"struct slice", []paper{{Title: "t", URL: "https://x/?key=s3cr3t-token"}}, `[{"title":"t","url":"https://x/?key=[REDACTED:TOKEN]"}]`},
+		{"map key", map[string]string{"s3cr3t-token": "v"}, `{"[REDACTED:TOKEN]":"v"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewToolRegistry()
+			registry.SetRedactor(redact)
+			registry.Register(&resultTool{name: "leaky", res: ToolResult{Output: tt.output}})
+			res := registry.Call(context.Background(), ToolCall{Name: "leaky"})
+			got, _ := json.Marshal(res.Output)
+			if string(got) != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// Outputs without a secret keep their concrete type.
+	registry := NewToolRegistry()
+	registry.SetRedactor(redact)
+	clean := []paper{{Title: "t"}}
+	registry.Register(&resultTool{name: "clean", res: ToolResult{Output: clean}})
+	if _, ok := registry.Call(context.Background(), ToolCall{Name: "clean"}).Output.([]paper); !ok {
+		t.Error("clean structured output was converted")
+	}
+}
+
+func TestRegistryRedactsErrors(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.SetRedactor(strings.NewReplacer("s3cr3t-token", "[REDACTED:TOKEN]").Replace)
+	registry.Register(&resultTool{name: "failing", res: ToolResult{
+		Error: errors.New("401 for s3cr3t-token"),
+		ErrorDetail: &ExecErrorDetail{
+			Phase:   "http",
+			Command: "GET /x?key=s3cr3t-token",
+			Output:  `{"token":"s3cr3t-token"}`,
+			ErrMsg:  "bad s3cr3t-token",
+		},
+	}})
+	res := registry.Call(context.Background(), ToolCall{Name: "failing"})
+	d := res.ErrorDetail
+	for _, s := range []string{res.Error.Error(), d.Command, d.Output, d.ErrMsg} {
+		if strings.Contains(s, "s3cr3t-token") {
+			t.Errorf("secret leaked: %q", s)
+		}
+	}
+}
+
+// deniedCount reads tool_denied_total for the tool and agent.
+func deniedCount(t *testing.T, tool, agent string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "tool_denied_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["tool"] == tool && labels["agent"] == agent {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestRegistryScopes(t *testing.T) {
+	read := &funcTool{name: "workspace_read", fn: func(ToolCall) ToolResult { return ToolResult{Output: "ok"} }}
+	grep := &funcTool{name: "workspace_grep", fn: func(ToolCall) ToolResult { return ToolResult{Output: "ok"} }}
+	exec := &funcTool{name: "docker_exec", fn: func(ToolCall) ToolResult { return ToolResult{Output: "ran"} }}
+	registry := compositeRegistry(read, grep, exec)
+	registry.SetScope("Coder", ToolScope{Allow: []string{"workspace_*"}, Deny: []string{"workspace_grep"}})
+
+	// The caller's scope is checked on every registry, scoped or not.
+	before := deniedCount(t, "docker_exec", "Coder")
+	res := registry.Call(context.Background(), ToolCall{Name: "docker_exec", Caller: "Coder"})
+	if !errors.Is(res.Error, ErrToolDenied) || res.Error.Error() != "tool not allowed: agent Coder may not call docker_exec" {
+		t.Errorf("denied caller: err = %v", res.Error)
+	}
+	res = registry.CallTool(context.Background(), ToolCall{Name: "workspace_grep", Caller: "Coder"})
+	if !errors.Is(res.Error, ErrToolDenied) {
+		t.Errorf("denied pattern: err = %v", res.Error)
+	}
+	if got := deniedCount(t, "docker_exec", "Coder") - before; got != 1 {
+		t.Errorf("tool_denied_total rose by %v, want 1", got)
+	}
+	for _, caller := range []string{"", "Unscoped"} {
+		if res := registry.Call(context.Background(), ToolCall{Name: "docker_exec", Caller: caller}); res.Error != nil {
+			t.Errorf("caller %q: %v", caller, res.Error)
+		}
+	}
+
+	// A view lists and runs only its agent's tools, whoever the caller is.
+	view := registry.Scoped("Coder")
+	var names []string
+	for _, tool := range view.List() {
+		names = append(names, tool.Name())
+	}
+	if len(names) != 1 || names[0] != "workspace_read" {
+		t.Errorf("view lists %v, want [workspace_read]", names)
+	}
+	if view.HasTool("docker_exec") || !view.Registered("docker_exec") {
+		t.Error("view should hide docker_exec but report it registered")
+	}
+	before = deniedCount(t, "docker_exec", "Coder")
+	calls := len(exec.called())
+	for _, call := range []func(context.Context, ToolCall) ToolResult{view.Call, view.CallTool} {
+		if res := call(context.Background(), ToolCall{Name: "docker_exec"}); !errors.Is(res.Error, ErrToolDenied) {
+			t.Errorf("call through the view: err = %v, want ErrToolDenied", res.Error)
+		}
+	}
+	if len(exec.called()) != calls {
+		t.Error("a denied tool was run")
+	}
+	if got := deniedCount(t, "docker_exec", "Coder") - before; got != 2 {
+		t.Errorf("tool_denied_total rose by %v, want 2", got)
+	}
+	if res := view.Call(context.Background(), ToolCall{Name: "workspace_read"}); res.Error != nil || res.Output != "ok" {
+		t.Errorf("permitted call: %v, %v", res.Output, res.Error)
+	}
+
+	// An unscoped view still checks the caller.
+	runner := registry.Scoped("Runner")
+	if res := runner.Call(context.Background(), ToolCall{Name: "docker_exec", Caller: "Coder"}); !errors.Is(res.Error, ErrToolDenied) {
+		t.Errorf("caller through another view: err = %v", res.Error)
+	}
+}
@@ -0,0 +1,178 @@
+// internal/tools/reload.go
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"aiupstart.com/go-gen/internal/config"
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// ToolReloader keeps the registry in line with tools.yaml (enabled flags)
+// and mcp_tools.yaml (HTTP and composite tool definitions). Load reads both files and
+// swaps the tools it manages in one step; a file that fails to load or
+// validate, or an HTTP entry whose client cannot be built (e.g. an unreadable
+// CA file), is rejected and the previous tools stay registered. Tools it does
+// not manage (e.g. MCP server and plugin tools) are left alone.
+type ToolReloader struct {
+	registry  *ToolRegistry
+	toolsPath string // tools.yaml; optional
+	mcpPath   string // mcp_tools.yaml
+	lookup    SecretLookup
+	builtins  []Tool // tools implemented in code, gated by tools.yaml
+
+	mu       sync.Mutex
+	managed  []string // names registered by the last successful Load
+	mcpCfg   *config.McpConfig
+	onReload []func(cfg *config.McpConfig)
+}
+
+func NewToolReloader(registry *ToolRegistry, toolsPath, mcpPath string, lookup SecretLookup, builtins ...Tool) *ToolReloader {
+	return &ToolReloader{registry: registry, toolsPath: toolsPath, mcpPath: mcpPath, lookup: lookup, builtins: builtins}
+}
+
+// OnReload registers fn to run after every successful Load, e.g. to refresh
+// the tool schemas sent to the LLM.
+func (r *ToolReloader) OnReload(fn func(cfg *config.McpConfig)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, fn)
+}
+
+// McpConfig returns the config of the last successful Load.
+func (r *ToolReloader) McpConfig() *config.McpConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mcpCfg
+}
+
+// Load reads both files and registers the enabled tools, unregistering the
+// ones that were removed or disabled. A missing tools.yaml enables every
+// tool.
+func (r *ToolReloader) Load() error {
+	var toolCfg *ToolConfig
+	if r.toolsPath != "" {
+		cfg, err := LoadToolConfig(r.toolsPath)
+		switch {
+		case err == nil:
+			toolCfg = cfg
+		case !os.IsNotExist(err):
+			return fmt.Errorf("%s: %w", r.toolsPath, err)
+		}
+	}
+	mcpCfg, err := config.LoadConfig(r.mcpPath)
+	if err != nil {
+		return err
+	}
+
+	// Build the tools aside, so a failure leaves the registry untouched.
+	staged := NewToolRegistry()
+	for _, t := range r.builtins {
+		if toolCfg.Enabled(t.Name()) {
+			staged.Register(t)
+		}
+	}
+	for _, t := range mcpCfg.McpTools {
+		if !toolCfg.Enabled(t.Name) {
+			continue
+		}
+		entry := config.McpConfig{McpTools: []config.McpToolConfig{t}}
+		ops, err := RegisterHttpOperations(&entry, staged, r.lookup)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.mcpPath, err)
+		}
+		for _, name := range ops {
+			if !toolCfg.Enabled(name) {
+				staged.Unregister(name)
+			}
+		}
+	}
+	for _, c := range mcpCfg.CompositeTools {
+		if toolCfg.Enabled(c.Name) {
+			staged.Register(NewCompositeTool(c, r.registry))
+		}
+	}
+	add := staged.List()
+	names := make([]string, len(add))
+	for i, t := range add {
+		names[i] = t.Name()
+	}
+	sort.Strings(names)
+
+	r.mu.Lock()
+	if r.mcpCfg != nil && !reflect.DeepEqual(r.mcpCfg.McpServers, mcpCfg.McpServers) {
+		utils.Logger.Warn().Str("file", r.mcpPath).Msg("mcp_servers changed; restart to connect or disconnect MCP servers")
+	}
+	if r.mcpCfg != nil && !reflect.DeepEqual(r.mcpCfg.Plugins, mcpCfg.Plugins) {
+		utils.Logger.Warn().Str("file", r.mcpPath).Msg("plugins changed; restart to start or stop plugins")
+	}
+	removed := missing(r.managed, names)
+	r.registry.Swap(removed, add)
+	r.managed = names
+	r.mcpCfg = mcpCfg
+	hooks := append([]func(*config.McpConfig){}, r.onReload...)
+	r.mu.Unlock()
+
+	utils.Logger.Info().Strs("tools", names).Strs("removed", removed).Msg("Tools loaded")
+	for _, fn := range hooks {
+		fn(mcpCfg)
+	}
+	return nil
+}
+
+// Watch polls both files every interval until ctx is done and reloads when
+// one of them changes. Rejected reloads are logged; the previous tools stay.
+func (r *ToolReloader) Watch(ctx context.Context, interval time.Duration) {
+	last := r.stamp()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := r.stamp()
+		if now == last {
+			continue
+		}
+		last = now
+		if err := r.Load(); err != nil {
+			utils.Logger.Error().Err(err).Msg("Tool config reload rejected; keeping the previous tools")
+			fmt.Println("Tool config reload rejected:", err)
+			continue
+		}
+		fmt.Println("Tool config reloaded")
+	}
+}
+
+// stamp summarizes the size and modification time of both files.
+func (r *ToolReloader) stamp() string {
+	s := ""
+	for _, path := range []string{r.toolsPath, r.mcpPath} {
+		if info, err := os.Stat(path); err == nil {
+			s += fmt.Sprintf("%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		} else {
+			s += path + ":missing;"
+		}
+	}
+	return s
+}
+
+// missing returns the names in old that are not in current (both sorted).
+func missing(old, current []string) []string {
+	var out []string
+	for _, name := range old {
+		i := sort.SearchStrings(current, name)
+		if i == len(current) || current[i] != name {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const reloadTestConfig = `mcp_tools:
+- name: inventory
+  endpoint: http://127.0.0.1:1
+  description: Inventory API
+%s  operations:
+    - name: list_items
+      path: /items
+      method: GET
+      description: List the items
+      parameters:
+        type: object
+        properties: {}
+`
+
+func writeMcpConfig(t *testing.T, path, tls string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Replace(reloadTestConfig, "%s", tls, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadRejectsBrokenHTTPClient(t *testing.T) {
+	dir := t.TempDir()
+	mcpPath := filepath.Join(dir, "mcp_tools.yaml")
+	writeMcpConfig(t, mcpPath, "")
+	registry := NewToolRegistry()
+	r := NewToolReloader(registry, "", mcpPath, nil)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !registry.HasTool("list_items") {
+		t.Fatal("list_items not registered")
+	}
+
+	writeMcpConfig(t, mcpPath, "  tls:\n    ca_file: "+filepath.Join(dir, "missing.pem")+"\n")
+	err := r.Load()
+	if err == nil || !strings.Contains(err.Error(), "reading CA file") {
+		t.Fatalf("err = %v, want the CA file error", err)
+	}
+	if !registry.HasTool("list_items") {
+		t.Error("rejected reload removed the working list_items tool")
+	}
+}
@@ -0,0 +1,223 @@
+// internal/tools/sandbox_cache.go
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"aiupstart.com/go-gen/internal/utils"
+)
+
+// PackageCache is a named docker volume mounted over a package manager's
+// download cache so repeat sessions skip re-downloading dependencies.
+type PackageCache struct {
+	Manager   string
+	MountPath string
+}
+
+var packageCaches = map[string]PackageCache{
+	"npm":   {Manager: "npm", MountPath: "/root/.npm"},
+	"pip":   {Manager: "pip", MountPath: "/root/.cache/pip"},
+	"nuget": {Manager: "nuget", MountPath: "/root/.nuget/packages"},
+}
+
+// Language to package manager cache mapping
+var langCacheMap = map[string][]string{
+	"python":  {"pip"},
+	"dotnet":  {"nuget"},
+	"npm":     {"npm"},
+	"angular": {"npm"},
+	"bash":    {"npm"},
+	"sh":      {"npm"},
+}
+
+const DefaultCacheVolumePrefix = "gogen-cache-"
+
+// CacheConfig enables the package cache volumes. MaxSizeMB limits are
+// enforced by EnforceCacheLimits, which empties a cache that grew too big.
+type CacheConfig struct {
+	Enabled      bool
+	VolumePrefix string
+	MaxSizeMB    map[string]int // manager -> limit, 0/missing = unlimited
+}
+
+// SandboxOptions are docker run settings shared by session and pooled containers.
+type SandboxOptions struct {
+	Cache       *CacheConfig
+	Mirror      *MirrorConfig
+	NetworkMode string // "" = docker default, "none" = isolated, or a network name
+}
+
+func (c *CacheConfig) volumeName(manager string) string {
+	prefix := c.VolumePrefix
+	if prefix == "" {
+		prefix = DefaultCacheVolumePrefix
+	}
+	return prefix + manager
+}
+
+// cacheManagersFor returns the package managers used by a language, falling
+// back to guessing from the image name (pooled containers only know the image).
+func cacheManagersFor(lang, image string) []string {
+	if m, ok := langCacheMap[lang]; ok {
+		return m
+	}
+	img := strings.ToLower(image)
+	switch {
+	case strings.Contains(img, "python"):
+		return []string{"pip"}
+	case strings.Contains(img, "dotnet"):
+		return []string{"nuget"}
+	case strings.Contains(img, "node"), strings.Contains(img, "angular"):
+		return []string{"npm"}
+	}
+	return nil
+}
+
+// runArgs returns the extra "docker run" arguments for a container.
+func (o *SandboxOptions) runArgs(lang, image string) []string {
+	if o == nil {
+		return nil
+	}
+	var args []string
+	if o.Cache != nil && o.Cache.Enabled {
+		for _, m := range cacheManagersFor(lang, image) {
+			args = append(args, "-v", o.Cache.volumeName(m)+":"+packageCaches[m].MountPath)
+		}
+	}
+	args = append(args, o.Mirror.runArgs()...)
+	if o.NetworkMode != "" {
+		args = append(args, "--network", o.NetworkMode)
+	}
+	return args
+}
+
+// CacheVolumeInfo describes one cache volume for the cache CLI.
+type CacheVolumeInfo struct {
+	Manager string
+	Volume  string
+	SizeMB  float64
+	Exists  bool
+}
+
+// ListCacheVolumes reports the size of every known cache volume.
+func ListCacheVolumes(ctx context.Context, cfg *CacheConfig) ([]CacheVolumeInfo, error) {
+	sizes, err := dockerVolumeSizes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []CacheVolumeInfo
+	for m := range packageCaches {
+		vol := cfg.volumeName(m)
+		size, ok := sizes[vol]
+		out = append(out, CacheVolumeInfo{Manager: m, Volume: vol, SizeMB: size, Exists: ok})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Manager < out[j].Manager })
+	return out, nil
+}
+
+// PruneCacheVolumes removes the cache volumes of the given managers (all if empty).
+// Volumes still mounted by a running container are reported and left alone.
+func PruneCacheVolumes(ctx context.Context, cfg *CacheConfig, managers []string) error {
+	if len(managers) == 0 {
+		for m := range packageCaches {
+			managers = append(managers, m)
+		}
+		sort.Strings(managers)
+	}
+	var failed []string
+	for _, m := range managers {
+		if _, ok := packageCaches[m]; !ok {
+			return fmt.Errorf("unknown package manager %q (want npm, pip or nuget)", m)
+		}
+		vol := cfg.volumeName(m)
+		out, err := exec.CommandContext(ctx, "docker", "volume", "rm", vol).CombinedOutput()
+		if err != nil && !strings.Contains(string(out), "no such volume") {
+			failed = append(failed, fmt.Sprintf("%s: %s", vol, strings.TrimSpace(string(out))))
+			continue
+		}
+		utils.Logger.Info().Str("volume", vol).Msg("Pruned package cache volume")
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not prune %d volume(s):\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// EnforceCacheLimits prunes every cache volume larger than its configured limit.
+func EnforceCacheLimits(ctx context.Context, cfg *CacheConfig) error {
+	if cfg == nil || !cfg.Enabled || len(cfg.MaxSizeMB) == 0 {
+		return nil
+	}
+	vols, err := ListCacheVolumes(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	var over []string
+	for _, v := range vols {
+		if limit := cfg.MaxSizeMB[v.Manager]; limit > 0 && v.SizeMB > float64(limit) {
+			utils.Logger.Warn().Str("volume", v.Volume).Msgf("Cache volume is %.0fMB, over the %dMB limit; pruning", v.SizeMB, limit)
+			over = append(over, v.Manager)
+		}
+	}
+	if len(over) == 0 {
+		return nil
+	}
+	return PruneCacheVolumes(ctx, cfg, over)
+}
+
+// dockerVolumeSizes returns volume name -> size in MB from "docker system df -v".
+func dockerVolumeSizes(ctx context.Context) (map[string]float64, error) {
+	out, err := exec.CommandContext(ctx, "docker", "system", "df", "-v", "--format", "{{json .Volumes}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker system df failed: %w", err)
+	}
+	var vols []struct {
+		Name string
+		Size string
+	}
+	if err := json.Unmarshal(out, &vols); err != nil {
+		return nil, fmt.Errorf("unexpected docker system df output: %w", err)
+	}
+	sizes := map[string]float64{}
+	for _, v := range vols {
+		mb, err := parseDockerSizeMB(v.Size)
+		if err != nil {
+			utils.Logger.Debug().Err(err).Str("volume", v.Name).Msg("Skipping volume with an unreadable size")
+			continue
+		}
+		sizes[v.Name] = mb
+	}
+	return sizes, nil
+}
+
+// dockerSizeUnits maps a size prefix to megabytes. Docker prints decimal
+// units ("12.5kB", "1.2GB"); "m"/"g" limits and binary "MiB" are read the
+// same way since the cache limit is approximate.
+var dockerSizeUnits = map[string]float64{"": 1e-6, "k": 1e-3, "m": 1, "g": 1e3, "t": 1e6}
+
+// parseDockerSizeMB parses a docker size ("0B", "12.5kB", "1.2GB", "512m",
+// "2GiB") into megabytes. The unit is case-insensitive and the trailing
+// "B" optional; a bare number is bytes.
+func parseDockerSizeMB(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	num, unit := s, ""
+	if i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); i >= 0 {
+		num, unit = s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	}
+	unit = strings.TrimSuffix(unit, "b")
+	if len(unit) == 2 && unit[1] == 'i' {
+		unit = unit[:1]
+	}
+	mb, ok := dockerSizeUnits[unit]
+	n, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mb, nil
+}
@@ -0,0 +1,35 @@
+package tools
+
+import "testing"
+
+func TestParseDockerSizeMB(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"0B", 0},
+		{"512", 0.000512},
+		{"12.5kB", 0.0125},
+		{"12.5KB", 0.0125},
+		{"300k", 0.3},
+		{"1.5MB", 1.5},
+		{"512m", 512},
+		{"2MiB", 2},
+		{"1.2GB", 1200},
+		{"2g", 2000},
+		{" 3 GB ", 3000},
+		{"1TB", 1e6},
+	}
+	for _, tt := range tests {
+		got, err := parseDockerSizeMB(tt.in)
+		if err != nil || got < tt.want*0.999999 || got > tt.want*1.000001 {
+			t.Errorf("parseDockerSizeMB(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "GB", "1.2.3GB", "12XB", "-1GB", "1 gigabyte", "5iB"} {
+		if got, err := parseDockerSizeMB(in); err == nil {
+			t.Errorf("parseDockerSizeMB(%q) = %v, want an error", in, got)
+		}
+	}
+}
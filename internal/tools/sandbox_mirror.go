@@ -0,0 +1,96 @@
+// internal/tools/sandbox_mirror.go
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MirrorConfig points sandbox package managers at local mirrors so generated
+// projects can install dependencies with the container network isolated:
+// host directories work with network none, URL mirrors need an internal
+// docker network that the mirror server is on.
+type MirrorConfig struct {
+	NpmRegistry  string // Verdaccio-style registry URL, e.g. http://verdaccio:4873/
+	PipIndexURL  string // PEP 503 simple index URL
+	PipFindLinks string // host directory of wheels/sdists, mounted read-only
+	NugetFeed    string // host directory (folder feed, mounted read-only) or feed URL
+}
+
+const (
+	mirrorPipDir   = "/mirror/pip"
+	mirrorNugetDir = "/mirror/nuget"
+)
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// runArgs mounts local feed directories and points npm/pip at the injected config files.
+func (m *MirrorConfig) runArgs() []string {
+	if m == nil {
+		return nil
+	}
+	var args []string
+	if m.NpmRegistry != "" {
+		// npm only reads a project .npmrc next to package.json; ng new creates a
+		// sub-directory, so use the workspace file as the user config instead.
+		args = append(args, "-e", "NPM_CONFIG_USERCONFIG=/workspace/.npmrc")
+	}
+	if m.PipIndexURL != "" || m.PipFindLinks != "" {
+		args = append(args, "-e", "PIP_CONFIG_FILE=/workspace/pip.conf")
+	}
+	if m.PipFindLinks != "" {
+		args = append(args, "-v", m.PipFindLinks+":"+mirrorPipDir+":ro")
+	}
+	if m.NugetFeed != "" && !isURL(m.NugetFeed) {
+		args = append(args, "-v", m.NugetFeed+":"+mirrorNugetDir+":ro")
+	}
+	return args
+}
+
+// configFiles returns the package manager config files to write into the
+// workspace root. NuGet discovers NuGet.config by walking up from the project.
+func (m *MirrorConfig) configFiles() []CodeBlock {
+	if m == nil {
+		return nil
+	}
+	var files []CodeBlock
+	if m.NpmRegistry != "" {
+		files = append(files, CodeBlock{
+			FileName: ".npmrc",
+			Code:     fmt.Sprintf("registry=%s\naudit=false\nfund=false\nupdate-notifier=false\n", m.NpmRegistry),
+		})
+	}
+	if m.PipIndexURL != "" || m.PipFindLinks != "" {
+		var b strings.Builder
+		b.WriteString("[global]\ndisable-pip-version-check = true\n")
+		if m.PipIndexURL != "" {
+			fmt.Fprintf(&b, "index-url = %s\n", m.PipIndexURL)
+		} else {
+			b.WriteString("no-index = true\n")
+		}
+		if m.PipFindLinks != "" {
+			fmt.Fprintf(&b, "find-links = %s\n", mirrorPipDir)
+		}
+		files = append(files, CodeBlock{FileName: "pip.conf", Code: b.String()})
+	}
+	if m.NugetFeed != "" {
+		source := m.NugetFeed
+		if !isURL(source) {
+			source = mirrorNugetDir
+		}
+		files = append(files, CodeBlock{
+			FileName: "NuGet.config",
+			Code: fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<configuration>
+  <packageSources>
+    <clear />
+    <add key="local-mirror" value="%s" />
+  </packageSources>
+</configuration>
+`, source),
+		})
+	}
+	return files
+}
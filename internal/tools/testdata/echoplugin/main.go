@@ -0,0 +1,69 @@
+// Command echoplugin is the plugin used by the PluginTool tests. It speaks
+// the JSON-lines plugin protocol and answers a call by its arguments:
+//
+//	{"crash": true}    exit without answering
+//	{"sleep": true}    never answer
+//	{"fail": true}     answer with an error
+//	{"noise": true}    write junk and a stray id before the answer
+//	{"getenv": "NAME"} answer with the variable's value, or null if unset
+//
+// Any other call echoes its arguments along with the process id.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type message struct {
+	ID     int64  `json:"id"`
+	Method string `json:"method"`
+	Params struct {
+		Args map[string]interface{} `json:"args"`
+	} `json:"params"`
+}
+
+func main() {
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		var msg message
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			fmt.Fprintln(os.Stderr, "bad request:", err)
+			continue
+		}
+		args := msg.Params.Args
+		reply := map[string]interface{}{"id": msg.ID}
+		switch {
+		case msg.Method == "describe":
+			reply["result"] = map[string]interface{}{
+				"name":        "echo",
+				"description": "Echo the arguments.",
+				"schema":      map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}},
+			}
+		case args["crash"] == true:
+			os.Exit(3)
+		case args["sleep"] == true:
+			time.Sleep(time.Hour)
+		case args["fail"] == true:
+			reply["error"] = "asked to fail"
+		case args["getenv"] != nil:
+			name, _ := args["getenv"].(string)
+			if v, ok := os.LookupEnv(name); ok {
+				reply["result"] = v
+			} else {
+				reply["result"] = nil
+			}
+		default:
+			if args["noise"] == true {
+				fmt.Println("not json")
+				fmt.Printf("{\"id\":%d,\"result\":\"stray\"}\n", msg.ID+1000)
+			}
+			reply["result"] = map[string]interface{}{"echo": args, "pid": os.Getpid()}
+		}
+		b, _ := json.Marshal(reply)
+		fmt.Println(string(b))
+	}
+}
@@ -33,6 +33,26 @@ type Tool interface {
     Call(ctx context.Context, call ToolCall) ToolResult
 }
 
+// ToolMetadata tells the agents how calls of a tool are executed.
+type ToolMetadata struct {
+    Executor         string // agent running the calls; "" lets the team's routing decide
+    RequiresApproval bool   // a human confirms each call, so a hitl agent runs it
+}
+
+// MetadataTool is implemented by tools that carry ToolMetadata.
+type MetadataTool interface {
+    Tool
+    Metadata() ToolMetadata
+}
+
+// MetadataOf returns the metadata of t, or the zero value for tools without.
+func MetadataOf(t Tool) ToolMetadata {
+    if m, ok := t.(MetadataTool); ok {
+        return m.Metadata()
+    }
+    return ToolMetadata{}
+}
+
 
 
 // ParseToolCall tries to extract a tool call from LLM output.
@@ -0,0 +1,135 @@
+//go:build !windows
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// openWorkspaceParent opens the directory holding rel inside root one path
+// component at a time with O_NOFOLLOW, so a component swapped for a symlink
+// by a command running in the container fails the open instead of being
+// followed out of the workspace. Missing directories are created (one
+// component at a time) when create is set. The caller closes the returned fd.
+func openWorkspaceParent(root, rel string, create bool) (int, string, error) {
+	fd, err := unix.Open(root, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, "", &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	segs := strings.Split(rel, "/")
+	for i, seg := range segs[:len(segs)-1] {
+		next, err := openWorkspaceDir(fd, seg)
+		if errors.Is(err, unix.ENOENT) && create {
+			if err = unix.Mkdirat(fd, seg, 0o755); err == nil || errors.Is(err, unix.EEXIST) {
+				next, err = openWorkspaceDir(fd, seg)
+			}
+		}
+		if err != nil {
+			err = workspacePathError(fd, strings.Join(segs[:i+1], "/"), seg, err)
+		}
+		unix.Close(fd)
+		if err != nil {
+			return -1, "", err
+		}
+		fd = next
+	}
+	return fd, segs[len(segs)-1], nil
+}
+
+func openWorkspaceDir(dirfd int, name string) (int, error) {
+	for {
+		fd, err := unix.Openat(dirfd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err != unix.EINTR {
+			return fd, err
+		}
+	}
+}
+
+// workspacePathError reports a symlink as such: opening one with
+// O_NOFOLLOW|O_DIRECTORY fails with ELOOP, or ENOTDIR on Linux.
+func workspacePathError(dirfd int, path, seg string, err error) error {
+	var st unix.Stat_t
+	if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.ENOTDIR) &&
+		unix.Fstatat(dirfd, seg, &st, unix.AT_SYMLINK_NOFOLLOW) == nil && st.Mode&unix.S_IFMT == unix.S_IFLNK {
+		return fmt.Errorf("path component %q is a symlink", seg)
+	}
+	return &os.PathError{Op: "open", Path: path, Err: err}
+}
+
+func (w *WorkspaceWriter) openFile(name string, flag int, create bool) (*os.File, error) {
+	rel, err := NormalizeWorkspacePath(name)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(w.Root)
+	if err != nil {
+		return nil, fmt.Errorf("workspace unavailable: %w", err)
+	}
+	dirfd, leaf, err := openWorkspaceParent(root, rel, create)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(dirfd)
+	fd, err := unix.Openat(dirfd, leaf, flag|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0o644)
+	if err != nil {
+		if errors.Is(err, unix.ELOOP) {
+			return nil, errors.New("target is a symlink")
+		}
+		return nil, &os.PathError{Op: "open", Path: rel, Err: err}
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(root, filepath.FromSlash(rel))), nil
+}
+
+// WriteFile creates or truncates name inside the workspace and writes data,
+// creating parent directories. No path component, the file included, may be
+// a symlink at the time it is opened.
+func (w *WorkspaceWriter) WriteFile(name string, data []byte) error {
+	f, err := w.openFile(name, unix.O_WRONLY|unix.O_CREAT|unix.O_TRUNC, true)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadFile reads name from the workspace without following symlinks.
+func (w *WorkspaceWriter) ReadFile(name string) ([]byte, error) {
+	f, err := w.openFile(name, unix.O_RDONLY, false)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Remove deletes name from the workspace without following symlinks in its
+// directories (a symlink named name is removed itself).
+func (w *WorkspaceWriter) Remove(name string) error {
+	rel, err := NormalizeWorkspacePath(name)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.EvalSymlinks(w.Root)
+	if err != nil {
+		return fmt.Errorf("workspace unavailable: %w", err)
+	}
+	dirfd, leaf, err := openWorkspaceParent(root, rel, false)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(dirfd)
+	if err := unix.Unlinkat(dirfd, leaf, 0); err != nil {
+		return &os.PathError{Op: "remove", Path: rel, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Windows has no openat; the sandbox host is expected to be Linux or macOS,
+// so these check the path with Resolve and then use the plain os calls.
+
+// WriteFile creates or truncates name inside the workspace and writes data,
+// creating parent directories.
+func (w *WorkspaceWriter) WriteFile(name string, data []byte) error {
+	dest, err := w.Resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(dest); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return errors.New("target is a symlink")
+	}
+	return os.WriteFile(dest, data, 0o644)
+}
+
+// ReadFile reads name from the workspace.
+func (w *WorkspaceWriter) ReadFile(name string) ([]byte, error) {
+	dest, err := w.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(dest); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return nil, errors.New("target is a symlink")
+	}
+	return os.ReadFile(dest)
+}
+
+// Remove deletes name from the workspace.
+func (w *WorkspaceWriter) Remove(name string) error {
+	dest, err := w.Resolve(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(dest)
+}
@@ -0,0 +1,578 @@
+// internal/tools/workspace_patch.go
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"aiupstart.com/go-gen/internal/metrics"
+	"aiupstart.com/go-gen/internal/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxPatchFuzz is how many leading/trailing context lines a hunk may drop
+// when it does not match as written (same idea as patch(1) --fuzz).
+const maxPatchFuzz = 2
+
+// WorkspacePatchTool applies unified diffs or search/replace edits to files
+// that already exist in the DockerExecTool session workspace, so repairs do not
+// have to resend whole files.
+type WorkspacePatchTool struct {
+	exec *DockerExecTool
+}
+
+// EditBlock replaces one occurrence of Search with Replace in FileName.
+// An empty Search on a missing file creates it.
+type EditBlock struct {
+	FileName string `json:"filename"`
+	Search   string `json:"search"`
+	Replace  string `json:"replace"`
+}
+
+type patchHunk struct {
+	oldStart int
+	header   string
+	lines    []string // prefixed with ' ', '-' or '+'
+}
+
+type filePatch struct {
+	oldName, newName string
+	hunks            []patchHunk
+}
+
+func NewWorkspacePatchTool(exec *DockerExecTool) *WorkspacePatchTool {
+	return &WorkspacePatchTool{exec: exec}
+}
+
+func (t *WorkspacePatchTool) Name() string { return "apply_patch" }
+func (t *WorkspacePatchTool) Description() string {
+	return "Apply a unified diff or search/replace edit blocks to files already in the docker_exec workspace. Prefer this over resending whole files when fixing errors."
+}
+
+func (t *WorkspacePatchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"patch": map[string]interface{}{
+				"type":        "string",
+				"description": "Unified diff (--- a/file, +++ b/file, @@ hunks). Paths are relative to /workspace.",
+			},
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Search/replace edits. Each search must match exactly one place in the file; include enough surrounding lines to be unique.",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"filename": map[string]interface{}{"type": "string", "description": "File path relative to /workspace"},
+						"search":   map[string]interface{}{"type": "string", "description": "Exact existing text to replace (empty to create a new file)"},
+						"replace":  map[string]interface{}{"type": "string", "description": "Replacement text"},
+					},
+					"required": []string{"filename", "search", "replace"},
+				},
+			},
+		},
+	}
+}
+
+func (t *WorkspacePatchTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	workspace := t.exec.Workspace()
+	if workspace == "" {
+		return ToolResult{Error: fmt.Errorf("no workspace yet: run docker_exec first to create the project files")}
+	}
+	writer := NewWorkspaceWriter(workspace)
+
+	var report []string
+	var failed bool
+
+	if patch, _ := call.Args["patch"].(string); strings.TrimSpace(patch) != "" {
+		files, err := parseUnifiedDiff(patch)
+		if err != nil {
+			return ToolResult{Error: fmt.Errorf("invalid patch: %w", err)}
+		}
+		for _, fp := range files {
+			lines, ok := applyFilePatch(writer, fp)
+			report = append(report, lines...)
+			failed = failed || !ok
+		}
+	}
+
+	if rawEdits, ok := call.Args["edits"].([]interface{}); ok {
+		var blocks []EditBlock
+		for i, raw := range rawEdits {
+			var e EditBlock
+			data, _ := utils.SafeMarshal(raw)
+			if err := utils.SafeUnmarshal(data, &e); err != nil || e.FileName == "" {
+				report = append(report, fmt.Sprintf("edit #%d: FAILED - invalid edit block", i+1))
+				failed = true
+				e = EditBlock{}
+			}
+			blocks = append(blocks, e)
+		}
+		lines, ok := applyEditBlocks(writer, blocks)
+		report = append(report, lines...)
+		failed = failed || !ok
+	}
+
+	if len(report) == 0 {
+		return ToolResult{Error: fmt.Errorf("nothing to apply: provide patch or edits")}
+	}
+	out := strings.Join(report, "\n")
+	if failed {
+		metrics.ToolErrorsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+		return ToolResult{
+			Output: out,
+			Error:  errors.New("some hunks or edits failed to apply; files with failures were left unchanged"),
+			ErrorDetail: &ExecErrorDetail{
+				Phase:   "patch",
+				Command: t.Name(),
+				Output:  out,
+				ErrMsg:  "some hunks or edits failed to apply",
+			},
+		}
+	}
+	return ToolResult{Output: out}
+}
+
+// editedFile is the in-memory state of one file targeted by edit blocks.
+type editedFile struct {
+	name    string
+	content string
+	exists  bool
+	err     error // first failure; the file is not written
+	edits   []int // indexes of the edits that target this file
+}
+
+// applyEditBlocks applies the edits in order to in-memory copies of their
+// files and writes each file once, only if every edit for it succeeded.
+// Blocks with an empty FileName were already reported as invalid.
+func applyEditBlocks(w *WorkspaceWriter, blocks []EditBlock) ([]string, bool) {
+	files := map[string]*editedFile{}
+	var order []*editedFile
+	results := make([]string, len(blocks))
+	applied := make([]bool, len(blocks))
+	ok := true
+	for i, e := range blocks {
+		if e.FileName == "" {
+			continue
+		}
+		dest, err := w.Resolve(e.FileName)
+		if err != nil {
+			results[i] = fmt.Sprintf("edit #%d (%s): FAILED - %v", i+1, e.FileName, err)
+			ok = false
+			continue
+		}
+		f := files[dest]
+		if f == nil {
+			f = &editedFile{name: e.FileName}
+			data, err := w.ReadFile(e.FileName)
+			switch {
+			case err == nil:
+				f.content, f.exists = string(data), true
+			case !os.IsNotExist(err):
+				f.err = err
+			}
+			files[dest] = f
+			order = append(order, f)
+		}
+		f.edits = append(f.edits, i)
+		if f.err != nil {
+			results[i] = fmt.Sprintf("edit #%d (%s): FAILED - %v", i+1, e.FileName, f.err)
+			ok = false
+			continue
+		}
+		content, err := applyEditBlock(f.content, f.exists, e)
+		if err != nil {
+			f.err = fmt.Errorf("edit #%d failed", i+1)
+			results[i] = fmt.Sprintf("edit #%d (%s): FAILED - %v", i+1, e.FileName, err)
+			ok = false
+			continue
+		}
+		f.content, f.exists = content, true
+		applied[i] = true
+		results[i] = fmt.Sprintf("edit #%d (%s): applied", i+1, e.FileName)
+	}
+
+	for _, f := range order {
+		if f.err == nil {
+			f.err = w.WriteBlocks([]CodeBlock{{FileName: f.name, Code: f.content}})
+			if f.err != nil {
+				ok = false
+			}
+		}
+		if f.err == nil {
+			continue
+		}
+		for _, i := range f.edits {
+			if applied[i] {
+				results[i] = fmt.Sprintf("edit #%d (%s): not written - %v", i+1, blocks[i].FileName, f.err)
+			}
+		}
+	}
+
+	var report []string
+	for _, r := range results {
+		if r != "" {
+			report = append(report, r)
+		}
+	}
+	return report, ok
+}
+
+// applyEditBlock applies one edit to content and returns the result; exists
+// is false when the file is not on disk yet.
+func applyEditBlock(content string, exists bool, e EditBlock) (string, error) {
+	if !exists {
+		if e.Search == "" {
+			return e.Replace, nil
+		}
+		return "", errors.New("file does not exist (use an empty search to create it)")
+	}
+	if e.Search == "" {
+		return "", errors.New("search is empty but the file already exists")
+	}
+
+	eol := lineEnding(content)
+	switch n := strings.Count(content, e.Search); {
+	case n == 1:
+		return strings.Replace(content, e.Search, withLineEnding(e.Replace, eol), 1), nil
+	case n > 1:
+		return "", fmt.Errorf("search text matches %d places; add surrounding lines to make it unique", n)
+	}
+	// Fall back to whitespace-insensitive line matching.
+	lines := splitLines(content)
+	search := splitLines(strings.TrimRight(e.Search, "\n"))
+	matches := findAll(lines, search, normalizeWS)
+	if len(matches) != 1 {
+		if len(matches) > 1 {
+			return "", fmt.Errorf("search text matches %d places (ignoring whitespace); add surrounding lines", len(matches))
+		}
+		return "", fmt.Errorf("search text not found; closest line: %s", closestLine(lines, search))
+	}
+	at := matches[0]
+	repl := splitLines(strings.TrimRight(e.Replace, "\n"))
+	lines = append(lines[:at], append(repl, lines[at+len(search):]...)...)
+	return joinLines(lines, eol, strings.HasSuffix(content, "\n")), nil
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	var files []filePatch
+	var cur *filePatch
+	var hunk *patchHunk
+	// oldLeft and newLeft count the lines the current hunk still expects, so
+	// removed or added lines that start with "--- " or "+++ " are content,
+	// not file headers. A "---"/"+++"/"@@" triple still starts a new file,
+	// in case a model overstated the hunk's line counts.
+	var oldLeft, newLeft int
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		inHunk := hunk != nil && (oldLeft > 0 || newLeft > 0)
+		fileHeader := strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+		if fileHeader && inHunk {
+			fileHeader = i+2 < len(lines) && strings.HasPrefix(lines[i+2], "@@")
+		}
+		switch {
+		case fileHeader:
+			files = append(files, filePatch{oldName: diffPath(line[4:])})
+			cur = &files[len(files)-1]
+			hunk = nil
+		case !inHunk && strings.HasPrefix(line, "+++ ") && cur != nil && cur.newName == "" && hunk == nil:
+			cur.newName = diffPath(line[4:])
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before file header", i+1)
+			}
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			start, _ := strconv.Atoi(m[1])
+			oldLeft, newLeft = hunkCount(m[2]), hunkCount(m[4])
+			cur.hunks = append(cur.hunks, patchHunk{oldStart: start, header: line})
+			hunk = &cur.hunks[len(cur.hunks)-1]
+		case hunk != nil && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+")):
+			hunk.lines = append(hunk.lines, line)
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			}
+		case hunk != nil && line == "":
+			// Some models drop the leading space on blank context lines.
+			if i != len(lines)-1 {
+				hunk.lines = append(hunk.lines, " ")
+				oldLeft--
+				newLeft--
+			}
+		case strings.HasPrefix(line, `\ No newline`):
+			// ignored
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no file headers (--- / +++) found")
+	}
+	return files, nil
+}
+
+// hunkCount parses the optional line count of a hunk range; it defaults to 1.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func diffPath(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// applyFilePatch applies all hunks of one file; the file is only written if every hunk applies.
+func applyFilePatch(w *WorkspaceWriter, fp filePatch) ([]string, bool) {
+	name := fp.newName
+	if name == "" || name == "/dev/null" {
+		name = fp.oldName
+	}
+	if _, err := w.Resolve(name); err != nil {
+		return []string{fmt.Sprintf("%s: FAILED - %v", name, err)}, false
+	}
+
+	if fp.newName == "/dev/null" {
+		if err := w.Remove(name); err != nil {
+			return []string{fmt.Sprintf("%s: FAILED - delete: %v", name, err)}, false
+		}
+		return []string{fmt.Sprintf("%s: deleted", name)}, true
+	}
+
+	var lines []string
+	trailingNL, eol := true, "\n"
+	if fp.oldName != "/dev/null" {
+		data, err := w.ReadFile(name)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: FAILED - %v (use /dev/null as the old file to create it)", name, err)}, false
+		}
+		lines = splitLines(string(data))
+		trailingNL = len(data) == 0 || strings.HasSuffix(string(data), "\n")
+		eol = lineEnding(string(data))
+	}
+
+	var report []string
+	ok := true
+	offset := 0 // net line shift from hunks already applied
+	for i, h := range fp.hunks {
+		var err error
+		lines, offset, err = applyHunk(lines, h, offset)
+		if err != nil {
+			report = append(report, fmt.Sprintf("%s hunk %d (%s): FAILED - %v", name, i+1, h.header, err))
+			ok = false
+			continue
+		}
+	}
+	if !ok {
+		return report, false
+	}
+	if err := w.WriteBlocks([]CodeBlock{{FileName: name, Code: joinLines(lines, eol, trailingNL)}}); err != nil {
+		return []string{fmt.Sprintf("%s: FAILED - %v", name, err)}, false
+	}
+	return []string{fmt.Sprintf("%s: %d hunk(s) applied", name, len(fp.hunks))}, true
+}
+
+// applyHunk locates the hunk near its stated position, trying an exact match,
+// then a whitespace-insensitive match, then dropping up to maxPatchFuzz
+// context lines from either end.
+func applyHunk(lines []string, h patchHunk, offset int) ([]string, int, error) {
+	var oldL, newL []string
+	for _, l := range h.lines {
+		switch l[0] {
+		case ' ':
+			oldL = append(oldL, l[1:])
+			newL = append(newL, l[1:])
+		case '-':
+			oldL = append(oldL, l[1:])
+		case '+':
+			newL = append(newL, l[1:])
+		}
+	}
+	want := h.oldStart - 1 + offset
+	if h.oldStart == 0 {
+		want = 0
+	}
+	if len(oldL) == 0 {
+		// Pure insertion (e.g. a new file): nothing to match, insert at the stated line.
+		at := max(0, min(want, len(lines)))
+		out := append(append(append([]string{}, lines[:at]...), newL...), lines[at:]...)
+		return out, offset + len(newL), nil
+	}
+
+	for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+		lead := min(fuzz, leadingContext(h.lines))
+		trail := min(fuzz, trailingContext(h.lines))
+		if fuzz > 0 && lead == 0 && trail == 0 {
+			break
+		}
+		o := oldL[lead : len(oldL)-trail]
+		n := newL[lead : len(newL)-trail]
+		for _, eq := range []func(a, b string) bool{exactEq, normalizeWS} {
+			if at := closestMatch(lines, o, want+lead, eq); at >= 0 {
+				out := append(append([]string{}, lines[:at]...), mergeHunk(h.lines[lead:len(h.lines)-trail], lines[at:at+len(o)])...)
+				out = append(out, lines[at+len(o):]...)
+				return out, offset + len(n) - len(o) + (at - (want + lead)), nil
+			}
+		}
+	}
+	return nil, offset, fmt.Errorf("context not found near line %d; expected %q, closest: %s", want+1, oldL[0], closestLine(lines, oldL))
+}
+
+// mergeHunk produces the replacement for a matched region, keeping the file's
+// own text for context lines so fuzzy whitespace matches do not reformat them.
+func mergeHunk(hunkLines, matched []string) []string {
+	var out []string
+	k := 0
+	for _, l := range hunkLines {
+		switch l[0] {
+		case ' ':
+			out = append(out, matched[k])
+			k++
+		case '-':
+			k++
+		case '+':
+			out = append(out, l[1:])
+		}
+	}
+	return out
+}
+
+func leadingContext(hl []string) int {
+	n := 0
+	for _, l := range hl {
+		if l[0] != ' ' {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func trailingContext(hl []string) int {
+	n := 0
+	for i := len(hl) - 1; i >= 0 && hl[i][0] == ' '; i-- {
+		n++
+	}
+	return n
+}
+
+// closestMatch returns the match of needle in lines nearest to want, or -1.
+func closestMatch(lines, needle []string, want int, eq func(a, b string) bool) int {
+	best := -1
+	for _, at := range findAll(lines, needle, eq) {
+		if best < 0 || abs(at-want) < abs(best-want) {
+			best = at
+		}
+	}
+	return best
+}
+
+func findAll(lines, needle []string, eq func(a, b string) bool) []int {
+	var out []int
+	if len(needle) == 0 {
+		return out
+	}
+	for i := 0; i+len(needle) <= len(lines); i++ {
+		match := true
+		for j := range needle {
+			if !eq(lines[i+j], needle[j]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// closestLine gives the model a hint of where its context went wrong.
+func closestLine(lines, needle []string) string {
+	if len(needle) == 0 {
+		return "(none)"
+	}
+	first := strings.TrimSpace(needle[0])
+	for i, l := range lines {
+		if first != "" && strings.Contains(strings.TrimSpace(l), first) {
+			return fmt.Sprintf("line %d: %q", i+1, l)
+		}
+	}
+	return "(no similar line in file)"
+}
+
+func exactEq(a, b string) bool { return a == b }
+
+func normalizeWS(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func joinLines(lines []string, eol string, trailingNL bool) string {
+	s := strings.Join(lines, eol)
+	if trailingNL && len(lines) > 0 {
+		s += eol
+	}
+	return s
+}
+
+// lineEnding returns the line ending of content's first line, so edited
+// files keep their CRLF endings.
+func lineEnding(content string) string {
+	if i := strings.IndexByte(content, '\n'); i > 0 && content[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// withLineEnding converts the line endings of s to eol.
+func withLineEnding(s, eol string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if eol == "\n" {
+		return s
+	}
+	return strings.ReplaceAll(s, "\n", eol)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
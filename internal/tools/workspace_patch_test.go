@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyEditBlocksWritesFileOnce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc a() {}\n\nfunc b() {}\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorkspaceWriter(dir)
+
+	// The second edit fails, so the first must not reach the disk either.
+	report, ok := applyEditBlocks(w, []EditBlock{
+		{FileName: "main.go", Search: "func a() {}", Replace: "func a() { b() }"},
+		{FileName: "main.go", Search: "func missing() {}", Replace: ""},
+	})
+	if ok {
+		t.Fatalf("failing edit reported as success: %v", report)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("file changed after a failed edit:\n%s", data)
+	}
+	if len(report) != 2 || !strings.Contains(report[0], "not written") || !strings.Contains(report[1], "FAILED") {
+		t.Errorf("report = %q", report)
+	}
+
+	// Later edits see the result of earlier ones for the same file.
+	report, ok = applyEditBlocks(w, []EditBlock{
+		{FileName: "main.go", Search: "func a() {}", Replace: "func a() { b() }"},
+		{FileName: "main.go", Search: "func a() { b() }", Replace: "func a() { b(); b() }"},
+		{FileName: "new.txt", Search: "", Replace: "hello\n"},
+	})
+	if !ok {
+		t.Fatalf("edits failed: %v", report)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "func a() { b(); b() }") {
+		t.Errorf("main.go =\n%s", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "new.txt")); string(data) != "hello\n" {
+		t.Errorf("new.txt = %q", data)
+	}
+}
+
+func TestParseUnifiedDiffHeadersInsideHunk(t *testing.T) {
+	// The hunk removes a line starting with "-- " and adds one starting with
+	// "++ ": as diff lines they read "--- " and "+++ ".
+	patch := strings.Join([]string{
+		"--- a/notes.md",
+		"+++ b/notes.md",
+		"@@ -1,3 +1,3 @@",
+		" title",
+		"--- old rule",
+		"+++ new rule",
+		" end",
+		"--- a/other.txt",
+		"+++ b/other.txt",
+		"@@ -1 +1 @@",
+		"-x",
+		"+y",
+		"",
+	}, "\n")
+	files, err := parseUnifiedDiff(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].newName != "notes.md" || files[1].newName != "other.txt" {
+		t.Fatalf("files = %+v, want notes.md and other.txt", files)
+	}
+	want := []string{" title", "--- old rule", "+++ new rule", " end"}
+	if got := files[0].hunks[0].lines; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("hunk lines = %q, want %q", got, want)
+	}
+}
+
+// applyPatch applies patch to a workspace holding files and returns the
+// tool's result.
+func applyPatch(t *testing.T, files map[string]string, patch string) (ToolResult, string) {
+	t.Helper()
+	exec := workspaceExec(t, files)
+	res := NewWorkspacePatchTool(exec).Call(context.Background(), ToolCall{Args: map[string]interface{}{"patch": patch}})
+	return res, exec.workspace
+}
+
+func readWorkspaceFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApplyHunkPlacement(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		patch   []string
+		want    string
+	}{
+		{
+			name:    "exact",
+			content: "a\nb\nc\n",
+			patch:   []string{"@@ -1,3 +1,3 @@", " a", "-b", "+B", " c"},
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "offset",
+			content: "new1\nnew2\nnew3\na\nb\nc\n",
+			patch:   []string{"@@ -1,3 +1,3 @@", " a", "-b", "+B", " c"},
+			want:    "new1\nnew2\nnew3\na\nB\nc\n",
+		},
+		{
+			name:    "whitespace keeps the file's context",
+			content: "if x {\n    return 1\n}\n",
+			patch:   []string{"@@ -1,3 +1,3 @@", " if x {", "-  return 1", "+    return 2", "  }"},
+			want:    "if x {\n    return 2\n}\n",
+		},
+		{
+			name:    "fuzz 2",
+			content: "a\nb\nc\nd\ne\nf\ng\n",
+			patch:   []string{"@@ -1,7 +1,7 @@", " X1", " X2", " c", "-d", "+D", " e", " Y1", " Y2"},
+			want:    "a\nb\nc\nD\ne\nf\ng\n",
+		},
+		{
+			name:    "later hunks shift by earlier ones",
+			content: "package p\nfunc a() {\n\treturn 1\n}\nfunc b() {\n\treturn 1\n}\n",
+			patch: []string{
+				"@@ -1,1 +1,3 @@", " package p", "+", `+import "fmt"`,
+				"@@ -6,1 +8,1 @@", "-\treturn 1", "+\treturn 2",
+			},
+			want: "package p\n\nimport \"fmt\"\nfunc a() {\n\treturn 1\n}\nfunc b() {\n\treturn 2\n}\n",
+		},
+		{
+			name:    "no trailing newline",
+			content: "a\nb",
+			patch:   []string{"@@ -1,2 +1,2 @@", " a", "-b", "+c"},
+			want:    "a\nc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := "--- a/f.txt\n+++ b/f.txt\n" + strings.Join(tt.patch, "\n") + "\n"
+			res, dir := applyPatch(t, map[string]string{"f.txt": tt.content}, patch)
+			if res.Error != nil {
+				t.Fatalf("%v\n%v", res.Error, res.Output)
+			}
+			if got := readWorkspaceFile(t, dir, "f.txt"); got != tt.want {
+				t.Errorf("f.txt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchCreateAndDelete(t *testing.T) {
+	patch := strings.Join([]string{
+		"--- /dev/null",
+		"+++ b/src/new.txt",
+		"@@ -0,0 +1,2 @@",
+		"+hello",
+		"+world",
+		"--- a/old.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"",
+	}, "\n")
+	res, dir := applyPatch(t, map[string]string{"old.txt": "bye\n"}, patch)
+	if res.Error != nil {
+		t.Fatalf("%v\n%v", res.Error, res.Output)
+	}
+	if got := readWorkspaceFile(t, dir, "src/new.txt"); got != "hello\nworld\n" {
+		t.Errorf("new.txt = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt not deleted: %v", err)
+	}
+	if out, _ := res.Output.(string); out != "src/new.txt: 1 hunk(s) applied\nold.txt: deleted" {
+		t.Errorf("report = %q", out)
+	}
+}
+
+func TestApplyPatchFailureReport(t *testing.T) {
+	patch := strings.Join([]string{
+		"--- a/good.txt",
+		"+++ b/good.txt",
+		"@@ -1 +1 @@",
+		"-one",
+		"+ONE",
+		"--- a/bad.txt",
+		"+++ b/bad.txt",
+		"@@ -1,2 +1,2 @@",
+		" keep",
+		"-gone",
+		"+new",
+		"@@ -5,1 +5,1 @@",
+		"-never there",
+		"+x",
+		"",
+	}, "\n")
+	res, dir := applyPatch(t, map[string]string{"good.txt": "one\n", "bad.txt": "keep\ngone\n"}, patch)
+	if res.Error == nil || res.ErrorDetail == nil || res.ErrorDetail.Phase != "patch" {
+		t.Fatalf("result = %+v, want a patch failure", res)
+	}
+	want := strings.Join([]string{
+		"good.txt: 1 hunk(s) applied",
+		`bad.txt hunk 2 (@@ -5,1 +5,1 @@): FAILED - context not found near line 5; expected "never there", closest: (no similar line in file)`,
+	}, "\n")
+	if out, _ := res.Output.(string); out != want {
+		t.Errorf("report:\n%s\nwant:\n%s", out, want)
+	}
+	// Files apply independently; a file with a failed hunk is not touched.
+	if got := readWorkspaceFile(t, dir, "good.txt"); got != "ONE\n" {
+		t.Errorf("good.txt = %q", got)
+	}
+	if got := readWorkspaceFile(t, dir, "bad.txt"); got != "keep\ngone\n" {
+		t.Errorf("bad.txt = %q, want it unchanged", got)
+	}
+}
+
+func TestApplyEditBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		exists  bool
+		edit    EditBlock
+		want    string
+		err     string
+	}{
+		{"create", "", false, EditBlock{Replace: "new\n"}, "new\n", ""},
+		{"create needs empty search", "", false, EditBlock{Search: "x"}, "", "does not exist"},
+		{"exists", "a\n", true, EditBlock{Replace: "b"}, "", "already exists"},
+		{"exact", "a\nb\nc\n", true, EditBlock{Search: "b\n", Replace: "B\n"}, "a\nB\nc\n", ""},
+		{"ambiguous", "x\nx\n", true, EditBlock{Search: "x", Replace: "y"}, "", "matches 2 places"},
+		{"whitespace", "if x {\n\treturn 1\n}\n", true, EditBlock{Search: "if x {\n  return 1\n}", Replace: "if x {\n\treturn 2\n}"}, "if x {\n\treturn 2\n}\n", ""},
+		{"not found", "alpha\nbeta\n", true, EditBlock{Search: "beta gamma"}, "", `closest line: (no similar line in file)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyEditBlock(tt.content, tt.exists, tt.edit)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("= %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchKeepsCRLF(t *testing.T) {
+	crlf := "a\r\n  b\r\nc\r\n"
+	// The hunk matches only ignoring whitespace, so it takes the fuzzy path.
+	patch := "--- a/win.txt\n+++ b/win.txt\n@@ -1,3 +1,4 @@\n a\n- b\n+b2\n+b3\n c\n"
+	res, dir := applyPatch(t, map[string]string{"win.txt": crlf}, patch)
+	if res.Error != nil {
+		t.Fatalf("%v\n%v", res.Error, res.Output)
+	}
+	if got := readWorkspaceFile(t, dir, "win.txt"); got != "a\r\nb2\r\nb3\r\nc\r\n" {
+		t.Errorf("patched win.txt = %q", got)
+	}
+
+	for _, e := range []EditBlock{
+		{Search: "a\n  b\n", Replace: "a\nB\n"}, // LF search: line matching
+		{Search: "  b", Replace: "B1\nB2"},      // exact match, multi-line replace
+	} {
+		got, err := applyEditBlock(crlf, true, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(got, "\n") != strings.Count(got, "\r\n") {
+			t.Errorf("edit %q -> %q mixes line endings", e.Search, got)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// internal/tools/workspace_tools.go
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"aiupstart.com/go-gen/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	maxReadLines     = 400
+	maxListEntries   = 500
+	maxGrepFileSize  = 1 << 20
+	defaultGrepLimit = 100
+)
+
+// DefaultWorkspaceIgnores are hidden from listing and grep unless explicitly requested.
+var DefaultWorkspaceIgnores = []string{"node_modules", ".git", ".angular", "dist", "bin", "obj", "__pycache__"}
+
+// WorkspaceListTool, WorkspaceReadTool and WorkspaceGrepTool let agents inspect
+// the files in the DockerExecTool session workspace (e.g. after "ng new").
+type WorkspaceListTool struct{ exec *DockerExecTool }
+type WorkspaceReadTool struct{ exec *DockerExecTool }
+type WorkspaceGrepTool struct{ exec *DockerExecTool }
+
+func NewWorkspaceListTool(exec *DockerExecTool) *WorkspaceListTool { return &WorkspaceListTool{exec} }
+func NewWorkspaceReadTool(exec *DockerExecTool) *WorkspaceReadTool { return &WorkspaceReadTool{exec} }
+func NewWorkspaceGrepTool(exec *DockerExecTool) *WorkspaceGrepTool { return &WorkspaceGrepTool{exec} }
+
+// sessionWorkspace returns the workspace root, or an error the model can act on.
+func sessionWorkspace(exec *DockerExecTool) (string, error) {
+	ws := exec.Workspace()
+	if ws == "" {
+		return "", fmt.Errorf("no workspace yet: run docker_exec first to create the project files")
+	}
+	return filepath.EvalSymlinks(ws)
+}
+
+// resolveWorkspaceDir resolves an optional sub-directory argument ("" or "." is the root).
+func resolveWorkspaceDir(root, dir string) (string, error) {
+	if d := strings.TrimSpace(dir); d == "" || d == "." || d == "/workspace" {
+		return root, nil
+	}
+	return NewWorkspaceWriter(root).Resolve(dir)
+}
+
+func ignorePatterns(args map[string]interface{}) []string {
+	ignores := append([]string{}, DefaultWorkspaceIgnores...)
+	if extra, ok := args["ignore"].([]interface{}); ok {
+		for _, e := range extra {
+			if s, ok := e.(string); ok && s != "" {
+				ignores = append(ignores, s)
+			}
+		}
+	}
+	if all, _ := args["include_ignored"].(bool); all {
+		return nil
+	}
+	return ignores
+}
+
+func intArg(args map[string]interface{}, name string, def int) int {
+	if v, ok := args[name].(float64); ok {
+		return int(v)
+	}
+	return def
+}
+
+// ---- workspace_list ----
+
+func (t *WorkspaceListTool) Name() string { return "workspace_list" }
+func (t *WorkspaceListTool) Description() string {
+	return "List the directory tree of the docker_exec workspace (/workspace), with depth limit and ignore rules (node_modules etc. hidden by default)."
+}
+func (t *WorkspaceListTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path":            map[string]interface{}{"type": "string", "description": "Sub-directory relative to /workspace (default: root)"},
+			"depth":           map[string]interface{}{"type": "integer", "description": "Maximum depth to descend (default 3)"},
+			"ignore":          map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Extra glob patterns to hide"},
+			"include_ignored": map[string]interface{}{"type": "boolean", "description": "Show default-ignored directories such as node_modules"},
+		},
+	}
+}
+
+func (t *WorkspaceListTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	root, err := sessionWorkspace(t.exec)
+	if err != nil {
+		return ToolResult{Error: err}
+	}
+	dirArg, _ := call.Args["path"].(string)
+	dir, err := resolveWorkspaceDir(root, dirArg)
+	if err != nil {
+		return ToolResult{Error: fmt.Errorf("path %q: %w", dirArg, err)}
+	}
+	depth := intArg(call.Args, "depth", 3)
+	ignores := ignorePatterns(call.Args)
+
+	var out []string
+	truncated := false
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // unreadable entries are skipped, not fatal
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if p == dir {
+			return nil
+		}
+		sub, _ := filepath.Rel(dir, p)
+		level := strings.Count(filepath.ToSlash(sub), "/")
+		if matchAnyGlob(ignores, rel) {
+			if info.IsDir() {
+				out = append(out, strings.Repeat("  ", level)+info.Name()+"/ (ignored)")
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(out) >= maxListEntries {
+			truncated = true
+			return filepath.SkipAll
+		}
+		line := strings.Repeat("  ", level) + info.Name()
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			line += "@"
+		case info.IsDir():
+			line += "/"
+		default:
+			line += fmt.Sprintf(" (%d bytes)", info.Size())
+		}
+		out = append(out, line)
+		if info.IsDir() && level+1 >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return ToolResult{Error: err}
+	}
+	if truncated {
+		out = append(out, fmt.Sprintf("... truncated after %d entries; narrow the path or depth", maxListEntries))
+	}
+	if len(out) == 0 {
+		return ToolResult{Output: "(empty)"}
+	}
+	return ToolResult{Output: strings.Join(out, "\n")}
+}
+
+// ---- workspace_read ----
+
+func (t *WorkspaceReadTool) Name() string { return "workspace_read" }
+func (t *WorkspaceReadTool) Description() string {
+	return "Read a file from the docker_exec workspace, optionally a line range. Lines are returned numbered."
+}
+func (t *WorkspaceReadTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path":       map[string]interface{}{"type": "string", "description": "File path relative to /workspace"},
+			"start_line": map[string]interface{}{"type": "integer", "description": "First line to return, 1-based (default 1)"},
+			"end_line":   map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Last line to return, inclusive (default start_line+%d)", maxReadLines-1)},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *WorkspaceReadTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	root, err := sessionWorkspace(t.exec)
+	if err != nil {
+		return ToolResult{Error: err}
+	}
+	name, _ := call.Args["path"].(string)
+	w := NewWorkspaceWriter(root)
+	if _, err := w.Resolve(name); err != nil {
+		return ToolResult{Error: fmt.Errorf("path %q: %w", name, err)}
+	}
+	data, err := w.ReadFile(name)
+	if err != nil {
+		return ToolResult{Error: fmt.Errorf("cannot read %s: %w", name, err)}
+	}
+	if isBinary(data) {
+		return ToolResult{Error: fmt.Errorf("%s looks like a binary file (%d bytes)", name, len(data))}
+	}
+
+	lines := splitLines(string(data))
+	if len(lines) == 0 {
+		return ToolResult{Output: name + " (empty file)"}
+	}
+	start := max(1, intArg(call.Args, "start_line", 1))
+	end := intArg(call.Args, "end_line", start+maxReadLines-1)
+	if end < start {
+		return ToolResult{Error: fmt.Errorf("end_line %d is before start_line %d", end, start)}
+	}
+	end = min(end, len(lines), start+maxReadLines-1)
+	if start > len(lines) {
+		return ToolResult{Error: fmt.Errorf("start_line %d is past the end of %s (%d lines)", start, name, len(lines))}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (lines %d-%d of %d)\n", name, start, end, len(lines))
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "%5d| %s\n", i, lines[i-1])
+	}
+	return ToolResult{Output: b.String()}
+}
+
+// ---- workspace_grep ----
+
+func (t *WorkspaceGrepTool) Name() string { return "workspace_grep" }
+func (t *WorkspaceGrepTool) Description() string {
+	return "Search files in the docker_exec workspace with a regular expression; returns path:line: text matches."
+}
+func (t *WorkspaceGrepTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern":          map[string]interface{}{"type": "string", "description": "Regular expression (Go RE2 syntax)"},
+			"path":             map[string]interface{}{"type": "string", "description": "Sub-directory relative to /workspace (default: root)"},
+			"glob":             map[string]interface{}{"type": "string", "description": "Only search files matching this glob, e.g. *.ts"},
+			"case_insensitive": map[string]interface{}{"type": "boolean", "description": "Ignore case"},
+			"max_results":      map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Maximum matches to return (default %d)", defaultGrepLimit)},
+			"ignore":           map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Extra glob patterns to skip"},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *WorkspaceGrepTool) Call(ctx context.Context, call ToolCall) ToolResult {
+	metrics.ToolCallsTotal.WithLabelValues(t.Name(), call.Caller).Inc()
+	timer := prometheus.NewTimer(metrics.ToolLatencySeconds.WithLabelValues(t.Name(), call.Caller))
+	defer timer.ObserveDuration()
+
+	root, err := sessionWorkspace(t.exec)
+	if err != nil {
+		return ToolResult{Error: err}
+	}
+	pattern, _ := call.Args["pattern"].(string)
+	if pattern == "" {
+		return ToolResult{Error: fmt.Errorf("missing argument: pattern")}
+	}
+	if ci, _ := call.Args["case_insensitive"].(bool); ci {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ToolResult{Error: fmt.Errorf("invalid pattern: %w", err)}
+	}
+	dirArg, _ := call.Args["path"].(string)
+	dir, err := resolveWorkspaceDir(root, dirArg)
+	if err != nil {
+		return ToolResult{Error: fmt.Errorf("path %q: %w", dirArg, err)}
+	}
+	glob, _ := call.Args["glob"].(string)
+	limit := intArg(call.Args, "max_results", defaultGrepLimit)
+	if limit <= 0 {
+		limit = defaultGrepLimit
+	}
+	ignores := ignorePatterns(call.Args)
+
+	var matches []string
+	truncated := false
+	walkErr := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if rel != "." && matchAnyGlob(ignores, rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > maxGrepFileSize {
+			return nil
+		}
+		if glob != "" && !matchGlob(glob, rel) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil || isBinary(data) {
+			return nil
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 64*1024), maxGrepFileSize)
+		for n := 1; sc.Scan(); n++ {
+			if re.MatchString(sc.Text()) {
+				if len(matches) >= limit {
+					truncated = true
+					return filepath.SkipAll
+				}
+				matches = append(matches, fmt.Sprintf("%s:%d: %s", rel, n, strings.TrimSpace(sc.Text())))
+			}
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return ToolResult{Error: walkErr}
+	}
+	if len(matches) == 0 {
+		return ToolResult{Output: "no matches"}
+	}
+	if truncated {
+		matches = append(matches, fmt.Sprintf("... truncated at %d matches", limit))
+	}
+	return ToolResult{Output: strings.Join(matches, "\n")}
+}
+
+// isBinary uses the same heuristic as git: a NUL byte in the first 8KB.
+func isBinary(data []byte) bool {
+	n := min(len(data), 8000)
+	return bytes.IndexByte(data[:n], 0) >= 0
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// workspaceExec returns a DockerExecTool whose session workspace holds files,
+// without starting a container.
+func workspaceExec(t *testing.T, files map[string]string) *DockerExecTool {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec := NewDockerExecTool("test", "")
+	exec.workspace = dir
+	return exec
+}
+
+func TestWorkspaceGrepNonPositiveMaxResults(t *testing.T) {
+	grep := NewWorkspaceGrepTool(workspaceExec(t, map[string]string{"app.ts": "const a = 1\nconst b = 2\n"}))
+	for _, limit := range []float64{0, -1} {
+		res := grep.Call(context.Background(), ToolCall{Args: map[string]interface{}{"pattern": "const", "max_results": limit}})
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		if out, _ := res.Output.(string); strings.Count(out, "app.ts:") != 2 {
+			t.Errorf("max_results %v: output = %q, want both matches", limit, out)
+		}
+	}
+}
+
+func TestWorkspaceReadLineRange(t *testing.T) {
+	read := NewWorkspaceReadTool(workspaceExec(t, map[string]string{"main.py": "one\ntwo\nthree\n"}))
+	res := read.Call(context.Background(), ToolCall{Args: map[string]interface{}{"path": "main.py", "start_line": 2.0, "end_line": 3.0}})
+	if out, _ := res.Output.(string); res.Error != nil || !strings.Contains(out, "lines 2-3 of 3") {
+		t.Fatalf("read 2-3 = %v, %v", res.Output, res.Error)
+	}
+	res = read.Call(context.Background(), ToolCall{Args: map[string]interface{}{"path": "main.py", "start_line": 3.0, "end_line": 2.0}})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "before start_line") {
+		t.Errorf("end_line before start_line: err = %v, output = %v", res.Error, res.Output)
+	}
+}
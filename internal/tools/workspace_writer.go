@@ -0,0 +1,153 @@
+// internal/tools/workspace_writer.go
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultMaxFileSize  = 2 << 20 // 2 MiB per file
+	DefaultMaxFileCount = 200     // files per tool call
+)
+
+// WorkspaceWriter writes LLM-supplied files into a session workspace, refusing
+// anything that would land outside of it.
+type WorkspaceWriter struct {
+	Root         string
+	MaxFileSize  int
+	MaxFileCount int
+}
+
+// FileWriteError describes why a single code block was rejected.
+type FileWriteError struct {
+	FileName string
+	Reason   string
+}
+
+func (e FileWriteError) Error() string {
+	return fmt.Sprintf("%s: %s", e.FileName, e.Reason)
+}
+
+// FileWriteErrors aggregates the per-file rejections of one tool call.
+type FileWriteErrors []FileWriteError
+
+func (errs FileWriteErrors) Error() string {
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, "- "+e.Error())
+	}
+	return fmt.Sprintf("%d file(s) rejected:\n%s", len(errs), strings.Join(lines, "\n"))
+}
+
+func NewWorkspaceWriter(root string) *WorkspaceWriter {
+	return &WorkspaceWriter{Root: root, MaxFileSize: DefaultMaxFileSize, MaxFileCount: DefaultMaxFileCount}
+}
+
+// NormalizeWorkspacePath converts a model-supplied file name into a clean,
+// slash-separated path relative to the workspace root. Windows separators are
+// accepted ("app\start.sh"), a leading "/workspace/" is stripped, and anything
+// absolute or escaping the root is rejected.
+func NormalizeWorkspacePath(name string) (string, error) {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" {
+		return "", errors.New("empty file name")
+	}
+	if strings.ContainsRune(name, 0) {
+		return "", errors.New("file name contains a NUL byte")
+	}
+	if len(name) >= 2 && name[1] == ':' {
+		return "", errors.New("absolute Windows paths are not allowed")
+	}
+	name = strings.TrimPrefix(name, "/workspace/")
+	if strings.HasPrefix(name, "/") {
+		return "", errors.New("absolute paths are not allowed; use a path relative to the workspace")
+	}
+	clean := path.Clean(name)
+	if clean == "." {
+		return "", errors.New("file name does not name a file")
+	}
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.New("path escapes the workspace")
+	}
+	return clean, nil
+}
+
+// Resolve maps a model-supplied file name to a host path inside Root. Every
+// existing component of the path is checked so a symlink planted by earlier
+// commands (e.g. "ln -s /etc out") is reported up front. The check alone
+// races with the running container; file access goes through WriteFile,
+// ReadFile and Remove, which refuse symlinks when they open each component.
+func (w *WorkspaceWriter) Resolve(name string) (string, error) {
+	rel, err := NormalizeWorkspacePath(name)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(w.Root)
+	if err != nil {
+		return "", fmt.Errorf("workspace unavailable: %w", err)
+	}
+	cur := root
+	for _, seg := range strings.Split(rel, "/") {
+		cur = filepath.Join(cur, seg)
+		info, err := os.Lstat(cur)
+		if os.IsNotExist(err) {
+			break // the rest will be created
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("path component %q is a symlink", seg)
+		}
+	}
+	dest := filepath.Join(root, filepath.FromSlash(rel))
+	if r, err := filepath.Rel(root, dest); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", errors.New("path escapes the workspace")
+	}
+	return dest, nil
+}
+
+// WriteBlocks validates all blocks first and only writes if every one is
+// acceptable, so the model gets the full list of problems in one round trip.
+// Writes go through WriteFile.
+func (w *WorkspaceWriter) WriteBlocks(blocks []CodeBlock) error {
+	if w.MaxFileCount > 0 && len(blocks) > w.MaxFileCount {
+		return FileWriteErrors{{FileName: "*", Reason: fmt.Sprintf("too many files in one call (%d > %d)", len(blocks), w.MaxFileCount)}}
+	}
+	var errs FileWriteErrors
+	seen := map[string]bool{}
+	for _, b := range blocks {
+		if w.MaxFileSize > 0 && len(b.Code) > w.MaxFileSize {
+			errs = append(errs, FileWriteError{b.FileName, fmt.Sprintf("file too large (%d bytes > %d)", len(b.Code), w.MaxFileSize)})
+			continue
+		}
+		dest, err := w.Resolve(b.FileName)
+		if err != nil {
+			errs = append(errs, FileWriteError{b.FileName, err.Error()})
+			continue
+		}
+		if seen[dest] {
+			errs = append(errs, FileWriteError{b.FileName, "duplicate file in the same call"})
+			continue
+		}
+		seen[dest] = true
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for _, b := range blocks {
+		if err := w.WriteFile(b.FileName, []byte(b.Code)); err != nil {
+			errs = append(errs, FileWriteError{b.FileName, fmt.Sprintf("failed to write file: %v", err)})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
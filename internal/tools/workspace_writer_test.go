@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWorkspacePath(t *testing.T) {
+	tests := []struct {
+		name, want, err string
+	}{
+		{"src/app.py", "src/app.py", ""},
+		{"/workspace/src/app.py", "src/app.py", ""},
+		{`app\start.sh`, "app/start.sh", ""},
+		{"a/./b/../c.txt", "a/c.txt", ""},
+		{"../x", "", "escapes"},
+		{"a/../../x", "", "escapes"},
+		{"/etc/passwd", "", "absolute"},
+		{`C:\Windows\x`, "", "absolute Windows"},
+		{"", "", "empty"},
+		{".", "", "does not name a file"},
+		{"a\x00b", "", "NUL"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeWorkspacePath(tt.name)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("NormalizeWorkspacePath(%q) = %q, %v, want error containing %q", tt.name, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeWorkspacePath(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// symlinkedWorkspace returns a workspace holding "out" -> outside/ and
+// "leak.txt" -> outside/target.txt.
+func symlinkedWorkspace(t *testing.T) (root, outside string) {
+	t.Helper()
+	root, outside = t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "target.txt"), []byte("original"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "out")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "target.txt"), filepath.Join(root, "leak.txt")); err != nil {
+		t.Fatal(err)
+	}
+	return root, outside
+}
+
+func TestWorkspaceWriterRefusesSymlinks(t *testing.T) {
+	root, outside := symlinkedWorkspace(t)
+	w := NewWorkspaceWriter(root)
+
+	for _, name := range []string{"out/new.txt", "out/target.txt", "leak.txt"} {
+		if err := w.WriteBlocks([]CodeBlock{{FileName: name, Code: "pwned"}}); err == nil || !strings.Contains(err.Error(), "symlink") {
+			t.Errorf("WriteBlocks(%s): err = %v, want a symlink rejection", name, err)
+		}
+		// WriteFile checks at open time, so it also holds when the link
+		// appears after Resolve.
+		if err := w.WriteFile(name, []byte("pwned")); err == nil || !strings.Contains(err.Error(), "symlink") {
+			t.Errorf("WriteFile(%s): err = %v, want a symlink rejection", name, err)
+		}
+		if _, err := w.ReadFile(name); err == nil {
+			t.Errorf("ReadFile(%s) followed the symlink", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("file created outside the workspace: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outside, "target.txt")); string(data) != "original" {
+		t.Errorf("outside target = %q, want it untouched", data)
+	}
+
+	// Removing the link removes the link, not its target.
+	if err := w.Remove("leak.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Remove("out/target.txt"); err == nil {
+		t.Error("Remove followed a symlinked directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "target.txt")); err != nil {
+		t.Errorf("outside target removed: %v", err)
+	}
+}
+
+func TestWorkspaceWriterWriteBlocks(t *testing.T) {
+	root := t.TempDir()
+	w := NewWorkspaceWriter(root)
+	err := w.WriteBlocks([]CodeBlock{
+		{FileName: "/workspace/src/pkg/main.py", Code: "print(1)\n"},
+		{FileName: `scripts\run.sh`, Code: "echo hi\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"src/pkg/main.py": "print(1)\n", "scripts/run.sh": "echo hi\n"} {
+		if data, err := w.ReadFile(name); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := w.ReadFile("missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile(missing) err = %v, want not-exist", err)
+	}
+
+	// One bad block rejects the whole call and every problem is reported.
+	err = w.WriteBlocks([]CodeBlock{
+		{FileName: "ok.txt", Code: "x"},
+		{FileName: "../escape.txt", Code: "x"},
+		{FileName: "/etc/cron.d/x", Code: "x"},
+		{FileName: "dup.txt", Code: "a"},
+		{FileName: "./dup.txt", Code: "b"},
+	})
+	var errs FileWriteErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("err = %v, want 3 rejections", err)
+	}
+	for i, want := range []string{"escapes", "absolute", "duplicate"} {
+		if !strings.Contains(errs[i].Reason, want) {
+			t.Errorf("rejection %d = %q, want %q", i, errs[i].Reason, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "ok.txt")); !os.IsNotExist(err) {
+		t.Error("a rejected call wrote files")
+	}
+}
+
+func TestWorkspaceWriterLimits(t *testing.T) {
+	w := &WorkspaceWriter{Root: t.TempDir(), MaxFileSize: 4, MaxFileCount: 2}
+	err := w.WriteBlocks([]CodeBlock{{FileName: "a", Code: "1"}, {FileName: "b", Code: "2"}, {FileName: "c", Code: "3"}})
+	if err == nil || !strings.Contains(err.Error(), "too many files") {
+		t.Errorf("3 files: err = %v, want the count limit", err)
+	}
+	err = w.WriteBlocks([]CodeBlock{{FileName: "big", Code: "12345"}})
+	if err == nil || !strings.Contains(err.Error(), "file too large") {
+		t.Errorf("5 bytes: err = %v, want the size limit", err)
+	}
+	if err := w.WriteBlocks([]CodeBlock{{FileName: "a", Code: "1234"}, {FileName: "b", Code: ""}}); err != nil {
+		t.Errorf("within limits: %v", err)
+	}
+}
+
+func TestDockerExecWriteLimits(t *testing.T) {
+	exec := workspaceExec(t, nil)
+	exec.SetWriteLimits(3, 1)
+	err := exec.copyFilesToContainer(context.Background(), []CodeBlock{{FileName: "a.py", Code: "1234"}})
+	if err == nil || !strings.Contains(err.Error(), "file too large") {
+		t.Errorf("err = %v, want the configured size limit", err)
+	}
+}
@@ -11,21 +11,23 @@ import (
 	// "github.com/mattn/go-colorable"
 	"path/filepath"
 	"io"
+	"sync"
+	"sync/atomic"
 )
 
 var (
-    Logger zerolog.Logger
+    Logger    zerolog.Logger
+    logFile   *os.File
+    logPath   = "run.log" // opened on the first log line; "" disables the file
+    logClosed bool
+    logMu     sync.Mutex
 )
 
 func init() {
     // Log to both file and console
-    logFile, err := os.OpenFile("run.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-    if err != nil {
-        panic(err)
-    }
 
     // Console writer with color
-    consoleWriter := zerolog.ConsoleWriter{Out: goColorableStdout(), TimeFormat: "15:04:05",
+    consoleWriter := zerolog.ConsoleWriter{Out: consoleOut{}, TimeFormat: "15:04:05",
 	FormatCaller: func(i interface{}) string {
 		return filepath.Base(i.(string)) // Show only the filename, not full path
 		},
@@ -56,14 +58,113 @@ func init() {
 	// 	consoleWriter.Out = io.Discard // Disable console output in production
 	// } 
 
-    multi := io.MultiWriter(consoleWriter, logFile)
+    multi := io.MultiWriter(consoleWriter, fileWriter{})
     zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
-    Logger = zerolog.New(multi).With().Timestamp().Caller().Logger()
+    Logger = zerolog.New(redactingWriter{multi}).With().Timestamp().Caller().Logger()
 
     // Also replace global log, so log.Info().Msg() etc works everywhere
     log.Logger = Logger
 }
 
+var logRedactor atomic.Value // func(string) string
+
+// SetLogRedactor installs a function applied to every log line before it is
+// written to the console or the log file (used to mask secret values).
+func SetLogRedactor(redact func(string) string) {
+    logRedactor.Store(redact)
+}
+
+type redactingWriter struct{ w io.Writer }
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+    if redact, ok := logRedactor.Load().(func(string) string); ok && redact != nil {
+        if _, err := r.w.Write([]byte(redact(string(p)))); err != nil {
+            return 0, err
+        }
+        return len(p), nil
+    }
+    return r.w.Write(p)
+}
+
+// ConfigureLogger applies the logging settings: the minimum level, the log
+// file ("" disables it) and whether lines also go to the console.
+func ConfigureLogger(level, file string, console bool) error {
+    lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+    if err != nil {
+        return err
+    }
+    zerolog.SetGlobalLevel(lvl)
+    if !console {
+        SetConsoleOutput(io.Discard)
+    }
+    logMu.Lock()
+    defer logMu.Unlock()
+    if logFile != nil && file != logPath {
+        logFile.Close()
+        logFile = nil
+    }
+    logPath = file
+    return nil
+}
+
+// CloseLogger flushes the log file to disk and closes it. Later log lines
+// only reach the console.
+func CloseLogger() error {
+    logMu.Lock()
+    defer logMu.Unlock()
+    logClosed = true
+    if logFile == nil {
+        return nil
+    }
+    f := logFile
+    logFile = nil
+    syncErr := f.Sync()
+    if err := f.Close(); err != nil {
+        return err
+    }
+    return syncErr
+}
+
+var consoleTarget atomic.Value // consoleTargetBox
+
+type consoleTargetBox struct{ w io.Writer }
+
+// SetConsoleOutput redirects console log lines, e.g. to stderr when stdout
+// carries a protocol such as MCP over stdio.
+func SetConsoleOutput(w io.Writer) {
+    consoleTarget.Store(consoleTargetBox{w})
+}
+
+// consoleOut writes to the SetConsoleOutput target, stdout by default.
+type consoleOut struct{}
+
+func (consoleOut) Write(p []byte) (int, error) {
+    if box, ok := consoleTarget.Load().(consoleTargetBox); ok {
+        return box.w.Write(p)
+    }
+    return goColorableStdout().Write(p)
+}
+
+// fileWriter writes to the log file (run.log by default) until CloseLogger
+// is called, opening it on first use.
+type fileWriter struct{}
+
+func (fileWriter) Write(p []byte) (int, error) {
+    logMu.Lock()
+    defer logMu.Unlock()
+    if logFile == nil {
+        if logClosed || logPath == "" {
+            return len(p), nil
+        }
+        f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+        if err != nil {
+            return 0, err
+        }
+        logFile = f
+    }
+    return logFile.Write(p)
+}
+
 // Windows/ANSI-safe colorable output
 func goColorableStdout() *os.File {
     return os.Stdout
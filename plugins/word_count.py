@@ -0,0 +1,62 @@
+#!/usr/bin/env python3
+"""Sample go-gen tool plugin: counts the lines, words and characters of a text.
+
+Plugins speak JSON lines on stdin/stdout (see tools.PluginTool):
+
+  -> {"id":1,"method":"describe"}
+  <- {"id":1,"result":{"name":"word_count","description":"...","schema":{...}}}
+  -> {"id":2,"method":"call","params":{"args":{"text":"..."}}}
+  <- {"id":2,"result":{...}}   or   {"id":2,"error":"message"}
+
+Write logs to stderr only; stdout carries the protocol. The plugin exits when
+stdin is closed.
+"""
+import json
+import sys
+
+DESCRIBE = {
+    "name": "word_count",
+    "description": "Count the lines, words and characters of a text.",
+    "schema": {
+        "type": "object",
+        "properties": {
+            "text": {"type": "string", "description": "Text to count"},
+        },
+        "required": ["text"],
+    },
+}
+
+
+def call(args):
+    text = args.get("text")
+    if not isinstance(text, str):
+        raise ValueError("text must be a string")
+    return {
+        "lines": len(text.splitlines()),
+        "words": len(text.split()),
+        "characters": len(text),
+    }
+
+
+def main():
+    for line in sys.stdin:
+        line = line.strip()
+        if not line:
+            continue
+        msg = json.loads(line)
+        reply = {"id": msg.get("id")}
+        try:
+            if msg.get("method") == "describe":
+                reply["result"] = DESCRIBE
+            elif msg.get("method") == "call":
+                reply["result"] = call(msg.get("params", {}).get("args", {}))
+            else:
+                reply["error"] = "unknown method %r" % msg.get("method")
+        except Exception as e:  # report, keep serving
+            reply["error"] = str(e)
+        sys.stdout.write(json.dumps(reply) + "\n")
+        sys.stdout.flush()
+
+
+if __name__ == "__main__":
+    main()